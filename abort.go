@@ -0,0 +1,79 @@
+package ecspresso
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// Abort stops the deployment in progress by rolling back the service to the task definition
+// of the previous stable deployment, and waits for the service to be stable.
+// It does nothing when no deployments are in progress.
+func (d *App) Abort(opt AbortOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	primary := inProgressDeployment(sv)
+	if primary == nil {
+		d.ResultLog("No deployments in progress. Nothing to abort")
+		return nil
+	}
+	d.Log("Aborting the deployment", aws.StringValue(primary.Id), "of", arnToName(aws.StringValue(primary.TaskDefinition)))
+	targetArn := previousStableTaskDefinition(sv)
+	if targetArn == aws.StringValue(primary.TaskDefinition) {
+		return errors.Errorf("the deployment in progress uses the same task definition %s as the previous one", arnToName(targetArn))
+	}
+	cancel()
+
+	force := true // the previous deployment may have a newer revision than the aborted one
+	return d.Rollback(RollbackOption{
+		DryRun:                   opt.DryRun,
+		DeregisterTaskDefinition: aws.Bool(false),
+		NoWait:                   opt.NoWait,
+		ToRevision:               aws.Int64(0),
+		ToArn:                    aws.String(targetArn),
+		Force:                    &force,
+	})
+}
+
+// inProgressDeployment returns the PRIMARY deployment of the service when it is rolling out.
+// Without rollout state (e.g. for old services), a PRIMARY deployment along with other deployments is in progress.
+func inProgressDeployment(sv *ecs.Service) *ecs.Deployment {
+	for _, dep := range sv.Deployments {
+		if aws.StringValue(dep.Status) != "PRIMARY" {
+			continue
+		}
+		switch aws.StringValue(dep.RolloutState) {
+		case ecs.DeploymentRolloutStateInProgress:
+			return dep
+		case "":
+			if len(sv.Deployments) > 1 {
+				return dep
+			}
+		}
+	}
+	return nil
+}
+
+// previousStableTaskDefinition returns the task definition ARN of the ACTIVE deployment which was replaced by
+// the deployment in progress. A completed one is preferred. It returns an empty string when not found,
+// and then rollback finds the previous revision of the family.
+func previousStableTaskDefinition(sv *ecs.Service) string {
+	var arn string
+	for _, dep := range sv.Deployments {
+		if aws.StringValue(dep.Status) != "ACTIVE" {
+			continue
+		}
+		if aws.StringValue(dep.RolloutState) == ecs.DeploymentRolloutStateCompleted {
+			return aws.StringValue(dep.TaskDefinition)
+		}
+		if arn == "" {
+			arn = aws.StringValue(dep.TaskDefinition)
+		}
+	}
+	return arn
+}
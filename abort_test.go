@@ -0,0 +1,72 @@
+package ecspresso
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func testAbortDeployment(id, status, rolloutState, td string) *ecs.Deployment {
+	return &ecs.Deployment{
+		Id:             aws.String(id),
+		Status:         aws.String(status),
+		RolloutState:   aws.String(rolloutState),
+		TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/" + td),
+	}
+}
+
+func TestAbortTarget(t *testing.T) {
+	sv := &ecs.Service{
+		Deployments: []*ecs.Deployment{
+			testAbortDeployment("ecs-svc/3", "PRIMARY", "IN_PROGRESS", "app:3"),
+			testAbortDeployment("ecs-svc/1", "ACTIVE", "FAILED", "app:1"),
+			testAbortDeployment("ecs-svc/2", "ACTIVE", "COMPLETED", "app:2"),
+		},
+	}
+	if dep := inProgressDeployment(sv); dep == nil || *dep.Id != "ecs-svc/3" {
+		t.Errorf("unexpected deployment in progress %v", dep)
+	}
+	if arn := previousStableTaskDefinition(sv); arn != "arn:aws:ecs:us-east-1:123456789012:task-definition/app:2" {
+		t.Errorf("unexpected previous task definition %s", arn)
+	}
+
+	sv.Deployments = sv.Deployments[:1]
+	sv.Deployments[0].RolloutState = aws.String("COMPLETED")
+	if dep := inProgressDeployment(sv); dep != nil {
+		t.Errorf("completed deployment must not be in progress %v", dep)
+	}
+	if arn := previousStableTaskDefinition(sv); arn != "" {
+		t.Errorf("unexpected previous task definition %s", arn)
+	}
+}
+
+func TestAbortNoDeploymentInProgress(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockPruneECS{
+		service: &ecs.Service{
+			ServiceName:    aws.String("test"),
+			TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/app:2"),
+			Deployments: []*ecs.Deployment{
+				testAbortDeployment("ecs-svc/2", "PRIMARY", "COMPLETED", "app:2"),
+			},
+		},
+	}
+	app.ecs = m
+	if err := app.Abort(AbortOption{DryRun: aws.Bool(false), NoWait: aws.Bool(true)}); err != nil {
+		t.Fatal(err)
+	}
+	if m.updated != nil {
+		t.Errorf("must not update the service without deployments in progress %v", m.updated)
+	}
+}
@@ -0,0 +1,197 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// Formats of diff outputs.
+const (
+	DiffFormatUnified = "unified"
+	DiffFormatGitHub  = "github"
+)
+
+// taskDefinitionChange is a change of the task definition in a structured diff.
+type taskDefinitionChange struct {
+	Container string // empty for task-level fields
+	Field     string
+	Kind      string // added, removed or changed
+	From      string
+	To        string
+}
+
+func (c taskDefinitionChange) String() string {
+	var subject string
+	if c.Container != "" {
+		subject = "container " + c.Container + ": "
+	}
+	if c.Field == "" {
+		return subject + c.Kind
+	}
+	subject += c.Field + " " + c.Kind
+	if c.Kind == "changed" && c.From != "" && c.To != "" {
+		subject += fmt.Sprintf(" %s -> %s", c.From, c.To)
+	}
+	return subject
+}
+
+// taskDefinitionChanges returns structured changes from the task definition to another one,
+// based on the normalized task definitions same as the unified diff.
+// Values of environment are never shown.
+func taskDefinitionChanges(from, to *ecs.TaskDefinition) ([]taskDefinitionChange, error) {
+	a, err := normalizedTaskDefinitionMap(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := normalizedTaskDefinitionMap(to)
+	if err != nil {
+		return nil, err
+	}
+	fromContainers := containersByName(a["containerDefinitions"])
+	toContainers := containersByName(b["containerDefinitions"])
+	delete(a, "containerDefinitions")
+	delete(b, "containerDefinitions")
+
+	changes := fieldChanges("", a, b)
+	for _, name := range unionKeys(fromContainers, toContainers) {
+		fc, fok := fromContainers[name]
+		tc, tok := toContainers[name]
+		switch {
+		case !fok:
+			changes = append(changes, taskDefinitionChange{Container: name, Kind: "added"})
+		case !tok:
+			changes = append(changes, taskDefinitionChange{Container: name, Kind: "removed"})
+		default:
+			changes = append(changes, containerChanges(name, fc, tc)...)
+		}
+	}
+	return changes, nil
+}
+
+func normalizedTaskDefinitionMap(td *ecs.TaskDefinition) (map[string]interface{}, error) {
+	b, err := normalizeTaskDefinition(td, nil)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func containersByName(v interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+	cds, _ := v.([]interface{})
+	for _, cd := range cds {
+		if c, ok := cd.(map[string]interface{}); ok {
+			name, _ := c["name"].(string)
+			m[name] = c
+		}
+	}
+	return m
+}
+
+func containerChanges(name string, from, to interface{}) []taskDefinitionChange {
+	a, _ := from.(map[string]interface{})
+	b, _ := to.(map[string]interface{})
+	var changes []taskDefinitionChange
+	for _, key := range []string{"environment", "secrets"} {
+		fromValues, toValues := namedValues(a[key]), namedValues(b[key])
+		delete(a, key)
+		delete(b, key)
+		for _, n := range unionKeys(fromValues, toValues) {
+			f, fok := fromValues[n]
+			t, tok := toValues[n]
+			field := key + " " + n
+			switch {
+			case !fok:
+				changes = append(changes, taskDefinitionChange{Container: name, Field: field, Kind: "added"})
+			case !tok:
+				changes = append(changes, taskDefinitionChange{Container: name, Field: field, Kind: "removed"})
+			case !reflect.DeepEqual(f, t):
+				changes = append(changes, taskDefinitionChange{Container: name, Field: field, Kind: "changed"})
+			}
+		}
+	}
+	return append(fieldChanges(name, a, b), changes...)
+}
+
+// namedValues returns values of environment or secrets by name.
+func namedValues(v interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+	vs, _ := v.([]interface{})
+	for _, v := range vs {
+		if kv, ok := v.(map[string]interface{}); ok {
+			name, _ := kv["name"].(string)
+			m[name] = kv
+		}
+	}
+	return m
+}
+
+func fieldChanges(container string, from, to map[string]interface{}) []taskDefinitionChange {
+	var changes []taskDefinitionChange
+	for _, key := range unionKeys(from, to) {
+		f, fok := from[key]
+		t, tok := to[key]
+		switch {
+		case !fok:
+			changes = append(changes, taskDefinitionChange{Container: container, Field: key, Kind: "added"})
+		case !tok:
+			changes = append(changes, taskDefinitionChange{Container: container, Field: key, Kind: "removed"})
+		case !reflect.DeepEqual(f, t):
+			changes = append(changes, taskDefinitionChange{Container: container, Field: key, Kind: "changed", From: scalarString(f), To: scalarString(t)})
+		}
+	}
+	return changes
+}
+
+// scalarString returns a string of the scalar value, or an empty string for objects and arrays.
+func scalarString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64, bool:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatGitHubAnnotations formats the changes as workflow commands of GitHub Actions,
+// so that they are shown as annotations of the file (e.g. in pull requests).
+func formatGitHubAnnotations(changes []taskDefinitionChange, file, title string) string {
+	var b strings.Builder
+	for _, c := range changes {
+		fmt.Fprintf(&b, "::warning file=%s,title=%s::%s\n",
+			escapeAnnotationProperty(file), escapeAnnotationProperty(title), escapeAnnotationData(c.String()))
+	}
+	return b.String()
+}
+
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
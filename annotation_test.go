@@ -0,0 +1,47 @@
+package ecspresso
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestGitHubAnnotations(t *testing.T) {
+	from := testDiffTaskDefinition(
+		&ecs.KeyValuePair{Name: aws.String("FOO"), Value: aws.String("foo")},
+		&ecs.KeyValuePair{Name: aws.String("BAR"), Value: aws.String("bar")},
+	)
+	from.Cpu = aws.String("256")
+	to := testDiffTaskDefinition(
+		&ecs.KeyValuePair{Name: aws.String("FOO"), Value: aws.String("secret value")},
+		&ecs.KeyValuePair{Name: aws.String("BAZ"), Value: aws.String("baz")},
+	)
+	to.Cpu = aws.String("512")
+	to.ContainerDefinitions[0].Image = aws.String("app:v2")
+	to.ContainerDefinitions = append(to.ContainerDefinitions, &ecs.ContainerDefinition{Name: aws.String("sidecar"), Image: aws.String("sidecar:v1")})
+
+	changes, err := taskDefinitionChanges(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := formatGitHubAnnotations(changes, "ecs-task-def.json", "Task definition differs from app:3 (running)")
+	expected := `::warning file=ecs-task-def.json,title=Task definition differs from app%3A3 (running)::cpu changed 256 -> 512
+::warning file=ecs-task-def.json,title=Task definition differs from app%3A3 (running)::container app: image changed app:v1 -> app:v2
+::warning file=ecs-task-def.json,title=Task definition differs from app%3A3 (running)::container app: environment BAR removed
+::warning file=ecs-task-def.json,title=Task definition differs from app%3A3 (running)::container app: environment BAZ added
+::warning file=ecs-task-def.json,title=Task definition differs from app%3A3 (running)::container app: environment FOO changed
+::warning file=ecs-task-def.json,title=Task definition differs from app%3A3 (running)::container sidecar: added
+`
+	if out != expected {
+		t.Errorf("unexpected annotations\n%s\nexpected\n%s", out, expected)
+	}
+
+	changes, err = taskDefinitionChanges(from, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("unexpected changes %v", changes)
+	}
+}
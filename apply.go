@@ -0,0 +1,130 @@
+package ecspresso
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// writePlanFile writes the plan to path, to be applied later by apply.
+func writePlanFile(path string, res *PlanResult) error {
+	b, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// readPlanFile reads the plan written by plan --out.
+func readPlanFile(path string) (*PlanResult, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var res PlanResult
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, errors.Wrapf(err, "%s is not a plan file", path)
+	}
+	if res.CurrentTaskDefinitionArn == "" || len(res.TaskDefinition) == 0 {
+		return nil, errors.Errorf("%s is not a plan file (written by plan --out)", path)
+	}
+	return &res, nil
+}
+
+// checkDrift returns ErrPlanDrift when the live service does not match the assumptions of the plan.
+func (res *PlanResult) checkDrift(service, cluster string, sv *ecs.Service) error {
+	if res.Service != service || res.Cluster != cluster {
+		return errors.Wrapf(ErrPlanDrift, "the plan is for %s/%s, not %s/%s", res.Service, res.Cluster, service, cluster)
+	}
+	if live := aws.StringValue(sv.TaskDefinition); live != res.CurrentTaskDefinitionArn {
+		return errors.Wrapf(ErrPlanDrift, "the task definition of the service is %s, but the plan was made against %s", arnToName(live), res.CurrentTaskDefinition)
+	}
+	return nil
+}
+
+// taskDefinition returns the task definition rendered by the plan without rendering again.
+func (res *PlanResult) taskDefinition() (*ecs.TaskDefinition, error) {
+	var in ecs.RegisterTaskDefinitionInput
+	if err := jsonutil.UnmarshalJSON(&in, bytes.NewReader(res.TaskDefinition)); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the task definition of the plan")
+	}
+	return &ecs.TaskDefinition{
+		ContainerDefinitions:    in.ContainerDefinitions,
+		Cpu:                     in.Cpu,
+		ExecutionRoleArn:        in.ExecutionRoleArn,
+		Family:                  in.Family,
+		Memory:                  in.Memory,
+		NetworkMode:             in.NetworkMode,
+		PlacementConstraints:    in.PlacementConstraints,
+		RequiresCompatibilities: in.RequiresCompatibilities,
+		TaskRoleArn:             in.TaskRoleArn,
+		ProxyConfiguration:      in.ProxyConfiguration,
+		RuntimePlatform:         in.RuntimePlatform,
+		Volumes:                 in.Volumes,
+	}, nil
+}
+
+// Apply registers the task definition of the plan file and deploys it, exactly as reviewed.
+// It aborts with ErrPlanDrift when the service was changed after the plan.
+func (d *App) Apply(opt ApplyOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+	d.setDeployID()
+
+	path := aws.StringValue(opt.PlanFile)
+	d.Log("Starting apply", path)
+	res, err := readPlanFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read plan")
+	}
+	if err := d.verifyIdentity(ctx); err != nil {
+		return err
+	}
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	if err := res.checkDrift(d.Service, d.Cluster, sv); err != nil {
+		return err
+	}
+	if dc := sv.DeploymentController; dc != nil && aws.StringValue(dc.Type) != ecs.DeploymentControllerTypeEcs {
+		return fmt.Errorf("apply supports only ECS deployment controller, not %s", aws.StringValue(dc.Type))
+	}
+	if !res.HasDiff {
+		d.ResultLog("No changes in the plan")
+		return nil
+	}
+
+	td, err := res.taskDefinition()
+	if err != nil {
+		return err
+	}
+	// images are already resolved by plan, if resolve_image_digests is enabled
+	newTd, err := d.registerTaskDefinition(ctx, td, false)
+	if err != nil {
+		return errors.Wrap(err, "failed to register task definition")
+	}
+	tdArn := aws.StringValue(newTd.TaskDefinitionArn)
+	if err := d.UpdateServiceTasks(ctx, tdArn, nil, DeployOption{ForceNewDeployment: aws.Bool(false)}); err != nil {
+		return errors.Wrap(err, "failed to update service tasks")
+	}
+	if aws.BoolValue(opt.NoWait) {
+		d.ResultLog("Service is deployed.")
+		return nil
+	}
+	if err := d.WaitServiceStable(ctx, time.Now()); err != nil {
+		return errors.Wrap(err, "failed to wait service stable")
+	}
+	if err := d.VerifyDeployedRevision(ctx, tdArn); err != nil {
+		return errors.Wrap(err, "failed to verify deployed revision")
+	}
+	d.ResultLog("Service is stable now. Completed!")
+	return nil
+}
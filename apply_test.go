@@ -0,0 +1,157 @@
+package ecspresso
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+type mockApplyECS struct {
+	mockPlanECS
+	registered *ecs.RegisterTaskDefinitionInput
+	updated    *ecs.UpdateServiceInput
+}
+
+func (m *mockApplyECS) RegisterTaskDefinitionWithContext(_ aws.Context, in *ecs.RegisterTaskDefinitionInput, _ ...request.Option) (*ecs.RegisterTaskDefinitionOutput, error) {
+	m.registered = in
+	return &ecs.RegisterTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			Family:            in.Family,
+			Revision:          aws.Int64(3),
+			TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/" + *in.Family + ":3"),
+		},
+	}, nil
+}
+
+func (m *mockApplyECS) UpdateServiceWithContext(_ aws.Context, in *ecs.UpdateServiceInput, _ ...request.Option) (*ecs.UpdateServiceOutput, error) {
+	m.updated = in
+	return &ecs.UpdateServiceOutput{}, nil
+}
+
+func testApplyApp(t *testing.T) (*App, *mockApplyECS) {
+	t.Helper()
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.ContainerDefinitions[0].Image = aws.String("katsubushi/katsubushi:v0.0.1")
+	m := &mockApplyECS{
+		mockPlanECS: mockPlanECS{
+			mockDiffECS: mockDiffECS{
+				taskDefinitions: map[string]*ecs.TaskDefinition{"katsubushi:2": old},
+			},
+			service: &ecs.Service{TaskDefinition: aws.String("katsubushi:2")},
+		},
+	}
+	app.ecs = m
+	app.sts = &mockSTS{}
+	return app, m
+}
+
+func TestPlanApply(t *testing.T) {
+	delayForServiceChanged = 0
+	defer func() { delayForServiceChanged = 3 * time.Second }()
+
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plan.json")
+
+	app, m := testApplyApp(t)
+	err = app.Plan(PlanOption{ShowValues: aws.Bool(false), Out: aws.String(path)})
+	if errors.Cause(err) != ErrDiffFound {
+		t.Fatalf("unexpected error %v", err)
+	}
+	planned, err := readPlanFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if planned.CurrentTaskDefinitionArn != "katsubushi:2" || !planned.HasDiff {
+		t.Errorf("unexpected plan %#v", planned)
+	}
+
+	// the task definition file is changed after the plan, but apply must ship the planned one
+	app.config.TaskDefinitionPath = "tests/not-found.json"
+	if err := app.Apply(ApplyOption{PlanFile: aws.String(path), NoWait: aws.Bool(true)}); err != nil {
+		t.Fatal(err)
+	}
+	if m.registered == nil {
+		t.Fatal("the task definition must be registered")
+	}
+	want, err := planned.taskDefinition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.registered.Tags = nil
+	got, _ := marshalJSONSorted(m.registered)
+	expected, _ := marshalJSONSorted(registerTaskDefinitionInput(want))
+	if string(got) != string(expected) {
+		t.Errorf("registered task definition must be the planned one\n%s\n%s", got, expected)
+	}
+	if aws.StringValue(want.ContainerDefinitions[0].Image) != "katsubushi/katsubushi:latest" {
+		t.Errorf("unexpected planned image %s", aws.StringValue(want.ContainerDefinitions[0].Image))
+	}
+	if m.updated == nil || aws.StringValue(m.updated.TaskDefinition) != "arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:3" {
+		t.Errorf("the service must be updated to the registered task definition %v", m.updated)
+	}
+}
+
+func TestApplyDrift(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plan.json")
+
+	app, m := testApplyApp(t)
+	if err := app.Plan(PlanOption{ShowValues: aws.Bool(false), Out: aws.String(path)}); errors.Cause(err) != ErrDiffFound {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// deployed by others after the plan
+	m.service.TaskDefinition = aws.String("katsubushi:3")
+	err = app.Apply(ApplyOption{PlanFile: aws.String(path), NoWait: aws.Bool(true)})
+	if errors.Cause(err) != ErrPlanDrift {
+		t.Fatalf("drift must be detected: %v", err)
+	}
+	if ErrorType(err) != "PlanDrift" {
+		t.Errorf("unexpected error type %s", ErrorType(err))
+	}
+	if m.registered != nil || m.updated != nil {
+		t.Error("nothing must be deployed on drift")
+	}
+
+	// a plan for another service or cluster
+	m.service.TaskDefinition = aws.String("katsubushi:2")
+	for _, c := range []struct{ service, cluster string }{{"other", "default"}, {"test", "other"}} {
+		app.Service, app.Cluster = c.service, c.cluster
+		err := app.Apply(ApplyOption{PlanFile: aws.String(path), NoWait: aws.Bool(true)})
+		if errors.Cause(err) != ErrPlanDrift || !strings.Contains(err.Error(), "the plan is for test/default") {
+			t.Errorf("a plan for %s/%s must not be applied: %v", c.service, c.cluster, err)
+		}
+	}
+	if m.registered != nil || m.updated != nil {
+		t.Error("nothing must be deployed for another service")
+	}
+}
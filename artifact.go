@@ -0,0 +1,50 @@
+package ecspresso
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+const artifactTimeFormat = "20060102T150405Z"
+
+// writeArtifact writes the task definition to be registered into the artifact_dir as
+// <family>-<timestamp>.json, and copies it to <family>-latest.json.
+// This is best-effort, so any errors are logged but not returned.
+func (d *App) writeArtifact(in *ecs.RegisterTaskDefinitionInput, now time.Time) {
+	if d.config.ArtifactDir == "" {
+		return
+	}
+	path, err := writeArtifactFiles(d.config.ArtifactDir, in, now)
+	if err != nil {
+		d.Log("[WARNING] failed to write the task definition artifact:", err)
+		return
+	}
+	d.DebugLog("task definition artifact is written to", path)
+}
+
+func writeArtifactFiles(dir string, in *ecs.RegisterTaskDefinitionInput, now time.Time) (string, error) {
+	b, err := marshalJSONSorted(in)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to marshal task definition to JSON")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	family := aws.StringValue(in.Family)
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.json", family, now.UTC().Format(artifactTimeFormat)))
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		return "", err
+	}
+	latest := filepath.Join(dir, family+"-latest.json")
+	if err := ioutil.WriteFile(latest, b, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,50 @@
+package ecspresso
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestWriteArtifactFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := &ecs.RegisterTaskDefinitionInput{
+		Family: aws.String("app"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1")},
+		},
+	}
+	now := time.Date(2020, 4, 1, 12, 34, 56, 0, time.UTC)
+	path, err := writeArtifactFiles(filepath.Join(dir, "artifacts"), in, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(dir, "artifacts", "app-20200401T123456Z.json"); path != expected {
+		t.Errorf("unexpected path %s expected %s", path, expected)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := ioutil.ReadFile(filepath.Join(dir, "artifacts", "app-latest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(latest) {
+		t.Errorf("latest must be same as the artifact %s %s", b, latest)
+	}
+	expected, _ := marshalJSONSorted(in)
+	if string(b) != string(expected) {
+		t.Errorf("unexpected artifact %s", b)
+	}
+}
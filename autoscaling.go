@@ -0,0 +1,43 @@
+package ecspresso
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/pkg/errors"
+)
+
+// desiredCountForAutoScaling returns a desired count to be set to the service,
+// considering a scalable target registered for the service.
+// When respect_auto_scaling is enabled in config, returns nil to keep the count managed by auto scaling.
+func (d *App) desiredCountForAutoScaling(count *int64) (*int64, error) {
+	if count == nil {
+		return nil, nil
+	}
+	resouceId := fmt.Sprintf("service/%s/%s", d.Cluster, d.Service)
+	out, err := d.autoScaling.DescribeScalableTargets(
+		&applicationautoscaling.DescribeScalableTargetsInput{
+			ResourceIds:       []*string{&resouceId},
+			ServiceNamespace:  aws.String("ecs"),
+			ScalableDimension: aws.String("ecs:service:DesiredCount"),
+		},
+	)
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "AccessDeniedException" {
+			d.DebugLog("unable to describe scalable targets. requires IAM for application-autoscaling:Describe* to detect auto-scaling.")
+			return count, nil
+		}
+		return nil, errors.Wrap(err, "failed to describe scalable targets")
+	}
+	if len(out.ScalableTargets) == 0 {
+		return count, nil
+	}
+	if d.config.RespectAutoScaling {
+		d.Log(fmt.Sprintf("Scalable target for %s exists. Desired count %d is not set because respect_auto_scaling is enabled", resouceId, *count))
+		return nil, nil
+	}
+	d.Log(fmt.Sprintf("[WARNING] Scalable target for %s exists. Desired count %d may conflict with auto scaling", resouceId, *count))
+	return count, nil
+}
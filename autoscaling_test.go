@@ -0,0 +1,92 @@
+package ecspresso
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling/applicationautoscalingiface"
+)
+
+type mockAutoScaling struct {
+	applicationautoscalingiface.ApplicationAutoScalingAPI
+	targets []*applicationautoscaling.ScalableTarget
+}
+
+func (m *mockAutoScaling) DescribeScalableTargets(_ *applicationautoscaling.DescribeScalableTargetsInput) (*applicationautoscaling.DescribeScalableTargetsOutput, error) {
+	return &applicationautoscaling.DescribeScalableTargetsOutput{ScalableTargets: m.targets}, nil
+}
+
+func TestDesiredCountForAutoScaling(t *testing.T) {
+	target := &applicationautoscaling.ScalableTarget{ResourceId: aws.String("service/default/test")}
+	for _, c := range []struct {
+		targets  []*applicationautoscaling.ScalableTarget
+		respect  bool
+		count    *int64
+		expected *int64
+	}{
+		{targets: nil, respect: true, count: aws.Int64(3), expected: aws.Int64(3)},
+		{targets: []*applicationautoscaling.ScalableTarget{target}, respect: false, count: aws.Int64(3), expected: aws.Int64(3)},
+		{targets: []*applicationautoscaling.ScalableTarget{target}, respect: true, count: aws.Int64(3), expected: nil},
+		{targets: []*applicationautoscaling.ScalableTarget{target}, respect: true, count: nil, expected: nil},
+	} {
+		app := &App{
+			Service:     "test",
+			Cluster:     "default",
+			autoScaling: &mockAutoScaling{targets: c.targets},
+			config:      &Config{RespectAutoScaling: c.respect},
+		}
+		got, err := app.desiredCountForAutoScaling(c.count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aws.Int64Value(got) != aws.Int64Value(c.expected) || (got == nil) != (c.expected == nil) {
+			t.Errorf("unexpected desired count %v expected %v", got, c.expected)
+		}
+	}
+}
+
+func TestDeployPreserveDesiredCount(t *testing.T) {
+	delayForServiceChanged = 0
+	defer func() { delayForServiceChanged = 3 * time.Second }()
+
+	for _, preserve := range []bool{false, true} {
+		app, err := NewApp(&Config{
+			Region:               "us-east-1",
+			Service:              "test",
+			Cluster:              "default",
+			Timeout:              time.Minute,
+			TaskDefinitionPath:   "tests/td.json",
+			PreserveDesiredCount: preserve,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &mockMultiClusterECS{
+			updated:       make(map[string]string),
+			desiredCounts: make(map[string]*int64),
+		}
+		app.ecs = m
+		app.autoScaling = &mockAutoScaling{}
+		app.sts = &mockSTS{}
+		err = app.Deploy(DeployOption{
+			DryRun:             aws.Bool(false),
+			DesiredCount:       aws.Int64(1),
+			SkipTaskDefinition: aws.Bool(true),
+			ForceNewDeployment: aws.Bool(false),
+			NoWait:             aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := int64(1)
+		if preserve {
+			// the live desired count
+			expected = 4
+		}
+		if got := aws.Int64Value(m.desiredCounts["default"]); got != expected {
+			t.Errorf("unexpected desired count %d expected %d (preserve:%t)", got, expected, preserve)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package ecspresso
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// ContainerDefinitionKeyOrder is the order of the leading keys of container definitions in outputs of render and export.
+// Other keys follow in alphabetical order.
+var ContainerDefinitionKeyOrder = []string{"name", "image", "cpu", "memory", "essential"}
+
+// orderedObject is a JSON object which is marshaled in the order of keys.
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, err := encodeJSON(k, "")
+		if err != nil {
+			return nil, err
+		}
+		vb, err := encodeJSON(o.values[k], "")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeJSON returns JSON of v without escaping HTML characters (e.g. && in commands), as AWS CLI does.
+func encodeJSON(v interface{}, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// canonicalContainerDefinition orders keys of the container definition by ContainerDefinitionKeyOrder.
+// Keys are compared case-insensitively for the properties of CloudFormation.
+func canonicalContainerDefinition(cd map[string]interface{}) orderedObject {
+	rank := func(key string) int {
+		for i, k := range ContainerDefinitionKeyOrder {
+			if strings.EqualFold(k, key) {
+				return i
+			}
+		}
+		return len(ContainerDefinitionKeyOrder)
+	}
+	keys := make([]string, 0, len(cd))
+	for k := range cd {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ri, rj := rank(keys[i]), rank(keys[j]); ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	return orderedObject{keys: keys, values: cd}
+}
+
+// canonicalContainerDefinitions orders keys of each container definition in cds.
+func canonicalContainerDefinitions(cds []interface{}) []interface{} {
+	r := make([]interface{}, 0, len(cds))
+	for _, cd := range cds {
+		if m, ok := cd.(map[string]interface{}); ok {
+			r = append(r, canonicalContainerDefinition(m))
+		} else {
+			r = append(r, cd)
+		}
+	}
+	return r
+}
+
+// canonicalizeTaskDefinition orders keys of container definitions in the task definition JSON object v.
+// Keys of other objects are sorted alphabetically when marshaled.
+func canonicalizeTaskDefinition(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for k, value := range m {
+		if cds, ok := value.([]interface{}); ok && strings.EqualFold(k, "containerDefinitions") {
+			m[k] = canonicalContainerDefinitions(cds)
+		}
+	}
+	return m
+}
+
+// marshalTaskDefinitionCanonical returns JSON of the task definition to be registered in the canonical key order,
+// for committed artifacts. It doesn't affect payloads of API calls.
+func marshalTaskDefinitionCanonical(td *ecs.TaskDefinition) ([]byte, error) {
+	b, err := jsonutil.BuildJSON(registerTaskDefinitionInput(td))
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	b, err = encodeJSON(canonicalizeTaskDefinition(v), "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,88 @@
+package ecspresso
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func testCanonicalTaskDefinition() *ecs.TaskDefinition {
+	return &ecs.TaskDefinition{
+		Family:      aws.String("app"),
+		NetworkMode: aws.String("awsvpc"),
+		Cpu:         aws.String("256"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Command:           aws.StringSlice([]string{"sh", "-c", "migrate && serve"}),
+				Essential:         aws.Bool(true),
+				MemoryReservation: aws.Int64(128),
+				Environment: []*ecs.KeyValuePair{
+					{Name: aws.String("B"), Value: aws.String("2")},
+				},
+				Memory: aws.Int64(256),
+				Image:  aws.String("app:v1"),
+				Cpu:    aws.Int64(128),
+				Name:   aws.String("app"),
+			},
+		},
+	}
+}
+
+// assertKeyOrder asserts keys appear in the order in s.
+func assertKeyOrder(t *testing.T, s string, keys ...string) {
+	t.Helper()
+	last := -1
+	for _, k := range keys {
+		i := strings.Index(s, k)
+		if i < 0 {
+			t.Errorf("%s is not found: %s", k, s)
+			return
+		}
+		if i < last {
+			t.Errorf("%s must be after %s: %s", k, keys, s)
+		}
+		last = i
+	}
+}
+
+func TestMarshalTaskDefinitionCanonical(t *testing.T) {
+	b, err := marshalTaskDefinitionCanonical(testCanonicalTaskDefinition())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	assertKeyOrder(t, s,
+		`"containerDefinitions"`,
+		`"name": "app"`, `"image"`, `"cpu": 128`, `"memory": 256`, `"essential"`,
+		`"command"`, `"environment"`, `"memoryReservation"`,
+		`"cpu": "256"`, `"family"`, `"networkMode"`,
+	)
+	if !strings.Contains(s, `"migrate && serve"`) {
+		t.Errorf("HTML characters must not be escaped: %s", s)
+	}
+	if !strings.HasPrefix(s, "{\n  \"containerDefinitions\": [\n    {\n      \"name\": \"app\",\n") || !strings.HasSuffix(s, "}\n") {
+		t.Errorf("unexpected format: %s", s)
+	}
+
+	b2, _ := marshalTaskDefinitionCanonical(testCanonicalTaskDefinition())
+	if !bytes.Equal(b, b2) {
+		t.Error("output must be stable")
+	}
+}
+
+func TestExportCanonical(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := exportTerraform(&buf, testCanonicalTaskDefinition()); err != nil {
+		t.Fatal(err)
+	}
+	assertKeyOrder(t, buf.String(), `"name"`, `"image"`, `"cpu"`, `"memory"`, `"essential"`, `"command"`, `"environment"`, `"memoryReservation"`)
+
+	buf.Reset()
+	if err := exportCloudFormation(&buf, testCanonicalTaskDefinition()); err != nil {
+		t.Fatal(err)
+	}
+	assertKeyOrder(t, buf.String(), `"Name"`, `"Image"`, `"Cpu": 128`, `"Memory"`, `"Essential"`, `"Command"`, `"Environment"`, `"MemoryReservation"`, `"Cpu": "256"`, `"Family"`)
+}
@@ -0,0 +1,34 @@
+package ecspresso
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// maxClientTokenLength is the maximum length of clientToken of CreateService API.
+const maxClientTokenLength = 36
+
+// createServiceClientToken returns a deterministic clientToken in the form of a UUID
+// derived from the cluster, the service and the deploy ID, so that retries of the same deploy are idempotent.
+func createServiceClientToken(cluster, service, deployID string) string {
+	b := sha256.Sum256([]byte(cluster + "/" + service + "/" + deployID))
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// setClientToken sets clientToken to the service definition.
+// client_token in config takes precedence over clientToken in the service definition.
+func (d *App) setClientToken(svd *ecs.CreateServiceInput) {
+	switch {
+	case d.config.ClientToken != "":
+		svd.ClientToken = aws.String(d.config.ClientToken)
+	case aws.StringValue(svd.ClientToken) != "":
+	default:
+		svd.ClientToken = aws.String(createServiceClientToken(d.Cluster, aws.StringValue(svd.ServiceName), d.deployID))
+	}
+	d.DebugLog("clientToken of create service:", aws.StringValue(svd.ClientToken))
+}
@@ -0,0 +1,82 @@
+package ecspresso
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+func testCreateClientToken(t *testing.T, conf *Config, creates int) []string {
+	t.Helper()
+	conf.Region = "us-east-1"
+	conf.Service = "test"
+	conf.Cluster = "default"
+	conf.Timeout = time.Minute
+	conf.ServiceDefinitionPath = "tests/sv-lb.json"
+	conf.TaskDefinitionPath = "tests/td.json"
+	app, err := NewApp(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockCreateECS{}
+	app.ecs = m
+	var tokens []string
+	for i := 0; i < creates; i++ {
+		err := app.Create(CreateOption{
+			DryRun:       aws.Bool(false),
+			DesiredCount: aws.Int64(1),
+			NoWait:       aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, aws.StringValue(m.created.ClientToken))
+	}
+	return tokens
+}
+
+func TestCreateClientToken(t *testing.T) {
+	tokens := testCreateClientToken(t, &Config{}, 2)
+	if !uuidRegexp.MatchString(tokens[0]) {
+		t.Errorf("clientToken must be in the form of a UUID: %s", tokens[0])
+	}
+	if tokens[0] != tokens[1] {
+		t.Errorf("clientToken must be stable across retries of the same deploy: %v", tokens)
+	}
+
+	a := testCreateClientToken(t, &Config{DeployID: "build-123"}, 1)
+	b := testCreateClientToken(t, &Config{DeployID: "build-123"}, 1)
+	if a[0] != b[0] {
+		t.Errorf("clientToken must be derived from the deploy ID: %s %s", a[0], b[0])
+	}
+	if c := testCreateClientToken(t, &Config{DeployID: "build-124"}, 1); c[0] == a[0] {
+		t.Errorf("clientToken must differ for another deploy: %s", c[0])
+	}
+
+	if d := testCreateClientToken(t, &Config{DeployID: "build-123", ClientToken: "my-token"}, 1); d[0] != "my-token" {
+		t.Errorf("client_token must override clientToken: %s", d[0])
+	}
+}
+
+func TestCreateServiceClientToken(t *testing.T) {
+	a := createServiceClientToken("default", "app", "x")
+	if a != createServiceClientToken("default", "app", "x") {
+		t.Error("clientToken must be deterministic")
+	}
+	if len(a) > maxClientTokenLength {
+		t.Errorf("clientToken %s is too long", a)
+	}
+	for _, b := range []string{
+		createServiceClientToken("other", "app", "x"),
+		createServiceClientToken("default", "web", "x"),
+		createServiceClientToken("default", "app", "y"),
+	} {
+		if a == b {
+			t.Errorf("clientToken must differ %s", b)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package ecspresso
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// OnDemandClusterTagKey is a tag key set to clusters created by ecspresso.
+// The value is the service name which triggered the creation.
+const OnDemandClusterTagKey = "ecspresso:created-for"
+
+func (d *App) createClusterIfMissing(ctx context.Context) error {
+	out, err := d.ecs.DescribeClustersWithContext(ctx, &ecs.DescribeClustersInput{
+		Clusters: []*string{aws.String(d.Cluster)},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to describe cluster")
+	}
+	for _, c := range out.Clusters {
+		// deleted clusters are returned as INACTIVE
+		if aws.StringValue(c.Status) != "INACTIVE" {
+			d.DebugLog("cluster", *c.ClusterName, "already exists")
+			return nil
+		}
+	}
+
+	d.Log("!!! Cluster", d.Cluster, "is not found. Creating a new cluster because create_cluster_if_missing is enabled !!!")
+	in := &ecs.CreateClusterInput{
+		ClusterName: aws.String(d.Cluster),
+		Tags: []*ecs.Tag{
+			{Key: aws.String(OnDemandClusterTagKey), Value: aws.String(d.Service)},
+		},
+	}
+	if len(d.config.ClusterCapacityProviders) > 0 {
+		in.CapacityProviders = aws.StringSlice(d.config.ClusterCapacityProviders)
+	}
+	d.DebugLog(in.String())
+	if _, err := d.ecs.CreateClusterWithContext(ctx, in); err != nil {
+		return err
+	}
+	d.Log("Cluster", d.Cluster, "is created")
+	return nil
+}
@@ -0,0 +1,77 @@
+package ecspresso
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockClusterECS struct {
+	ecsiface.ECSAPI
+	describeOut *ecs.DescribeClustersOutput
+	created     *ecs.CreateClusterInput
+}
+
+func (m *mockClusterECS) DescribeClustersWithContext(_ aws.Context, _ *ecs.DescribeClustersInput, _ ...request.Option) (*ecs.DescribeClustersOutput, error) {
+	return m.describeOut, nil
+}
+
+func (m *mockClusterECS) CreateClusterWithContext(_ aws.Context, in *ecs.CreateClusterInput, _ ...request.Option) (*ecs.CreateClusterOutput, error) {
+	m.created = in
+	return &ecs.CreateClusterOutput{}, nil
+}
+
+func TestCreateClusterIfMissing(t *testing.T) {
+	m := &mockClusterECS{
+		describeOut: &ecs.DescribeClustersOutput{
+			Failures: []*ecs.Failure{
+				{Arn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:cluster/preview"), Reason: aws.String("MISSING")},
+			},
+		},
+	}
+	app := &App{
+		ecs:     m,
+		Service: "test",
+		Cluster: "preview",
+		config: &Config{
+			CreateClusterIfMissing:   true,
+			ClusterCapacityProviders: []string{"FARGATE", "FARGATE_SPOT"},
+		},
+	}
+	if err := app.createClusterIfMissing(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m.created == nil {
+		t.Fatal("cluster was not created")
+	}
+	if *m.created.ClusterName != "preview" {
+		t.Errorf("unexpected cluster name %s", *m.created.ClusterName)
+	}
+	if len(m.created.CapacityProviders) != 2 {
+		t.Errorf("unexpected capacity providers %v", m.created.CapacityProviders)
+	}
+	if tag := m.created.Tags[0]; *tag.Key != OnDemandClusterTagKey || *tag.Value != "test" {
+		t.Errorf("unexpected tag %s", tag.String())
+	}
+}
+
+func TestCreateClusterIfMissingExists(t *testing.T) {
+	m := &mockClusterECS{
+		describeOut: &ecs.DescribeClustersOutput{
+			Clusters: []*ecs.Cluster{
+				{ClusterName: aws.String("preview"), Status: aws.String("ACTIVE")},
+			},
+		},
+	}
+	app := &App{ecs: m, Service: "test", Cluster: "preview", config: &Config{}}
+	if err := app.createClusterIfMissing(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m.created != nil {
+		t.Error("cluster must not be created when exists")
+	}
+}
@@ -7,8 +7,8 @@ import (
 
 	"github.com/alecthomas/kingpin"
 	"github.com/kayac/ecspresso"
-
-	config "github.com/kayac/go-config"
+	isatty "github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
 )
 
 var Version = "current"
@@ -22,6 +22,12 @@ func _main() int {
 
 	conf := kingpin.Flag("config", "config file").String()
 	debug := kingpin.Flag("debug", "enable debug log").Bool()
+	quiet := kingpin.Flag("quiet", "suppress progress logs except errors and final outcome").Bool()
+	allowLatest := kingpin.Flag("allow-latest", "allow mutable image tags even if disallow_mutable_tags is set in config").Bool()
+	allowUnmatched := kingpin.Flag("allow-unmatched", "keep images of containers which are not found in image_manifest in config").Bool()
+	setCommand := kingpin.Flag("set-command", `override the command of the container in the task definition (e.g. app='["sleep","3600"]')`).PlaceHolder("CONTAINER=JSON").StringMap()
+	outputRevision := kingpin.Flag("output-revision", "print only the registered revision number to STDOUT (other outputs are written to STDERR)").Bool()
+	assumeYes := kingpin.Flag("yes", "non-interactive mode. proceed without confirmations except for destructive actions (use --force)").Bool()
 
 	var isSetSuspendAutoScaling bool
 	deploy := kingpin.Command("deploy", "deploy service")
@@ -34,6 +40,8 @@ func _main() int {
 		SuspendAutoScaling: deploy.Flag("suspend-auto-scaling", "set suspend to auto-scaling attached with the ECS service").IsSetByUser(&isSetSuspendAutoScaling).Bool(),
 		RollbackEvents:     deploy.Flag("rollback-events", " rollback when specified events happened (DEPLOYMENT_FAILURE,DEPLOYMENT_STOP_ON_ALARM,DEPLOYMENT_STOP_ON_REQUEST,...) CodeDeploy only.").String(),
 		UpdateService:      deploy.Flag("update-service", "update service attributes by service definition").Bool(),
+		ExitCodeOnDiff:     deploy.Flag("exit-code", "exit with code 2 when any differences are found in dry-run").Bool(),
+		AllowNoChange:      deploy.Flag("allow-no-change", "allow to deploy the same task definition as current even if require_change is set in config").Bool(),
 	}
 
 	refresh := kingpin.Command("refresh", "refresh service. equivalent to deploy --skip-task-definiton --force-new-deployment")
@@ -47,21 +55,78 @@ func _main() int {
 
 	create := kingpin.Command("create", "create service")
 	createOption := ecspresso.CreateOption{
-		DryRun:       create.Flag("dry-run", "dry-run").Bool(),
-		DesiredCount: create.Flag("tasks", "desired count of tasks").Default("1").Int64(),
-		NoWait:       create.Flag("no-wait", "exit ecspresso immediately after just created without waiting for service stable").Bool(),
+		DryRun:               create.Flag("dry-run", "dry-run").Bool(),
+		DesiredCount:         create.Flag("tasks", "desired count of tasks").Default("1").Int64(),
+		NoWait:               create.Flag("no-wait", "exit ecspresso immediately after just created without waiting for service stable").Bool(),
+		DeploymentController: create.Flag("deployment-controller", "deployment controller type of the service").Default("").Enum("", "ECS", "CODE_DEPLOY", "EXTERNAL"),
 	}
 
 	status := kingpin.Command("status", "show status of service")
 	statusOption := ecspresso.StatusOption{
-		Events: status.Flag("events", "show events num").Default("2").Int(),
+		Events:         status.Flag("events", "show events num").Default("2").Int(),
+		Query:          status.Flag("query", "JMESPath query to the describe-services output (like aws cli --query)").Default("").String(),
+		Watch:          status.Flag("watch", "refresh the status every interval (e.g. 10s) until interrupted").Default("0s").Duration(),
+		TaskDefinition: status.Flag("task-definition", "print only the task definition (family:revision) of the service").Bool(),
+	}
+
+	tasks := kingpin.Command("tasks", "list tasks of service")
+	tasksOption := ecspresso.TasksOption{
+		DesiredStatus: tasks.Flag("status", "filter by desired status of tasks").Default("RUNNING").Enum("RUNNING", "STOPPED"),
+		StartedBy:     tasks.Flag("started-by", "filter by startedBy. when specified, lists tasks started with the value instead of tasks of the service").Default("").String(),
+	}
+
+	stopTask := kingpin.Command("stop-task", "stop a task of service")
+	stopTaskOption := ecspresso.StopTaskOption{
+		DryRun: stopTask.Flag("dry-run", "dry-run").Bool(),
+		ID:     stopTask.Flag("id", "task ID or ARN to stop").Required().String(),
+		Reason: stopTask.Flag("reason", "reason for stopping the task").Default("stopped by ecspresso").String(),
+	}
+
+	createTaskSet := kingpin.Command("create-task-set", "register a task definition and create a task set of the service (EXTERNAL deployment controller only)")
+	createTaskSetOption := ecspresso.CreateTaskSetOption{
+		DryRun:     createTaskSet.Flag("dry-run", "dry-run").Bool(),
+		Scale:      createTaskSet.Flag("scale", "scale of the task set in percent of the desired count of the service").Default("100").Float64(),
+		ExternalID: createTaskSet.Flag("external-id", "external ID of the task set").Default("").String(),
+		Promote:    createTaskSet.Flag("promote", "promote the task set to primary after created").Bool(),
+	}
+
+	promoteTaskSet := kingpin.Command("promote-task-set", "promote a task set to primary of the service (EXTERNAL deployment controller only)")
+	promoteTaskSetOption := ecspresso.PromoteTaskSetOption{
+		DryRun: promoteTaskSet.Flag("dry-run", "dry-run").Bool(),
+		ID:     promoteTaskSet.Flag("id", "task set ID or ARN to promote").Required().String(),
+	}
+
+	pruneDeployments := kingpin.Command("prune-deployments", "report deployments stuck in non-primary state, and clear them by a force new deployment")
+	pruneDeploymentsOption := ecspresso.PruneDeploymentsOption{
+		DryRun:    pruneDeployments.Flag("dry-run", "dry-run").Bool(),
+		Fix:       pruneDeployments.Flag("fix", "clear stuck deployments by a force new deployment").Bool(),
+		Threshold: pruneDeployments.Flag("threshold", "duration to regard non-primary deployments as stuck").Default("30m").Duration(),
+	}
+
+	kingpin.Command("whoami", "show the caller identity and the source of credentials")
+
+	revisions := kingpin.Command("revisions", "list recent revisions of the task definition family of the service")
+	revisionsOption := ecspresso.RevisionsOption{
+		DryRun: revisions.Flag("dry-run", "dry-run for the selected revision").Bool(),
+		NoWait: revisions.Flag("no-wait", "exit ecspresso immediately after just deployed the selected revision without waiting for service stable").Bool(),
+		Limit:  revisions.Flag("limit", "number of revisions to list").Default("10").Int64(),
+		Select: revisions.Flag("select", "select a revision to deploy interactively").Bool(),
 	}
 
 	rollback := kingpin.Command("rollback", "rollback service")
 	rollbackOption := ecspresso.RollbackOption{
-		DryRun: rollback.Flag("dry-run", "dry-run").Bool(),
+		DryRun:                   rollback.Flag("dry-run", "dry-run").Bool(),
 		DeregisterTaskDefinition: rollback.Flag("deregister-task-definition", "deregister rolled back task definition").Bool(),
-		NoWait: rollback.Flag("no-wait", "exit ecspresso immediately after just rollbacked without waiting for service stable").Bool(),
+		NoWait:                   rollback.Flag("no-wait", "exit ecspresso immediately after just rollbacked without waiting for service stable").Bool(),
+		ToRevision:               rollback.Flag("to-revision", "rollback to the specified revision of the task definition family").Default("0").Int64(),
+		ToArn:                    rollback.Flag("to-arn", "rollback to the specified task definition ARN").Default("").String(),
+		Force:                    rollback.Flag("force", "allow to rollback to a newer revision than current").Bool(),
+	}
+
+	abort := kingpin.Command("abort", "abort the deployment in progress by rolling back to the previous stable task definition")
+	abortOption := ecspresso.AbortOption{
+		DryRun: abort.Flag("dry-run", "dry-run").Bool(),
+		NoWait: abort.Flag("no-wait", "exit ecspresso immediately after just aborted without waiting for service stable").Bool(),
 	}
 
 	delete := kingpin.Command("delete", "delete service")
@@ -78,6 +143,8 @@ func _main() int {
 		TaskOverrideStr:    run.Flag("overrides", "task overrides JSON string").Default("").String(),
 		SkipTaskDefinition: run.Flag("skip-task-definition", "skip register a new task definition").Bool(),
 		Count:              run.Flag("count", "the number of tasks (max 10)").Default("1").Int64(),
+		StartedBy:          run.Flag("started-by", "startedBy of the task (default ecspresso/$USER)").Default("").String(),
+		PropagateTags:      run.Flag("propagate-tags", "propagate tags of the task definition to the task").Default("").Enum("", "TASK_DEFINITION"),
 	}
 
 	register := kingpin.Command("register", "register task definition")
@@ -86,9 +153,51 @@ func _main() int {
 		Output: register.Flag("output", "output registered task definition").Bool(),
 	}
 
+	diff := kingpin.Command("diff", "display diff of the task definition compared with the one of the service")
+	diffOption := ecspresso.DiffOption{
+		SinceRevision: diff.Flag("since-revision", "compare with the specified revision of the task definition family").Default("0").Int64(),
+		Against:       diff.Flag("against", "base to be compared: running (the task definition of the service), latest-active (the latest ACTIVE revision of the family) or revision:N").Default("running").String(),
+		ShowValues:    diff.Flag("show-values", "show values of environment without masking").Bool(),
+		Format:        diff.Flag("format", "output format: unified or github (annotations of GitHub Actions)").Default("unified").String(),
+	}
+
+	plan := kingpin.Command("plan", "display diff and rendered task definition without deploying. exit with 2 when differences are found")
+	planOption := ecspresso.PlanOption{
+		ShowValues: plan.Flag("show-values", "show values of environment without masking").Bool(),
+		Out:        plan.Flag("out", "save the plan to the file, to be applied by apply").String(),
+	}
+
+	apply := kingpin.Command("apply", "deploy exactly what the plan file saved by plan --out has, aborting when the service has drifted")
+	applyOption := ecspresso.ApplyOption{
+		PlanFile: apply.Arg("plan", "plan file").Required().String(),
+		NoWait:   apply.Flag("no-wait", "exit ecspresso immediately after just deployed without waiting for service stable").Bool(),
+	}
+
+	validate := kingpin.Command("validate", "validate config, task definition and service definition")
+	validateOption := ecspresso.ValidateOption{
+		Local:           validate.Flag("local", "validate locally without any AWS API calls").Bool(),
+		CreateLogGroups: validate.Flag("create-log-groups", "create log groups of awslogs containers which do not exist").Bool(),
+	}
+
+	_ = kingpin.Command("render", "render task definition JSON to be registered")
+	renderOption := ecspresso.RenderOption{}
+
+	export := kingpin.Command("export", "export task definition as a resource of Terraform or CloudFormation")
+	exportOption := ecspresso.ExportOption{
+		Format: export.Flag("format", "output format").Default("terraform").Enum("terraform", "cloudformation"),
+	}
+
 	_ = kingpin.Command("wait", "wait until service stable")
 	waitOption := ecspresso.WaitOption{}
 
+	importCompose := kingpin.Command("import-compose", "create a task definition file by a Docker Compose file")
+	importComposeOption := ecspresso.ImportComposeOption{
+		ComposeFile: importCompose.Arg("compose-file", "Docker Compose file").Required().String(),
+		Family:      importCompose.Flag("family", "family of the task definition").Required().String(),
+		Output:      importCompose.Flag("output", "output task definition file path").Default("ecs-task-def.json").String(),
+		Force:       importCompose.Flag("force", "overwrite the existing file without confirmation").Bool(),
+	}
+
 	init := kingpin.Command("init", "create service/task definition files by existing ECS service")
 	initOption := ecspresso.InitOption{
 		Region:                init.Flag("region", "AWS region name").Required().String(),
@@ -96,6 +205,7 @@ func _main() int {
 		Service:               init.Flag("service", "service name").Required().String(),
 		TaskDefinitionPath:    init.Flag("task-definition-path", "output task definition file path").Default("ecs-task-def.json").String(),
 		ServiceDefinitionPath: init.Flag("service-definition-path", "output service definition file path").Default("ecs-service-def.json").String(),
+		Force:                 init.Flag("force", "overwrite existing files without confirmation").Bool(),
 	}
 
 	sub := kingpin.Parse()
@@ -103,6 +213,7 @@ func _main() int {
 		fmt.Println("ecspresso", Version)
 		return 0
 	}
+	ecspresso.Version = Version
 
 	c := ecspresso.NewDefaultConfig()
 	if sub == "init" {
@@ -112,14 +223,54 @@ func _main() int {
 		c.TaskDefinitionPath = *initOption.TaskDefinitionPath
 		c.ServiceDefinitionPath = *initOption.ServiceDefinitionPath
 		initOption.ConfigFilePath = conf
+	} else if sub == "import-compose" {
+		c.TaskDefinitionPath = *importComposeOption.Output
 	} else {
-		if err := config.LoadWithEnv(c, *conf); err != nil {
-			log.Println("Cloud not load config file", conf, err)
+		var err error
+		if c, err = ecspresso.LoadConfig(*conf); err != nil {
+			log.Println("Cloud not load config file", *conf, err)
 			kingpin.Usage()
 			return 1
 		}
 	}
 
+	if *allowLatest {
+		c.DisallowMutableTags = false
+	}
+	if *allowUnmatched {
+		c.AllowUnmatchedImages = true
+	}
+	if *quiet {
+		c.Quiet = true
+	}
+	if *outputRevision {
+		c.PrintRevision = true
+	}
+	if len(*setCommand) > 0 {
+		overrides, err := ecspresso.ParseCommandOverrides(*setCommand)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		c.CommandOverrides = overrides
+	}
+	if *assumeYes || !isatty.IsTerminal(os.Stdin.Fd()) {
+		c.NonInteractive = true
+	}
+	if c.Quiet && *debug {
+		log.Println("--quiet and --debug are mutually exclusive")
+		return 1
+	}
+
+	if sub == "validate" && *validateOption.Local {
+		if err := ecspresso.ValidateLocal(c); err != nil {
+			log.Printf("%s FAILED. %s", sub, err)
+			return 1
+		}
+		log.Println("Validation passed")
+		return 0
+	}
+
 	app, err := ecspresso.NewApp(c)
 	if err != nil {
 		log.Println(err)
@@ -137,8 +288,24 @@ func _main() int {
 		err = app.Deploy(refreshOption)
 	case "status":
 		err = app.Status(statusOption)
+	case "tasks":
+		err = app.Tasks(tasksOption)
+	case "stop-task":
+		err = app.StopTask(stopTaskOption)
+	case "create-task-set":
+		err = app.CreateTaskSet(createTaskSetOption)
+	case "promote-task-set":
+		err = app.PromoteTaskSet(promoteTaskSetOption)
+	case "prune-deployments":
+		err = app.PruneDeployments(pruneDeploymentsOption)
+	case "whoami":
+		err = app.Whoami()
+	case "revisions":
+		err = app.Revisions(revisionsOption)
 	case "rollback":
 		err = app.Rollback(rollbackOption)
+	case "abort":
+		err = app.Abort(abortOption)
 	case "create":
 		err = app.Create(createOption)
 	case "delete":
@@ -149,14 +316,38 @@ func _main() int {
 		err = app.Wait(waitOption)
 	case "register":
 		err = app.Register(registerOption)
+	case "diff":
+		err = app.Diff(diffOption)
+	case "plan":
+		err = app.Plan(planOption)
+	case "apply":
+		err = app.Apply(applyOption)
+	case "validate":
+		err = app.Validate(validateOption)
+	case "render":
+		err = app.Render(renderOption)
+	case "export":
+		err = app.Export(exportOption)
 	case "init":
 		err = app.Init(initOption)
+	case "import-compose":
+		err = app.ImportCompose(importComposeOption)
 	default:
 		kingpin.Usage()
 		return 1
 	}
+	if errors.Cause(err) == ecspresso.ErrDiffFound {
+		return 2
+	}
 	if err != nil {
-		log.Printf("%s FAILED. %s", sub, err)
+		if b, jerr := app.ErrorJSON(err); c.OutputFormat == ecspresso.OutputFormatJSON && jerr == nil {
+			os.Stdout.Write(b)
+		} else {
+			log.Printf("%s FAILED. %s", sub, app.Mask(err.Error()))
+		}
+		if e, ok := errors.Cause(err).(*ecspresso.ExitCodeError); ok {
+			return e.Code
+		}
 		return 1
 	}
 
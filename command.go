@@ -0,0 +1,46 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// ParseCommandOverrides parses values of --set-command (container name to JSON array of the command).
+func ParseCommandOverrides(m map[string]string) (map[string][]string, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	overrides := make(map[string][]string, len(m))
+	for name, s := range m {
+		var command []string
+		if err := json.Unmarshal([]byte(s), &command); err != nil {
+			return nil, fmt.Errorf("command of container %s must be a JSON array of strings: %s", name, s)
+		}
+		overrides[name] = command
+	}
+	return overrides, nil
+}
+
+// applyCommandOverrides rewrites commands of the containers in the task definition.
+func applyCommandOverrides(td *ecs.TaskDefinition, overrides map[string][]string) error {
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+CONTAINERS:
+	for _, name := range names {
+		for _, c := range td.ContainerDefinitions {
+			if aws.StringValue(c.Name) == name {
+				c.Command = aws.StringSlice(overrides[name])
+				continue CONTAINERS
+			}
+		}
+		return fmt.Errorf("container %s to override the command is not found in the task definition", name)
+	}
+	return nil
+}
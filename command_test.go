@@ -0,0 +1,55 @@
+package ecspresso
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestParseCommandOverrides(t *testing.T) {
+	overrides, err := ParseCommandOverrides(map[string]string{"app": `["sleep","3600"]`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := overrides["app"]; len(c) != 2 || c[0] != "sleep" || c[1] != "3600" {
+		t.Errorf("unexpected command %v", c)
+	}
+	for _, s := range []string{`sleep 3600`, `["sleep",3600]`, `{"command":"sleep"}`, `["sleep"`} {
+		if _, err := ParseCommandOverrides(map[string]string{"app": s}); err == nil || !strings.Contains(err.Error(), "command of container app must be a JSON array of strings") {
+			t.Errorf("%s must be invalid: %v", s, err)
+		}
+	}
+}
+
+func TestCommandOverrides(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:             "ap-northeast-1",
+		Timeout:            time.Minute,
+		Service:            "test",
+		Cluster:            "default",
+		TaskDefinitionPath: "tests/td.json",
+		CommandOverrides:   map[string][]string{"katsubushi": {"sleep", "3600"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	td, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range td.ContainerDefinitions {
+		if aws.StringValue(c.Name) != "katsubushi" {
+			continue
+		}
+		if cmd := aws.StringValueSlice(c.Command); strings.Join(cmd, " ") != "sleep 3600" {
+			t.Errorf("unexpected command %v", cmd)
+		}
+	}
+
+	app.config.CommandOverrides = map[string][]string{"unknown": {"sleep"}}
+	if _, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath); err == nil || !strings.Contains(err.Error(), "container unknown to override the command is not found") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package ecspresso
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// composeSupportedKeys are keys of compose services which are converted to container definitions.
+var composeSupportedKeys = map[string]bool{
+	"image":       true,
+	"ports":       true,
+	"environment": true,
+	"depends_on":  true,
+	"healthcheck": true,
+	"command":     true,
+	"entrypoint":  true,
+	"working_dir": true,
+}
+
+type composeFile struct {
+	Services map[string]map[string]interface{} `yaml:"services"`
+}
+
+// ImportCompose converts services in a Docker Compose file to container definitions,
+// and saves them as a task definition file.
+func (d *App) ImportCompose(opt ImportComposeOption) error {
+	b, err := ioutil.ReadFile(*opt.ComposeFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to read compose file")
+	}
+	td, warnings, err := composeToTaskDefinition(b, *opt.Family)
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert %s", *opt.ComposeFile)
+	}
+	for _, w := range warnings {
+		d.Log("[WARNING]", w)
+	}
+	out, err := MarshalJSON(td)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal task definition to JSON")
+	}
+	d.Log("save task definition to", *opt.Output)
+	if err := d.saveFile(*opt.Output, out, CreateFileMode, aws.BoolValue(opt.Force)); err != nil {
+		return errors.Wrap(err, "failed to write file")
+	}
+	return nil
+}
+
+// composeToTaskDefinition converts the common subset of a Docker Compose file to a task definition.
+// It returns warnings for compose features which have no ECS equivalent.
+func composeToTaskDefinition(src []byte, family string) (*ecs.RegisterTaskDefinitionInput, []string, error) {
+	var cf composeFile
+	if err := yaml.Unmarshal(src, &cf); err != nil {
+		return nil, nil, err
+	}
+	if len(cf.Services) == 0 {
+		return nil, nil, errors.New("no services are defined")
+	}
+	names := make([]string, 0, len(cf.Services))
+	for name := range cf.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	td := &ecs.RegisterTaskDefinitionInput{Family: aws.String(family)}
+	var warnings []string
+	for _, name := range names {
+		c, ws, err := composeServiceToContainer(name, cf.Services[name])
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "service %s", name)
+		}
+		td.ContainerDefinitions = append(td.ContainerDefinitions, c)
+		warnings = append(warnings, ws...)
+	}
+	return td, warnings, nil
+}
+
+func composeServiceToContainer(name string, sv map[string]interface{}) (*ecs.ContainerDefinition, []string, error) {
+	c := &ecs.ContainerDefinition{
+		Name:      aws.String(name),
+		Essential: aws.Bool(true),
+	}
+	var warnings []string
+	keys := make([]string, 0, len(sv))
+	for key := range sv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !composeSupportedKeys[key] {
+			warnings = append(warnings, fmt.Sprintf("service %s: %s is not supported. ignored", name, key))
+		}
+	}
+
+	if image, ok := sv["image"].(string); ok {
+		c.Image = aws.String(image)
+	} else {
+		return nil, nil, errors.New("image is required")
+	}
+	if v, ok := sv["working_dir"].(string); ok {
+		c.WorkingDirectory = aws.String(v)
+	}
+	var err error
+	if c.Command, err = composeStringList(sv["command"]); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid command")
+	}
+	if c.EntryPoint, err = composeStringList(sv["entrypoint"]); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid entrypoint")
+	}
+	if c.PortMappings, err = composePortMappings(sv["ports"]); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid ports")
+	}
+	if c.Environment, err = composeEnvironment(sv["environment"]); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid environment")
+	}
+	if c.DependsOn, err = composeDependsOn(sv["depends_on"]); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid depends_on")
+	}
+	if hc, ok := sv["healthcheck"]; ok {
+		if c.HealthCheck, err = composeHealthCheck(hc); err != nil {
+			return nil, nil, errors.Wrap(err, "invalid healthcheck")
+		}
+	}
+	return c, warnings, nil
+}
+
+// composeStringList converts a string (split by spaces) or a list of strings.
+func composeStringList(v interface{}) ([]*string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return aws.StringSlice(strings.Fields(v)), nil
+	case []interface{}:
+		ss := make([]*string, 0, len(v))
+		for _, s := range v {
+			ss = append(ss, aws.String(fmt.Sprint(s)))
+		}
+		return ss, nil
+	default:
+		return nil, fmt.Errorf("unexpected value %v", v)
+	}
+}
+
+// composePortMappings converts short syntax ports like "8080:80", "80" and "53:53/udp".
+// The host port is ignored because it must be same as the container port for awsvpc network mode.
+func composePortMappings(v interface{}) ([]*ecs.PortMapping, error) {
+	ports, ok := v.([]interface{})
+	if v == nil {
+		return nil, nil
+	} else if !ok {
+		return nil, fmt.Errorf("unexpected value %v", v)
+	}
+	var pms []*ecs.PortMapping
+	for _, p := range ports {
+		s := fmt.Sprint(p)
+		protocol := ecs.TransportProtocolTcp
+		if i := strings.Index(s, "/"); i != -1 {
+			protocol = s[i+1:]
+			s = s[:i]
+		}
+		parts := strings.Split(s, ":")
+		port, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported port %v", p)
+		}
+		pms = append(pms, &ecs.PortMapping{
+			ContainerPort: aws.Int64(port),
+			Protocol:      aws.String(protocol),
+		})
+	}
+	return pms, nil
+}
+
+// composeEnvironment converts a map or a list of "KEY=VALUE".
+func composeEnvironment(v interface{}) ([]*ecs.KeyValuePair, error) {
+	var env []*ecs.KeyValuePair
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case map[interface{}]interface{}:
+		for k, val := range v {
+			s := ""
+			if val != nil {
+				s = fmt.Sprint(val)
+			}
+			env = append(env, &ecs.KeyValuePair{Name: aws.String(fmt.Sprint(k)), Value: aws.String(s)})
+		}
+	case []interface{}:
+		for _, kv := range v {
+			pair := strings.SplitN(fmt.Sprint(kv), "=", 2)
+			if len(pair) == 1 {
+				pair = append(pair, "")
+			}
+			env = append(env, &ecs.KeyValuePair{Name: aws.String(pair[0]), Value: aws.String(pair[1])})
+		}
+	default:
+		return nil, fmt.Errorf("unexpected value %v", v)
+	}
+	sort.Slice(env, func(i, j int) bool { return *env[i].Name < *env[j].Name })
+	return env, nil
+}
+
+var composeDependsOnConditions = map[string]string{
+	"service_started":                ecs.ContainerConditionStart,
+	"service_healthy":                ecs.ContainerConditionHealthy,
+	"service_completed_successfully": ecs.ContainerConditionSuccess,
+}
+
+// composeDependsOn converts a list of services or a map of services with conditions.
+func composeDependsOn(v interface{}) ([]*ecs.ContainerDependency, error) {
+	var deps []*ecs.ContainerDependency
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		for _, name := range v {
+			deps = append(deps, &ecs.ContainerDependency{
+				ContainerName: aws.String(fmt.Sprint(name)),
+				Condition:     aws.String(ecs.ContainerConditionStart),
+			})
+		}
+	case map[interface{}]interface{}:
+		for name, opts := range v {
+			condition := ecs.ContainerConditionStart
+			if m, ok := opts.(map[interface{}]interface{}); ok {
+				if c, ok := m["condition"].(string); ok {
+					if condition, ok = composeDependsOnConditions[c]; !ok {
+						return nil, fmt.Errorf("unsupported condition %s", c)
+					}
+				}
+			}
+			deps = append(deps, &ecs.ContainerDependency{
+				ContainerName: aws.String(fmt.Sprint(name)),
+				Condition:     aws.String(condition),
+			})
+		}
+		sort.Slice(deps, func(i, j int) bool { return *deps[i].ContainerName < *deps[j].ContainerName })
+	default:
+		return nil, fmt.Errorf("unexpected value %v", v)
+	}
+	return deps, nil
+}
+
+func composeHealthCheck(v interface{}) (*ecs.HealthCheck, error) {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected value %v", v)
+	}
+	hc := &ecs.HealthCheck{}
+	switch test := m["test"].(type) {
+	case string:
+		hc.Command = aws.StringSlice([]string{"CMD-SHELL", test})
+	case []interface{}:
+		for _, s := range test {
+			hc.Command = append(hc.Command, aws.String(fmt.Sprint(s)))
+		}
+	default:
+		return nil, errors.New("test is required")
+	}
+	for key, field := range map[string]**int64{
+		"interval":     &hc.Interval,
+		"timeout":      &hc.Timeout,
+		"start_period": &hc.StartPeriod,
+	} {
+		s, ok := m[key].(string)
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", key)
+		}
+		*field = aws.Int64(int64(d / time.Second))
+	}
+	if retries, ok := m["retries"].(int); ok {
+		hc.Retries = aws.Int64(int64(retries))
+	}
+	return hc, nil
+}
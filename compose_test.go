@@ -0,0 +1,95 @@
+package ecspresso
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+var testComposeFile = `
+version: "3.8"
+services:
+  web:
+    image: nginx:1.19
+    ports:
+      - "8080:80"
+      - 443
+    environment:
+      APP_ENV: production
+      DEBUG:
+    depends_on:
+      app:
+        condition: service_healthy
+    volumes:
+      - ./html:/usr/share/nginx/html
+  app:
+    image: myapp:v1
+    command: ["./app", "--port", "3000"]
+    working_dir: /app
+    environment:
+      - DB_HOST=db
+      - DB_PORT=5432
+    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost:3000/"]
+      interval: 30s
+      timeout: 5s
+      retries: 3
+      start_period: 1m
+    build: .
+`
+
+func TestComposeToTaskDefinition(t *testing.T) {
+	td, warnings, err := composeToTaskDefinition([]byte(testComposeFile), "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *td.Family != "myapp" || len(td.ContainerDefinitions) != 2 {
+		t.Fatalf("unexpected task definition %s", td)
+	}
+	app, web := td.ContainerDefinitions[0], td.ContainerDefinitions[1]
+
+	if *app.Name != "app" || *app.Image != "myapp:v1" || *app.WorkingDirectory != "/app" {
+		t.Errorf("unexpected container %s", app)
+	}
+	if got := strings.Join(aws.StringValueSlice(app.Command), " "); got != "./app --port 3000" {
+		t.Errorf("unexpected command %s", got)
+	}
+	if len(app.Environment) != 2 || *app.Environment[0].Name != "DB_HOST" || *app.Environment[0].Value != "db" {
+		t.Errorf("unexpected environment %v", app.Environment)
+	}
+	hc := app.HealthCheck
+	if hc == nil || len(hc.Command) != 4 || *hc.Interval != 30 || *hc.Timeout != 5 || *hc.Retries != 3 || *hc.StartPeriod != 60 {
+		t.Errorf("unexpected healthCheck %s", hc)
+	}
+
+	if len(web.PortMappings) != 2 || *web.PortMappings[0].ContainerPort != 80 || *web.PortMappings[1].ContainerPort != 443 {
+		t.Errorf("unexpected portMappings %v", web.PortMappings)
+	}
+	if len(web.Environment) != 2 || *web.Environment[1].Name != "DEBUG" || *web.Environment[1].Value != "" {
+		t.Errorf("unexpected environment %v", web.Environment)
+	}
+	if len(web.DependsOn) != 1 || *web.DependsOn[0].ContainerName != "app" || *web.DependsOn[0].Condition != "HEALTHY" {
+		t.Errorf("unexpected dependsOn %v", web.DependsOn)
+	}
+
+	expected := []string{
+		"service app: build is not supported. ignored",
+		"service web: volumes is not supported. ignored",
+	}
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
+
+func TestComposeToTaskDefinitionInvalid(t *testing.T) {
+	for _, src := range []string{
+		`services: {}`,
+		"services:\n  app:\n    command: ./app\n",
+		"services:\n  app:\n    image: app\n    ports: [\"8080-8081:80-81\"]\n",
+	} {
+		if _, _, err := composeToTaskDefinition([]byte(src), "app"); err == nil {
+			t.Errorf("must be an error: %s", src)
+		}
+	}
+}
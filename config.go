@@ -2,41 +2,199 @@ package ecspresso
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
 	"text/template"
 	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
 )
 
 const (
 	DefaultClusterName = "default"
 	DefaultTimeout     = 10 * time.Minute
+
+	DefaultPollIntervalMin = 2 * time.Second
+	DefaultPollIntervalMax = 30 * time.Second
 )
 
+var accountIDRegexp = regexp.MustCompile(`^[0-9]{12}$`)
+
 type Config struct {
 	Region                string         `yaml:"region"`
 	Cluster               string         `yaml:"cluster"`
 	Service               string         `yaml:"service"`
 	ServiceDefinitionPath string         `yaml:"service_definition"`
 	TaskDefinitionPath    string         `yaml:"task_definition"`
+	PatchPath             string         `yaml:"patch,omitempty"`
 	Timeout               time.Duration  `yaml:"timeout"`
 	Plugins               []ConfigPlugin `yaml:"plugins"`
 
+	RegisterTimeout time.Duration `yaml:"register_timeout,omitempty"`
+	WaitTimeout     time.Duration `yaml:"wait_timeout,omitempty"`
+
+	EmitCloudWatchMetrics bool   `yaml:"emit_cloudwatch_metrics,omitempty"`
+	MetricsNamespace      string `yaml:"metrics_namespace,omitempty"`
+
+	CreateClusterIfMissing   bool     `yaml:"create_cluster_if_missing,omitempty"`
+	ClusterCapacityProviders []string `yaml:"cluster_capacity_providers,omitempty"`
+
+	DisallowMutableTags bool `yaml:"disallow_mutable_tags,omitempty"`
+
+	DeploymentController string `yaml:"deployment_controller,omitempty"`
+
+	RespectAutoScaling bool `yaml:"respect_auto_scaling,omitempty"`
+
+	Quiet bool `yaml:"quiet,omitempty"`
+
+	RampSteps []int64 `yaml:"ramp_steps,omitempty"`
+
+	PollIntervalMin time.Duration `yaml:"poll_interval_min,omitempty"`
+	PollIntervalMax time.Duration `yaml:"poll_interval_max,omitempty"`
+
+	DeployRoleARN string `yaml:"deploy_role_arn,omitempty"`
+
+	DeployID string `yaml:"deploy_id,omitempty"`
+
+	EnvPrefix string `yaml:"env_prefix,omitempty"`
+
+	ArtifactDir string `yaml:"artifact_dir,omitempty"`
+
+	MinStableDuration time.Duration `yaml:"min_stable_duration,omitempty"`
+
+	Clusters         []string `yaml:"clusters,omitempty"`
+	ClustersFailFast bool     `yaml:"clusters_fail_fast,omitempty"`
+
+	PrettyPrint bool `yaml:"pretty_print,omitempty"`
+
+	ResolveImageDigests bool `yaml:"resolve_image_digests,omitempty"`
+
+	EndpointURL string `yaml:"endpoint_url,omitempty"`
+
+	Tags        map[string]string `yaml:"tags,omitempty"`
+	DefaultTags map[string]string `yaml:"default_tags,omitempty"`
+
+	PreserveDesiredCount bool `yaml:"preserve_desired_count,omitempty"`
+
+	OutputFormat string `yaml:"output_format,omitempty"`
+
+	FamilySuffix string `yaml:"family_suffix,omitempty"`
+
+	NonInteractive bool `yaml:"non_interactive,omitempty"`
+
+	OTLPEndpoint string `yaml:"otlp_endpoint,omitempty"`
+
+	CommandOverrides map[string][]string `yaml:"command_overrides,omitempty"`
+
+	LogFile   string `yaml:"log_file,omitempty"`
+	LogSyslog bool   `yaml:"log_syslog,omitempty"`
+
+	ExpectedAccountID string `yaml:"expected_account_id,omitempty"`
+
+	PostStableTargetHealthCheck bool `yaml:"post_stable_target_health_check,omitempty"`
+
+	PostStableContainerHealthCheck bool   `yaml:"post_stable_container_health_check,omitempty"`
+	PrimaryContainer               string `yaml:"primary_container,omitempty"`
+
+	WaitForSteadyStateEvent bool `yaml:"wait_for_steady_state_event,omitempty"`
+
+	RequireChange bool `yaml:"require_change,omitempty"`
+
+	PrintRevision bool `yaml:"print_revision,omitempty"`
+
+	OrphanWindow time.Duration `yaml:"orphan_window,omitempty"`
+
+	KeepActiveRevisions int `yaml:"keep_active_revisions,omitempty"`
+
+	NotifySNSTopicARN string `yaml:"notify_sns_topic_arn,omitempty"`
+
+	MaskAccountIDs bool `yaml:"mask_account_ids,omitempty"`
+
+	EventBufferSize int `yaml:"event_buffer_size,omitempty"`
+
+	PostDeployProbeURL    string `yaml:"post_deploy_probe_url,omitempty"`
+	PostDeployProbeStatus int    `yaml:"post_deploy_probe_status,omitempty"`
+	PostDeployProbeHost   string `yaml:"post_deploy_probe_host,omitempty"`
+
+	ImageManifestPath    string `yaml:"image_manifest,omitempty"`
+	AllowUnmatchedImages bool   `yaml:"allow_unmatched_images,omitempty"`
+
+	SpotWithFallback *SpotWithFallback `yaml:"spot_with_fallback,omitempty"`
+
+	RecordDeployHistory bool `yaml:"record_deploy_history,omitempty"`
+	DeployHistoryLimit  int  `yaml:"deploy_history_limit,omitempty"`
+
+	ClientID  string `yaml:"client_id,omitempty"`
+	UserAgent string `yaml:"user_agent,omitempty"`
+
+	ClientToken string `yaml:"client_token,omitempty"`
+
 	templateFuncs []template.FuncMap
+	pluginsSetUp  bool
 }
 
 func (c *Config) Validate() error {
 	if c.Cluster == "" {
 		c.Cluster = DefaultClusterName
 	}
-	if c.TaskDefinitionPath == "" {
-		return errors.New("task_definition is not defined")
+	if c.PollIntervalMin <= 0 {
+		c.PollIntervalMin = DefaultPollIntervalMin
+	}
+	if c.PollIntervalMax <= 0 {
+		c.PollIntervalMax = DefaultPollIntervalMax
 	}
-	for _, p := range c.Plugins {
-		if err := p.Setup(c); err != nil {
-			return err
+	if c.PollIntervalMin > c.PollIntervalMax {
+		return errors.New("poll_interval_min must not be greater than poll_interval_max")
+	}
+	if c.DeployRoleARN != "" {
+		if a, err := arn.Parse(c.DeployRoleARN); err != nil || a.Service != "iam" || !strings.HasPrefix(a.Resource, "role/") {
+			return fmt.Errorf("deploy_role_arn %s is not an IAM role ARN", c.DeployRoleARN)
+		}
+	}
+	if c.EndpointURL != "" {
+		if u, err := url.Parse(c.EndpointURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint_url %s is not a valid http(s) URL", c.EndpointURL)
+		}
+	}
+	if c.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("otlp_endpoint %s is not a valid http(s) URL", c.OTLPEndpoint)
+		}
+	}
+	if c.PostDeployProbeURL != "" {
+		if u, err := url.Parse(c.PostDeployProbeURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("post_deploy_probe_url %s is not a valid http(s) URL", c.PostDeployProbeURL)
+		}
+	}
+	if s := c.PostDeployProbeStatus; s != 0 && (s < 100 || s > 599) {
+		return fmt.Errorf("post_deploy_probe_status %d is not a valid HTTP status code", s)
+	}
+	if c.SpotWithFallback != nil {
+		if err := c.SpotWithFallback.Validate(); err != nil {
+			return fmt.Errorf("invalid spot_with_fallback: %s", err)
 		}
 	}
-	return nil
+	if n := c.DeployHistoryLimit; n < 0 || n >= maxTags {
+		return fmt.Errorf("deploy_history_limit must be between 0 and %d", maxTags-1)
+	}
+	if len(c.ClientToken) > maxClientTokenLength {
+		return fmt.Errorf("client_token must be up to %d characters", maxClientTokenLength)
+	}
+	if c.ExpectedAccountID != "" && !accountIDRegexp.MatchString(c.ExpectedAccountID) {
+		return fmt.Errorf("expected_account_id %s is not a 12-digit AWS account ID", c.ExpectedAccountID)
+	}
+	switch c.OutputFormat {
+	case "", OutputFormatText, OutputFormatJSON:
+	default:
+		return fmt.Errorf("output_format must be %s or %s", OutputFormatText, OutputFormatJSON)
+	}
+	if c.TaskDefinitionPath == "" && c.PatchPath == "" {
+		return errors.New("task_definition is not defined")
+	}
+	return c.setupPlugins()
 }
 
 func NewDefaultConfig() *Config {
@@ -1,8 +1,12 @@
 package ecspresso_test
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -38,6 +42,51 @@ func TestLoadServiceDefinition(t *testing.T) {
 	}
 }
 
+func TestLoadServiceDefinitionRoundTrip(t *testing.T) {
+	app, err := ecspresso.NewApp(&ecspresso.Config{
+		Region:             "ap-northeast-1",
+		Timeout:            300 * time.Second,
+		Service:            "test",
+		Cluster:            "default2",
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := app.LoadServiceDefinition("tests/sv.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err := app.LoadServiceDefinition("tests/sv.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		t.Errorf("service definitions loaded from JSON and YAML are different\n%s\n%s", fromJSON, fromYAML)
+	}
+
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b, err := json.Marshal(fromJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "service.json")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := app.LoadServiceDefinition(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON, reloaded) {
+		t.Errorf("service definition is changed by round trip\n%s\n%s", fromJSON, reloaded)
+	}
+}
+
 func TestLoadConfigWithPlugin(t *testing.T) {
 	dir, _ := os.Getwd()
 	defer os.Chdir(dir)
@@ -81,3 +130,75 @@ func TestLoadConfigWithPlugin(t *testing.T) {
 		t.Errorf("unexpected image got:%s", image)
 	}
 }
+
+func TestLoadTemplatedConfig(t *testing.T) {
+	dir, _ := os.Getwd()
+	defer os.Chdir(dir)
+	os.Chdir(filepath.Join(dir, "tests"))
+	if v, ok := os.LookupEnv("CLUSTER"); ok {
+		defer os.Setenv("CLUSTER", v)
+	} else {
+		defer os.Unsetenv("CLUSTER")
+	}
+	os.Setenv("CLUSTER", "staging")
+
+	conf, err := ecspresso.LoadConfig("config-templated.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Cluster != "staging" {
+		t.Errorf("unexpected cluster %s", conf.Cluster)
+	}
+	if conf.Service != "test-ap-northeast-1" {
+		t.Errorf("unexpected service %s", conf.Service)
+	}
+	if conf.DeployID != "subnet-07ac54af5e41a4fc4" {
+		t.Errorf("unexpected deploy_id by tfstate %s", conf.DeployID)
+	}
+	if conf.Timeout != 10*time.Minute {
+		t.Errorf("unexpected timeout %s", conf.Timeout)
+	}
+
+	tmp, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	path := filepath.Join(tmp, "config-unknown.yaml")
+	if err := ioutil.WriteFile(path, []byte("region: ap-northeast-1\ntask_definition: ecs-task-def.json\ntask_defintion: typo.json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ecspresso.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "unknown keys: task_defintion") {
+		t.Errorf("unknown keys must be rejected: %v", err)
+	}
+}
+
+func TestLoadConfigWithEnvPrefix(t *testing.T) {
+	for _, key := range []string{"CLUSTER", "ECSPRESSO_CLUSTER"} {
+		if v, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, v)
+		} else {
+			defer os.Unsetenv(key)
+		}
+	}
+	os.Unsetenv("CLUSTER")
+	os.Setenv("ECSPRESSO_CLUSTER", "staging")
+
+	tmp, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	path := filepath.Join(tmp, "config.yaml")
+	src := "region: ap-northeast-1\nenv_prefix: ECSPRESSO_\ncluster: '{{ must_env `CLUSTER` }}'\ntask_definition: ecs-task-def.json\n"
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := ecspresso.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Cluster != "staging" {
+		t.Errorf("unexpected cluster %s", conf.Cluster)
+	}
+}
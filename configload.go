@@ -0,0 +1,139 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/kayac/go-config"
+	"github.com/pkg/errors"
+)
+
+// configPlaceholderFuncs are template functions to render the config file before plugins are set up.
+var configPlaceholderFuncs = template.FuncMap{
+	"tfstate":        func(string) string { return "" },
+	"aws_account_id": func() string { return "" },
+	"aws_region":     func() string { return "" },
+}
+
+// configEnvPlaceholderFuncs are env and must_env template functions which never fail,
+// to read env_prefix from the config file before resolving environment variables.
+var configEnvPlaceholderFuncs = template.FuncMap{
+	"env":      func(...string) string { return "" },
+	"must_env": func(string) string { return "" },
+}
+
+// LoadConfig loads the config file in YAML rendered by the same template functions as the task definition:
+// env and must_env (with env_prefix), tfstate (by the tfstate plugin), aws_account_id and aws_region.
+//
+// The config file is rendered in passes. At first, env_prefix is read with placeholders of all functions.
+// Next, it is rendered with the env functions (with env_prefix) and placeholders of others to resolve plugins
+// and the settings for AWS. Then it is rendered with the functions and decoded. Unknown keys are rejected.
+// Plugins are set up only once, and Validate doesn't set up them again.
+func LoadConfig(path string) (*Config, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s read failed", path)
+	}
+
+	var prefix struct {
+		EnvPrefix string `yaml:"env_prefix"`
+	}
+	l := config.New()
+	l.Funcs(configEnvPlaceholderFuncs)
+	l.Funcs(configPlaceholderFuncs)
+	if err := l.LoadWithEnvBytes(&prefix, src); err != nil {
+		return nil, errors.Wrapf(err, "%s load failed", path)
+	}
+
+	pre := NewDefaultConfig()
+	l = newLoader(&Config{EnvPrefix: prefix.EnvPrefix})
+	l.Funcs(configPlaceholderFuncs)
+	if err := l.LoadWithEnvBytes(pre, src); err != nil {
+		return nil, errors.Wrapf(err, "%s load failed", path)
+	}
+	if err := pre.setupPlugins(); err != nil {
+		return nil, err
+	}
+
+	l = newLoader(pre)
+	l.Funcs(configAWSTemplateFuncs(pre))
+	c := NewDefaultConfig()
+	if err := l.LoadWithEnvBytes(c, src); err != nil {
+		return nil, errors.Wrapf(err, "%s load failed", path)
+	}
+	c.templateFuncs = pre.templateFuncs
+	c.pluginsSetUp = true
+	var keys map[string]interface{}
+	if err := l.LoadWithEnvBytes(&keys, src); err != nil {
+		return nil, errors.Wrapf(err, "%s load failed", path)
+	}
+	if unknown := unknownConfigKeys(keys); len(unknown) > 0 {
+		return nil, fmt.Errorf("%s has unknown keys: %s", path, strings.Join(unknown, ", "))
+	}
+	return c, nil
+}
+
+// unknownConfigKeys returns keys which are not defined in Config.
+func unknownConfigKeys(keys map[string]interface{}) []string {
+	known := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("yaml")
+		if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+	var unknown []string
+	for k := range keys {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// configAWSTemplateFuncs returns aws_account_id and aws_region template functions for the config file.
+// The account ID is resolved by STS GetCallerIdentity (as deploy_role_arn if set) only when used.
+func configAWSTemplateFuncs(c *Config) template.FuncMap {
+	var accountID string
+	return template.FuncMap{
+		"aws_account_id": func() (string, error) {
+			if accountID != "" {
+				return accountID, nil
+			}
+			awsConfig := aws.Config{Region: aws.String(c.Region)}
+			if c.EndpointURL != "" {
+				awsConfig.Endpoint = aws.String(c.EndpointURL)
+			}
+			sess, err := session.NewSessionWithOptions(session.Options{
+				Config:            awsConfig,
+				SharedConfigState: session.SharedConfigEnable,
+			})
+			if err != nil {
+				return "", err
+			}
+			if c.DeployRoleARN != "" {
+				sess = sess.Copy(&aws.Config{Credentials: stscreds.NewCredentials(sess, c.DeployRoleARN)})
+			}
+			out, err := sts.New(sess).GetCallerIdentityWithContext(context.Background(), &sts.GetCallerIdentityInput{})
+			if err != nil {
+				return "", errors.Wrap(err, "failed to get caller identity for aws_account_id")
+			}
+			accountID = aws.StringValue(out.Account)
+			return accountID, nil
+		},
+		"aws_region": func() string {
+			return c.Region
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package ecspresso
+
+import (
+	"github.com/Songmu/prompter"
+)
+
+// prompt functions. replaced in tests.
+var (
+	promptYN   = prompter.YN
+	promptText = prompter.Prompt
+)
+
+// confirm asks the prompt and reports whether to proceed.
+// When expected is empty, a yes/no question is asked, otherwise the answer must be equal to expected.
+//
+// In the non-interactive mode, confirm never prompts. It proceeds for non-destructive actions,
+// and doesn't for destructive ones (use --force to proceed without confirmations).
+func (d *App) confirm(prompt string, expected string, destructive bool) bool {
+	if d.config.NonInteractive {
+		if destructive {
+			d.Log("[WARNING]", prompt, "is not confirmed in non-interactive mode")
+			return false
+		}
+		d.DebugLog(prompt, "is confirmed automatically in non-interactive mode")
+		return true
+	}
+	if expected == "" {
+		return promptYN(prompt, false)
+	}
+	return promptText(prompt, "") == expected
+}
@@ -0,0 +1,38 @@
+package ecspresso
+
+import "testing"
+
+func TestConfirm(t *testing.T) {
+	defer func(yn func(string, bool) bool, text func(string, string) string) {
+		promptYN, promptText = yn, text
+	}(promptYN, promptText)
+	var prompted int
+	promptYN = func(string, bool) bool { prompted++; return true }
+	promptText = func(string, string) string { prompted++; return "app" }
+
+	d := &App{config: &Config{}}
+	if !d.confirm("Overwrite?", "", false) {
+		t.Error("yes must be confirmed")
+	}
+	if !d.confirm("Enter the service name", "app", true) {
+		t.Error("expected answer must be confirmed")
+	}
+	if d.confirm("Enter the service name", "other", true) {
+		t.Error("unexpected answer must not be confirmed")
+	}
+	if prompted != 3 {
+		t.Errorf("unexpected prompted %d", prompted)
+	}
+
+	prompted = 0
+	d.config.NonInteractive = true
+	if !d.confirm("Overwrite?", "", false) {
+		t.Error("non-destructive actions must proceed in non-interactive mode")
+	}
+	if d.confirm("Enter the service name", "app", true) {
+		t.Error("destructive actions must not proceed in non-interactive mode")
+	}
+	if prompted != 0 {
+		t.Errorf("must not prompt in non-interactive mode: %d", prompted)
+	}
+}
@@ -0,0 +1,130 @@
+package ecspresso
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockCreateECS struct {
+	ecsiface.ECSAPI
+	registered      bool
+	registeredTags  []*ecs.Tag
+	registeredInput *ecs.RegisterTaskDefinitionInput
+	created         *ecs.CreateServiceInput
+}
+
+func (m *mockCreateECS) RegisterTaskDefinitionWithContext(_ aws.Context, in *ecs.RegisterTaskDefinitionInput, _ ...request.Option) (*ecs.RegisterTaskDefinitionOutput, error) {
+	m.registered = true
+	m.registeredTags = in.Tags
+	m.registeredInput = in
+	return &ecs.RegisterTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			Family:            in.Family,
+			Revision:          aws.Int64(1),
+			TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/" + *in.Family + ":1"),
+		},
+	}, nil
+}
+
+func (m *mockCreateECS) CreateServiceWithContext(_ aws.Context, in *ecs.CreateServiceInput, _ ...request.Option) (*ecs.CreateServiceOutput, error) {
+	m.created = in
+	return &ecs.CreateServiceOutput{}, nil
+}
+
+func TestCreateWithDeploymentController(t *testing.T) {
+	for _, controller := range []string{"ECS", "CODE_DEPLOY", "EXTERNAL"} {
+		app, err := NewApp(&Config{
+			Region:                "us-east-1",
+			Service:               "test",
+			Cluster:               "default",
+			Timeout:               time.Minute,
+			ServiceDefinitionPath: "tests/sv-lb.json",
+			TaskDefinitionPath:    "tests/td.json",
+			DeploymentController:  controller,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &mockCreateECS{}
+		app.ecs = m
+		err = app.Create(CreateOption{
+			DryRun:       aws.Bool(false),
+			DesiredCount: aws.Int64(1),
+			NoWait:       aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatal(controller, err)
+		}
+		if got := *m.created.DeploymentController.Type; got != controller {
+			t.Errorf("unexpected deployment controller expected %s got %s", controller, got)
+		}
+		if controller == "EXTERNAL" {
+			if m.registered || m.created.TaskDefinition != nil {
+				t.Errorf("task definition must not be specified for EXTERNAL")
+			}
+		} else if !m.registered || m.created.TaskDefinition == nil {
+			t.Errorf("task definition must be specified for %s", controller)
+		}
+	}
+}
+
+func TestSetDeploymentControllerInvalid(t *testing.T) {
+	svd := &ecs.CreateServiceInput{
+		DeploymentConfiguration: &ecs.DeploymentConfiguration{
+			DeploymentCircuitBreaker: &ecs.DeploymentCircuitBreaker{
+				Enable:   aws.Bool(true),
+				Rollback: aws.Bool(true),
+			},
+		},
+	}
+	if err := setDeploymentController(svd, "ECS"); err != nil {
+		t.Error(err)
+	}
+	if err := setDeploymentController(svd, "EXTERNAL"); err == nil {
+		t.Error("circuit breaker with EXTERNAL must be an error")
+	}
+	if err := setDeploymentController(&ecs.CreateServiceInput{}, "FOO"); err == nil {
+		t.Error("unknown controller must be an error")
+	}
+}
+
+func TestDeployWithECSDeploymentController(t *testing.T) {
+	delayForServiceChanged = 0
+	defer func() { delayForServiceChanged = 3 * time.Second }()
+
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// services created with --deployment-controller ECS report the controller explicitly
+	m := &mockMultiClusterECS{
+		updated:    make(map[string]string),
+		controller: &ecs.DeploymentController{Type: aws.String("ECS")},
+	}
+	app.ecs = m
+	app.autoScaling = &mockAutoScaling{}
+	app.sts = &mockSTS{}
+	err = app.Deploy(DeployOption{
+		DryRun:             aws.Bool(false),
+		SkipTaskDefinition: aws.Bool(true),
+		ForceNewDeployment: aws.Bool(false),
+		NoWait:             aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.updated["default"] == "" {
+		t.Error("the service must be updated by rolling deploy")
+	}
+}
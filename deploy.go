@@ -36,16 +36,56 @@ Resources:
 `
 )
 
-func (d *App) Deploy(opt DeployOption) error {
+func (d *App) Deploy(opt DeployOption) (err error) {
+	if len(d.config.Clusters) > 0 {
+		return d.deployToClusters(opt)
+	}
 	ctx, cancel := d.Start()
 	defer cancel()
+	d.setDeployID()
+
+	root := d.startSpan("deploy", nil)
+	defer func() {
+		root.End(err)
+		d.flushTraces()
+	}()
+
+	if d.config.EmitCloudWatchMetrics && !*opt.DryRun {
+		defer func(startedAt time.Time) {
+			d.putDeployMetrics(startedAt, err == nil)
+		}(time.Now())
+	}
 
 	d.Log("Starting deploy", opt.DryRunString())
+	if err := d.verifyIdentity(ctx); err != nil {
+		return err
+	}
 	sv, err := d.DescribeServiceStatus(ctx, 0)
 	if err != nil {
 		return errors.Wrap(err, "failed to describe service status")
 	}
 
+	var tdArn string
+	if !*opt.DryRun {
+		defer func(startedAt time.Time, oldArn string) {
+			d.printDeploySummary(startedAt, oldArn, tdArn, err)
+		}(time.Now(), aws.StringValue(sv.TaskDefinition))
+		defer func() {
+			if err == nil {
+				d.WarnOrphanRevisions(ctx)
+			}
+		}()
+		defer func(serviceArn string) {
+			if err == nil {
+				d.recordDeployHistory(ctx, serviceArn, tdArn)
+			}
+		}(aws.StringValue(sv.ServiceArn))
+		d.publishDeployNotification(DeployOutcomeStarted, aws.StringValue(sv.TaskDefinition), nil)
+		defer func() {
+			d.publishDeployNotification(DeployOutcomeSucceeded, tdArn, err)
+		}()
+	}
+
 	var count *int64
 	if sv.SchedulingStrategy != nil && *sv.SchedulingStrategy == "DAEMON" {
 		count = nil
@@ -55,28 +95,86 @@ func (d *App) Deploy(opt DeployOption) error {
 	} else {
 		count = opt.DesiredCount
 	}
+	if d.config.PreserveDesiredCount && aws.StringValue(sv.SchedulingStrategy) != "DAEMON" {
+		if count != nil {
+			d.Log("[WARNING] desired count", *count, "is ignored because preserve_desired_count is enabled")
+		}
+		// pass the live desired count explicitly
+		count = sv.DesiredCount
+		d.Log("Desired count is preserved:", aws.Int64Value(count))
+	}
+	if count, err = d.desiredCountForAutoScaling(count); err != nil {
+		return err
+	}
 
-	var tdArn string
+	var td *ecs.TaskDefinition
 	if *opt.SkipTaskDefinition {
 		tdArn = *sv.TaskDefinition
+	} else if d.registeredArn != "" {
+		// registered for another cluster
+		tdArn = d.registeredArn
 	} else {
-		td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
+		span := d.startSpan("load", root)
+		if d.config.PatchPath != "" {
+			td, err = d.PatchTaskDefinition(ctx, *sv.TaskDefinition, d.config.PatchPath)
+		} else {
+			td, err = d.LoadTaskDefinition(d.config.TaskDefinitionPath)
+		}
+		span.End(err)
 		if err != nil {
 			return errors.Wrap(err, "failed to load task definition")
 		}
+		d.warnFamilyMismatch(aws.StringValue(sv.TaskDefinition), td)
+		attrs, err := d.serviceAttributesFor(sv, aws.BoolValue(opt.UpdateService))
+		if err != nil {
+			return err
+		}
+		if err := validateLoadBalancers(attrs.LoadBalancers, td); err != nil {
+			return errors.Wrap(err, "task definition does not match load balancers of the service")
+		}
+		if err := validateServiceConnect(attrs.ServiceConnectConfiguration, td); err != nil {
+			return errors.Wrap(err, "task definition does not match service connect configuration of the service")
+		}
+		if err := d.verifySecretsReadable(ctx, td); err != nil {
+			// resource-based policies of secrets may allow the role, so it is not fatal
+			d.Log("[WARNING]", err)
+		}
+		resolveDigests := d.config.ResolveImageDigests
+		if d.config.RequireChange && !aws.BoolValue(opt.AllowNoChange) {
+			if resolveDigests {
+				// images of the current task definition are resolved to digests
+				d.resolveImageDigests(ctx, td)
+				resolveDigests = false
+			}
+			if err := d.requireTaskDefinitionChanged(ctx, aws.StringValue(sv.TaskDefinition), td); err != nil {
+				return err
+			}
+		}
+		d.warnRollbackRetention(ctx, aws.StringValue(sv.TaskDefinition))
 		if *opt.DryRun {
 			d.Log("task definition:", td.String())
 		} else {
-			newTd, err := d.RegisterTaskDefinition(ctx, td)
+			span := d.startSpan("register", root)
+			newTd, err := d.registerTaskDefinition(ctx, td, resolveDigests)
+			span.End(err)
 			if err != nil {
 				return errors.Wrap(err, "failed to register task definition")
 			}
 			tdArn = *newTd.TaskDefinitionArn
+			d.registeredArn = tdArn
+			span.SetAttribute("ecs.task_definition", arnToName(tdArn))
 		}
 	}
 	if count != nil {
 		d.Log("desired count:", *count)
 	}
+	root.SetAttribute("ecs.task_definition", arnToName(tdArn))
+	var diffFound bool
+	if *opt.DryRun {
+		if diffFound, err = d.dryRunDiff(ctx, sv, td, count, opt); err != nil {
+			return errors.Wrap(err, "failed to diff")
+		}
+	}
 	if opt.UpdateService != nil && *opt.UpdateService {
 		sv, err = d.UpdateServiceAttributes(ctx, opt)
 		if err != nil {
@@ -84,7 +182,13 @@ func (d *App) Deploy(opt DeployOption) error {
 		}
 	}
 	if *opt.DryRun {
-		d.Log("DRY RUN OK")
+		if err := d.checkPermissions(ctx, deployPermissionChecks(td)); err != nil {
+			return errors.Wrap(err, "insufficient permissions to deploy")
+		}
+		if diffFound && aws.BoolValue(opt.ExitCodeOnDiff) {
+			return ErrDiffFound
+		}
+		d.ResultLog("DRY RUN OK")
 		return nil
 	}
 
@@ -98,28 +202,66 @@ func (d *App) Deploy(opt DeployOption) error {
 	// detect controller
 	if dc := sv.DeploymentController; dc != nil {
 		switch t := *dc.Type; t {
+		case "ECS":
 		case "CODE_DEPLOY":
-			return d.DeployByCodeDeploy(ctx, tdArn, count, sv, opt)
+			span := d.startSpan("update", root)
+			err := d.DeployByCodeDeploy(ctx, tdArn, count, sv, opt)
+			span.End(err)
+			return err
 		default:
 			return fmt.Errorf("could not deploy a service using deployment controller type %s", t)
 		}
 	}
 
 	// rolling deploy (ECS internal)
-	if err := d.UpdateServiceTasks(ctx, tdArn, count, opt); err != nil {
+	if len(d.config.RampSteps) > 0 {
+		if sv.SchedulingStrategy != nil && *sv.SchedulingStrategy == "DAEMON" {
+			return errors.New("ramp_steps is not supported for DAEMON services")
+		}
+		final := aws.Int64Value(sv.DesiredCount)
+		if count != nil {
+			final = *count
+		}
+		span := d.startSpan("update", root)
+		err := d.deployWithRamp(ctx, tdArn, aws.Int64Value(sv.DesiredCount), final, opt)
+		span.End(err)
+		if err != nil {
+			return err
+		}
+		if *opt.NoWait {
+			d.ResultLog("Service is deployed.")
+			return nil
+		}
+		if err := d.VerifyDeployedRevision(ctx, tdArn); err != nil {
+			return errors.Wrap(err, "failed to verify deployed revision")
+		}
+		d.ResultLog("Service is stable now. Completed!")
+		return nil
+	}
+
+	span := d.startSpan("update", root)
+	err = d.UpdateServiceTasks(ctx, tdArn, count, opt)
+	span.End(err)
+	if err != nil {
 		return errors.Wrap(err, "failed to update service tasks")
 	}
 
 	if *opt.NoWait {
-		d.Log("Service is deployed.")
+		d.ResultLog("Service is deployed.")
 		return nil
 	}
 
-	if err := d.WaitServiceStable(ctx, time.Now()); err != nil {
+	span = d.startSpan("wait", root)
+	err = d.WaitServiceStable(ctx, time.Now())
+	span.End(err)
+	if err != nil {
 		return errors.Wrap(err, "failed to wait service stable")
 	}
+	if err := d.VerifyDeployedRevision(ctx, tdArn); err != nil {
+		return errors.Wrap(err, "failed to verify deployed revision")
+	}
 
-	d.Log("Service is stable now. Completed!")
+	d.ResultLog("Service is stable now. Completed!")
 	return nil
 }
 
@@ -163,6 +305,11 @@ func (d *App) UpdateServiceAttributes(ctx context.Context, opt DeployOption) (*e
 		ForceNewDeployment:            opt.ForceNewDeployment,
 		PlacementConstraints:          svd.PlacementConstraints,
 		PlacementStrategy:             svd.PlacementStrategy,
+		ServiceConnectConfiguration:   svd.ServiceConnectConfiguration,
+	}
+	if dc := svd.DeploymentController; dc == nil || aws.StringValue(dc.Type) == ecs.DeploymentControllerTypeEcs {
+		// load balancers can be updated only for ECS deployment controller
+		in.LoadBalancers = svd.LoadBalancers
 	}
 	if *opt.DryRun {
 		d.Log("update service input:", in.String())
@@ -0,0 +1,43 @@
+package ecspresso
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// DeployIDTagKey is a tag key of task definitions registered by deploy.
+const DeployIDTagKey = "ecspresso:deploy-id"
+
+// newDeployID generates a random UUID (version 4).
+func newDeployID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (d *App) setDeployID() {
+	if d.deployID != "" {
+		return
+	}
+	if d.config.DeployID != "" {
+		d.deployID = d.config.DeployID
+	} else {
+		d.deployID = newDeployID()
+	}
+}
+
+func (d *App) deployIDTags() []*ecs.Tag {
+	if d.deployID == "" {
+		return nil
+	}
+	return []*ecs.Tag{
+		{Key: aws.String(DeployIDTagKey), Value: aws.String(d.deployID)},
+	}
+}
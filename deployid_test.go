@@ -0,0 +1,58 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewDeployID(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	id := newDeployID()
+	if !re.MatchString(id) {
+		t.Errorf("invalid UUID %s", id)
+	}
+	if id == newDeployID() {
+		t.Error("deploy IDs must be unique")
+	}
+}
+
+func TestDeployIDPropagation(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		TaskDefinitionPath: "tests/td.json",
+		DeployID:           "ci-run-123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockCreateECS{}
+	app.ecs = m
+	app.setDeployID()
+
+	td, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.RegisterTaskDefinition(context.Background(), td); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.registeredTags) != 1 || *m.registeredTags[0].Key != DeployIDTagKey || *m.registeredTags[0].Value != "ci-run-123" {
+		t.Errorf("unexpected tags %v", m.registeredTags)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.Contains(line, "test/default deploy_id:ci-run-123 ") {
+			t.Errorf("log must include the deploy ID: %s", line)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+const (
+	diagnoseTimeout     = 30 * time.Second
+	diagnoseStoppedTask = 3
+	diagnoseEvents      = 3
+	diagnoseBlocked     = 3
+)
+
+// diagnoseUnstableService returns likely causes why the service is not stable.
+// It uses a new context because the context for waiting may be already expired.
+func (d *App) diagnoseUnstableService(startedAt time.Time) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), diagnoseTimeout)
+	defer cancel()
+
+	out, err := d.ecs.DescribeServicesWithContext(ctx, d.DescribeServicesInput())
+	if err != nil || len(out.Services) == 0 {
+		d.DebugLog("failed to describe service for diagnosis", err)
+		return nil
+	}
+	stopped, err := d.listTasks(ctx, ecs.DesiredStatusStopped, "")
+	if err != nil {
+		d.DebugLog("failed to list stopped tasks for diagnosis", err)
+	}
+	reasons := unstableReasons(out.Services[0], stopped, startedAt)
+	reasons = append(reasons, d.blockedDependencyReasons(ctx)...)
+	for _, r := range reasons {
+		d.Log("[DIAGNOSIS]", r)
+	}
+	return reasons
+}
+
+func unstableReasons(sv *ecs.Service, stopped []*ecs.Task, startedAt time.Time) []string {
+	var reasons []string
+	for _, dep := range sv.Deployments {
+		if aws.StringValue(dep.Status) != "PRIMARY" {
+			continue
+		}
+		if desired, running := aws.Int64Value(dep.DesiredCount), aws.Int64Value(dep.RunningCount); desired != running {
+			reasons = append(reasons, fmt.Sprintf("primary deployment %s has desired:%d but running:%d", arnToName(*dep.TaskDefinition), desired, running))
+		}
+	}
+
+	sort.Slice(stopped, func(i, j int) bool {
+		return aws.TimeValue(stopped[i].StoppedAt).After(aws.TimeValue(stopped[j].StoppedAt))
+	})
+	n := 0
+	for _, t := range stopped {
+		if n >= diagnoseStoppedTask {
+			break
+		}
+		if t.StoppedAt == nil || t.StoppedAt.Before(startedAt) {
+			continue
+		}
+		n++
+		r := fmt.Sprintf("task %s stopped: %s", arnToName(*t.TaskArn), aws.StringValue(t.StoppedReason))
+		for _, c := range t.Containers {
+			if c.ExitCode != nil && *c.ExitCode != 0 || c.Reason != nil {
+				r += fmt.Sprintf(" (container %s exitCode:%s %s)", *c.Name, formatExitCode(c.ExitCode), aws.StringValue(c.Reason))
+			}
+		}
+		reasons = append(reasons, r)
+	}
+
+	n = 0
+	for _, e := range sv.Events {
+		if n >= diagnoseEvents {
+			break
+		}
+		if e.CreatedAt.Before(startedAt) {
+			continue
+		}
+		if msg := aws.StringValue(e.Message); strings.Contains(msg, "unhealthy") || strings.Contains(msg, "health check") {
+			n++
+			reasons = append(reasons, msg)
+		}
+	}
+	return reasons
+}
+
+// blockedDependencyReasons returns reasons why tasks stuck in PENDING or ACTIVATING can't start,
+// by dependsOn conditions of containers which are not met.
+func (d *App) blockedDependencyReasons(ctx context.Context) []string {
+	tasks, err := d.listTasks(ctx, ecs.DesiredStatusRunning, "")
+	if err != nil {
+		d.DebugLog("failed to list running tasks for diagnosis", err)
+		return nil
+	}
+	tds := make(map[string]*ecs.TaskDefinition)
+	var reasons []string
+	for _, task := range tasks {
+		switch aws.StringValue(task.LastStatus) {
+		case "PROVISIONING", "PENDING", "ACTIVATING":
+		default:
+			continue
+		}
+		tdArn := aws.StringValue(task.TaskDefinitionArn)
+		td, ok := tds[tdArn]
+		if !ok {
+			if td, err = d.DescribeTaskDefinition(ctx, tdArn); err != nil {
+				d.DebugLog("failed to describe task definition for diagnosis", err)
+				continue
+			}
+			tds[tdArn] = td
+		}
+		for _, b := range blockedDependencies(task, td) {
+			reasons = append(reasons, fmt.Sprintf("task %s: %s", arnToName(aws.StringValue(task.TaskArn)), b))
+		}
+		if len(reasons) >= diagnoseBlocked {
+			break
+		}
+	}
+	return reasons
+}
+
+// blockedDependencies returns descriptions of containers of the task waiting on dependencies
+// whose conditions of dependsOn are not met yet.
+func blockedDependencies(task *ecs.Task, td *ecs.TaskDefinition) []string {
+	containers := make(map[string]*ecs.Container, len(task.Containers))
+	for _, c := range task.Containers {
+		containers[aws.StringValue(c.Name)] = c
+	}
+	var blocked []string
+	for _, cd := range td.ContainerDefinitions {
+		name := aws.StringValue(cd.Name)
+		if c, ok := containers[name]; ok && aws.StringValue(c.LastStatus) == "RUNNING" {
+			continue
+		}
+		for _, dep := range cd.DependsOn {
+			depName, condition := aws.StringValue(dep.ContainerName), aws.StringValue(dep.Condition)
+			c, ok := containers[depName]
+			if !ok {
+				continue
+			}
+			if dependencyConditionMet(c, condition) {
+				continue
+			}
+			state := aws.StringValue(c.LastStatus)
+			if h := aws.StringValue(c.HealthStatus); h != "" {
+				state += ", " + h
+			}
+			if c.ExitCode != nil {
+				state += ", exitCode:" + formatExitCode(c.ExitCode)
+			}
+			blocked = append(blocked, fmt.Sprintf("container %s is waiting on %s (%s), but %s is %s", name, depName, condition, depName, state))
+		}
+	}
+	return blocked
+}
+
+func dependencyConditionMet(c *ecs.Container, condition string) bool {
+	status := aws.StringValue(c.LastStatus)
+	switch condition {
+	case ecs.ContainerConditionStart:
+		return status == "RUNNING" || status == "STOPPED"
+	case ecs.ContainerConditionComplete:
+		return status == "STOPPED"
+	case ecs.ContainerConditionSuccess:
+		return status == "STOPPED" && c.ExitCode != nil && *c.ExitCode == 0
+	case ecs.ContainerConditionHealthy:
+		return aws.StringValue(c.HealthStatus) == ecs.HealthStatusHealthy
+	}
+	return true
+}
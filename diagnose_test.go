@@ -0,0 +1,124 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestUnstableReasons(t *testing.T) {
+	startedAt := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	sv := &ecs.Service{
+		Deployments: []*ecs.Deployment{
+			{
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/app:2"),
+				DesiredCount:   aws.Int64(2),
+				RunningCount:   aws.Int64(0),
+			},
+		},
+		Events: []*ecs.ServiceEvent{
+			{
+				CreatedAt: aws.Time(startedAt.Add(time.Minute)),
+				Message:   aws.String("(service app) (task 0123) failed ELB health checks in (target-group arn:aws:elasticloadbalancing:ap-northeast-1:123456789012:targetgroup/app/0123)"),
+			},
+			{
+				CreatedAt: aws.Time(startedAt.Add(-time.Minute)),
+				Message:   aws.String("(service app) (instance i-0123) is unhealthy in (target-group old)"),
+			},
+		},
+	}
+	stopped := []*ecs.Task{
+		{
+			TaskArn:       aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0123"),
+			StoppedAt:     aws.Time(startedAt.Add(2 * time.Minute)),
+			StoppedReason: aws.String("Essential container in task exited"),
+			Containers: []*ecs.Container{
+				{Name: aws.String("app"), ExitCode: aws.Int64(1)},
+			},
+		},
+	}
+	reasons := unstableReasons(sv, stopped, startedAt)
+	expected := []string{
+		"primary deployment app:2 has desired:2 but running:0",
+		"task 0123 stopped: Essential container in task exited (container app exitCode:1 )",
+		"failed ELB health checks",
+	}
+	if len(reasons) != len(expected) {
+		t.Fatalf("unexpected reasons %v", reasons)
+	}
+	for i, e := range expected {
+		if !strings.Contains(reasons[i], e) {
+			t.Errorf("reason %q must contain %q", reasons[i], e)
+		}
+	}
+}
+
+type mockDiagnoseECS struct {
+	mockDiffECS
+	tasks []*ecs.Task
+}
+
+func (m *mockDiagnoseECS) ListTasksPagesWithContext(_ aws.Context, _ *ecs.ListTasksInput, fn func(*ecs.ListTasksOutput, bool) bool, _ ...request.Option) error {
+	var arns []*string
+	for _, task := range m.tasks {
+		arns = append(arns, task.TaskArn)
+	}
+	fn(&ecs.ListTasksOutput{TaskArns: arns}, true)
+	return nil
+}
+
+func (m *mockDiagnoseECS) DescribeTasksWithContext(_ aws.Context, _ *ecs.DescribeTasksInput, _ ...request.Option) (*ecs.DescribeTasksOutput, error) {
+	return &ecs.DescribeTasksOutput{Tasks: m.tasks}, nil
+}
+
+func TestBlockedDependencyReasons(t *testing.T) {
+	tdArn := "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/app:2"
+	td := &ecs.TaskDefinition{
+		Family:            aws.String("app"),
+		TaskDefinitionArn: aws.String(tdArn),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name: aws.String("app"),
+				DependsOn: []*ecs.ContainerDependency{
+					{ContainerName: aws.String("proxy"), Condition: aws.String("HEALTHY")},
+					{ContainerName: aws.String("migrate"), Condition: aws.String("SUCCESS")},
+				},
+			},
+			{Name: aws.String("proxy")},
+			{Name: aws.String("migrate")},
+		},
+	}
+	d := &App{
+		config: &Config{},
+		ecs: &mockDiagnoseECS{
+			mockDiffECS: mockDiffECS{taskDefinitions: map[string]*ecs.TaskDefinition{tdArn: td}},
+			tasks: []*ecs.Task{
+				{
+					TaskArn:           aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0123"),
+					TaskDefinitionArn: aws.String(tdArn),
+					LastStatus:        aws.String("PENDING"),
+					Containers: []*ecs.Container{
+						{Name: aws.String("app"), LastStatus: aws.String("PENDING")},
+						{Name: aws.String("proxy"), LastStatus: aws.String("RUNNING"), HealthStatus: aws.String("UNHEALTHY")},
+						{Name: aws.String("migrate"), LastStatus: aws.String("STOPPED"), ExitCode: aws.Int64(0)},
+					},
+				},
+				{
+					TaskArn:           aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/4567"),
+					TaskDefinitionArn: aws.String(tdArn),
+					LastStatus:        aws.String("RUNNING"),
+				},
+			},
+		},
+	}
+	reasons := d.blockedDependencyReasons(context.Background())
+	if len(reasons) != 1 || reasons[0] != "task 0123: container app is waiting on proxy (HEALTHY), but proxy is RUNNING, UNHEALTHY" {
+		t.Errorf("unexpected reasons %v", reasons)
+	}
+}
@@ -0,0 +1,407 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Bases of the diff to be compared with the local task definition.
+const (
+	DiffAgainstRunning        = "running"
+	DiffAgainstLatestActive   = "latest-active"
+	DiffAgainstRevisionPrefix = "revision:"
+)
+
+// Diff prints a unified diff (or GitHub Actions annotations by opt.Format) between the task definition on AWS
+// and the local task definition.
+// By default the task definition of the service is compared.
+// opt.Against selects the latest ACTIVE revision of the family, or the specified revision instead.
+func (d *App) Diff(opt DiffOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	against := aws.StringValue(opt.Against)
+	if rev := aws.Int64Value(opt.SinceRevision); rev > 0 {
+		against = fmt.Sprintf("%s%d", DiffAgainstRevisionPrefix, rev)
+	}
+	base, rev, err := parseDiffAgainst(against)
+	if err != nil {
+		return err
+	}
+	switch aws.StringValue(opt.Format) {
+	case "", DiffFormatUnified, DiffFormatGitHub:
+	default:
+		return errors.Errorf("--format must be %s or %s", DiffFormatUnified, DiffFormatGitHub)
+	}
+	if base != DiffAgainstRunning {
+		td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to load task definition")
+		}
+		family := aws.StringValue(td.Family)
+		var remoteArn string
+		if base == DiffAgainstLatestActive {
+			if remoteArn, err = d.latestActiveRevision(ctx, family); err != nil {
+				return err
+			}
+		} else {
+			remoteArn = fmt.Sprintf("%s:%d", family, rev)
+		}
+		return d.diffTaskDefinition(ctx, remoteArn, base, td, opt)
+	}
+
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	remoteArn := *sv.TaskDefinition
+	td, err := d.loadTaskDefinitionFor(ctx, remoteArn)
+	if err != nil {
+		return errors.Wrap(err, "failed to load task definition")
+	}
+	if err := d.diffTaskDefinition(ctx, remoteArn, base, td, opt); err != nil {
+		return err
+	}
+	attrs, err := d.serviceAttributesFor(sv, true)
+	if err != nil {
+		return err
+	}
+	if err := validateLoadBalancers(attrs.LoadBalancers, td); err != nil {
+		d.Log("[WARNING]", err)
+	}
+	if d.config.ServiceDefinitionPath == "" {
+		return nil
+	}
+	svd, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to load service definition")
+	}
+	ds, err := diffLoadBalancers(sv.LoadBalancers, svd.LoadBalancers, d.Service+" loadBalancers", d.config.ServiceDefinitionPath+" loadBalancers")
+	if err != nil {
+		return err
+	}
+	if aws.StringValue(opt.Format) == DiffFormatGitHub {
+		if ds != "" {
+			changes := []taskDefinitionChange{{Field: "loadBalancers", Kind: "changed"}}
+			fmt.Fprint(d.stdout(), formatGitHubAnnotations(changes, d.config.ServiceDefinitionPath, "Service definition differs from "+d.Service))
+		}
+		return nil
+	}
+	fmt.Fprint(d.stdout(), ds)
+	return nil
+}
+
+// loadTaskDefinitionFor loads the local task definition to be deployed over the remote one.
+// When patch is defined in config, the patch is applied to the remote task definition.
+func (d *App) loadTaskDefinitionFor(ctx context.Context, remoteArn string) (*ecs.TaskDefinition, error) {
+	if d.config.PatchPath != "" {
+		return d.PatchTaskDefinition(ctx, remoteArn, d.config.PatchPath)
+	}
+	return d.LoadTaskDefinition(d.config.TaskDefinitionPath)
+}
+
+func (d *App) diffTaskDefinition(ctx context.Context, remoteArn, base string, td *ecs.TaskDefinition, opt DiffOption) error {
+	if aws.StringValue(opt.Format) == DiffFormatGitHub {
+		return d.annotateTaskDefinitionChanges(ctx, remoteArn, base, td)
+	}
+	ds, err := d.diffRemoteTaskDefinition(ctx, remoteArn, base, td, aws.BoolValue(opt.ShowValues))
+	if err != nil {
+		return err
+	}
+	if ds == "" {
+		d.Log("No differences from", arnToName(remoteArn))
+		return nil
+	}
+	fmt.Fprint(d.stdout(), ds)
+	return nil
+}
+
+// diffRemoteTaskDefinition returns a unified diff between the task definition on AWS and td.
+// When base is not empty, it is shown with the name of the remote task definition in the header.
+func (d *App) diffRemoteTaskDefinition(ctx context.Context, remoteArn, base string, td *ecs.TaskDefinition, showValues bool) (string, error) {
+	remote, err := d.describeRemoteTaskDefinition(ctx, remoteArn, td)
+	if err != nil {
+		return "", err
+	}
+	return diffTaskDefinitions(remote, td, diffFromName(remoteArn, base), d.config.TaskDefinitionPath, showValues)
+}
+
+// annotateTaskDefinitionChanges prints changes from the task definition on AWS to td as GitHub Actions annotations.
+func (d *App) annotateTaskDefinitionChanges(ctx context.Context, remoteArn, base string, td *ecs.TaskDefinition) error {
+	remote, err := d.describeRemoteTaskDefinition(ctx, remoteArn, td)
+	if err != nil {
+		return err
+	}
+	changes, err := taskDefinitionChanges(remote, td)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		d.Log("No differences from", arnToName(remoteArn))
+		return nil
+	}
+	fmt.Fprint(d.stdout(), formatGitHubAnnotations(changes, d.config.TaskDefinitionPath, "Task definition differs from "+diffFromName(remoteArn, base)))
+	return nil
+}
+
+// describeRemoteTaskDefinition describes the task definition on AWS to be compared with td.
+func (d *App) describeRemoteTaskDefinition(ctx context.Context, remoteArn string, td *ecs.TaskDefinition) (*ecs.TaskDefinition, error) {
+	remote, err := d.DescribeTaskDefinition(ctx, remoteArn)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecs.ErrCodeClientException {
+			return nil, errors.Errorf("task definition %s is not found", remoteArn)
+		}
+		return nil, errors.Wrapf(err, "failed to describe task definition %s", remoteArn)
+	}
+	if aws.StringValue(remote.Family) != aws.StringValue(td.Family) {
+		return nil, errors.Errorf("task definition %s does not belong to the family %s", remoteArn, aws.StringValue(td.Family))
+	}
+	return remote, nil
+}
+
+func diffFromName(remoteArn, base string) string {
+	if base == "" {
+		return arnToName(remoteArn)
+	}
+	return arnToName(remoteArn) + " (" + base + ")"
+}
+
+// parseDiffAgainst parses the base to be compared by diff: running, latest-active or revision:N.
+func parseDiffAgainst(s string) (string, int64, error) {
+	switch {
+	case s == "" || s == DiffAgainstRunning:
+		return DiffAgainstRunning, 0, nil
+	case s == DiffAgainstLatestActive:
+		return DiffAgainstLatestActive, 0, nil
+	case strings.HasPrefix(s, DiffAgainstRevisionPrefix):
+		rev, err := strconv.ParseInt(strings.TrimPrefix(s, DiffAgainstRevisionPrefix), 10, 64)
+		if err != nil || rev <= 0 {
+			return "", 0, errors.Errorf("invalid revision of --against %s", s)
+		}
+		return s, rev, nil
+	}
+	return "", 0, errors.Errorf("--against must be %s, %s or %sN: %s", DiffAgainstRunning, DiffAgainstLatestActive, DiffAgainstRevisionPrefix, s)
+}
+
+// latestActiveRevision returns the ARN of the latest ACTIVE revision of the family,
+// which may differ from the one of the service when registered out of band.
+func (d *App) latestActiveRevision(ctx context.Context, family string) (string, error) {
+	var nextToken *string
+	for {
+		out, err := d.ecs.ListTaskDefinitionsWithContext(ctx,
+			&ecs.ListTaskDefinitionsInput{
+				NextToken:    nextToken,
+				FamilyPrefix: aws.String(family),
+				Status:       aws.String(ecs.TaskDefinitionStatusActive),
+				MaxResults:   aws.Int64(100),
+				Sort:         aws.String("DESC"),
+			},
+		)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to list taskdefinitions")
+		}
+		for _, tdArn := range out.TaskDefinitionArns {
+			// FamilyPrefix also matches other families having the prefix
+			if f, _ := parseTaskDefinitionName(arnToName(*tdArn)); f == family {
+				return *tdArn, nil
+			}
+		}
+		if nextToken = out.NextToken; nextToken == nil {
+			return "", errors.Errorf("no active revisions of the family %s", family)
+		}
+	}
+}
+
+// requireTaskDefinitionChanged returns ErrNoChange when td is identical to the current task definition.
+// It is expected that a deploy changes something (e.g. bumps an image tag).
+func (d *App) requireTaskDefinitionChanged(ctx context.Context, currentArn string, td *ecs.TaskDefinition) error {
+	if family, _ := parseTaskDefinitionName(arnToName(currentArn)); family != aws.StringValue(td.Family) {
+		return nil
+	}
+	ds, err := d.diffRemoteTaskDefinition(ctx, currentArn, "", td, true)
+	if err != nil {
+		return err
+	}
+	if ds != "" {
+		return nil
+	}
+	d.Log("Nothing is changed from", arnToName(currentArn))
+	return errors.Wrapf(ErrNoChange, "%s is identical to the task definition to be deployed. use --allow-no-change to deploy it anyway", arnToName(currentArn))
+}
+
+func (d *App) describeService(ctx context.Context) (*ecs.Service, error) {
+	out, err := d.ecs.DescribeServicesWithContext(ctx, d.DescribeServicesInput())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe service")
+	}
+	if len(out.Services) == 0 {
+		return nil, ErrServiceNotFound
+	}
+	return out.Services[0], nil
+}
+
+// diffTaskDefinitions returns a unified diff of normalized task definitions.
+// It returns an empty string when there are no differences.
+// Values of environment are masked unless showValues is true.
+func diffTaskDefinitions(from, to *ecs.TaskDefinition, fromName, toName string, showValues bool) (string, error) {
+	var maskFrom, maskTo envMasker
+	if !showValues {
+		fromValues := environmentValues(from)
+		maskFrom = func(_, _, _ string) string {
+			return maskedValue
+		}
+		maskTo = func(container, name, value string) string {
+			if v, ok := fromValues[container+"/"+name]; ok && v != value {
+				return maskedValue + " (changed)"
+			}
+			return maskedValue
+		}
+	}
+	a, err := normalizeTaskDefinition(from, maskFrom)
+	if err != nil {
+		return "", err
+	}
+	b, err := normalizeTaskDefinition(to, maskTo)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(a)),
+		B:        difflib.SplitLines(string(b)),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+}
+
+// maskedValue replaces values of environment in diffs.
+// Values are never shown even in a hashed form, because short hashes of low-entropy secrets can be brute-forced.
+const maskedValue = "********"
+
+// envMasker returns a masked string of the value of the environment variable name of the container.
+type envMasker func(container, name, value string) string
+
+// environmentValues returns values of environment of containers in the task definition keyed by "container/name".
+func environmentValues(td *ecs.TaskDefinition) map[string]string {
+	values := make(map[string]string)
+	for _, cd := range td.ContainerDefinitions {
+		for _, env := range cd.Environment {
+			values[aws.StringValue(cd.Name)+"/"+aws.StringValue(env.Name)] = aws.StringValue(env.Value)
+		}
+	}
+	return values
+}
+
+// normalizeTaskDefinition returns JSON of the task definition to be registered.
+// Keys of objects are sorted, and environment and secrets of containers are sorted by name,
+// so that diffs show only meaningful changes.
+// When mask is not nil, values of environment are replaced by mask.
+func normalizeTaskDefinition(td *ecs.TaskDefinition, mask envMasker) ([]byte, error) {
+	b, err := jsonutil.BuildJSON(registerTaskDefinitionInput(td))
+	if err != nil {
+		return nil, err
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	if cds, ok := v["containerDefinitions"].([]interface{}); ok {
+		for _, cd := range cds {
+			c, ok := cd.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, key := range []string{"environment", "secrets"} {
+				if vs, ok := c[key].([]interface{}); ok {
+					sortByName(vs)
+				}
+			}
+			if vs, ok := c["environment"].([]interface{}); ok && mask != nil {
+				container, _ := c["name"].(string)
+				for _, v := range vs {
+					if m, ok := v.(map[string]interface{}); ok {
+						name, _ := m["name"].(string)
+						if value, ok := m["value"].(string); ok {
+							m["value"] = mask(container, name, value)
+						}
+					}
+				}
+			}
+		}
+	}
+	b, err = json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func sortByName(vs []interface{}) {
+	name := func(v interface{}) string {
+		if m, ok := v.(map[string]interface{}); ok {
+			s, _ := m["name"].(string)
+			return s
+		}
+		return ""
+	}
+	sort.SliceStable(vs, func(i, j int) bool {
+		return name(vs[i]) < name(vs[j])
+	})
+}
+
+// dryRunDiff prints diffs of the task definition and the service-level settings to be deployed
+// against the live service. It reports whether any differences are found.
+// The service definition is considered only when --update-service is set.
+func (d *App) dryRunDiff(ctx context.Context, sv *ecs.Service, td *ecs.TaskDefinition, count *int64, opt DeployOption) (bool, error) {
+	found := false
+	remoteArn := aws.StringValue(sv.TaskDefinition)
+	if td != nil {
+		if family, _ := parseTaskDefinitionName(arnToName(remoteArn)); family != aws.StringValue(td.Family) {
+			d.Log("task definition family is changed from", family, "to", aws.StringValue(td.Family))
+			found = true
+		} else {
+			ds, err := d.diffRemoteTaskDefinition(ctx, remoteArn, "", td, false)
+			if err != nil {
+				return false, err
+			}
+			if ds == "" {
+				d.Log("No differences from", arnToName(remoteArn))
+			} else {
+				fmt.Fprint(d.stdout(), ds)
+				found = true
+			}
+		}
+	}
+
+	var svd *ecs.CreateServiceInput
+	toName := "deploy"
+	if aws.BoolValue(opt.UpdateService) && d.config.ServiceDefinitionPath != "" {
+		var err error
+		if svd, err = d.LoadServiceDefinition(d.config.ServiceDefinitionPath); err != nil {
+			return false, errors.Wrap(err, "failed to load service definition")
+		}
+		toName = d.config.ServiceDefinitionPath
+	}
+	ds, err := diffServiceAttributes(serviceAttributes(sv), desiredServiceAttributes(sv, svd, count), d.Service, toName)
+	if err != nil {
+		return false, err
+	}
+	if ds == "" {
+		d.Log("No differences of service settings")
+	} else {
+		fmt.Fprint(d.stdout(), ds)
+		found = true
+	}
+	return found, nil
+}
@@ -0,0 +1,394 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/pkg/errors"
+)
+
+type mockDiffECS struct {
+	ecsiface.ECSAPI
+	taskDefinitions map[string]*ecs.TaskDefinition
+}
+
+func (m *mockDiffECS) DescribeTaskDefinitionWithContext(_ aws.Context, in *ecs.DescribeTaskDefinitionInput, _ ...request.Option) (*ecs.DescribeTaskDefinitionOutput, error) {
+	td, ok := m.taskDefinitions[*in.TaskDefinition]
+	if !ok {
+		return nil, awserr.New(ecs.ErrCodeClientException, "Unable to describe task definition.", nil)
+	}
+	return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: td}, nil
+}
+
+func testDiffTaskDefinition(env ...*ecs.KeyValuePair) *ecs.TaskDefinition {
+	return &ecs.TaskDefinition{
+		Family:            aws.String("app"),
+		Revision:          aws.Int64(3),
+		TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/app:3"),
+		Status:            aws.String("ACTIVE"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1"), Environment: env},
+		},
+	}
+}
+
+func TestDiffTaskDefinitions(t *testing.T) {
+	from := testDiffTaskDefinition(
+		&ecs.KeyValuePair{Name: aws.String("FOO"), Value: aws.String("foo")},
+		&ecs.KeyValuePair{Name: aws.String("BAR"), Value: aws.String("bar")},
+	)
+	to := testDiffTaskDefinition(
+		&ecs.KeyValuePair{Name: aws.String("BAR"), Value: aws.String("bar")},
+		&ecs.KeyValuePair{Name: aws.String("FOO"), Value: aws.String("foo")},
+	)
+	to.Revision = nil
+	to.TaskDefinitionArn = nil
+	to.Status = nil
+	ds, err := diffTaskDefinitions(from, to, "app:3", "td.json", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds != "" {
+		t.Errorf("order of environment and read-only fields must be ignored: %s", ds)
+	}
+
+	to.ContainerDefinitions[0].Image = aws.String("app:v2")
+	ds, err = diffTaskDefinitions(from, to, "app:3", "td.json", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"--- app:3", "+++ td.json", `-      "image": "app:v1",`, `+      "image": "app:v2",`} {
+		if !strings.Contains(ds, s) {
+			t.Errorf("diff must contain %s: %s", s, ds)
+		}
+	}
+}
+
+func TestDiffSinceRevision(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	td, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := testDiffTaskDefinition()
+	old.Family = aws.String("other")
+	app.ecs = &mockDiffECS{
+		taskDefinitions: map[string]*ecs.TaskDefinition{
+			"katsubushi:1": td,
+			"katsubushi:2": old,
+		},
+	}
+	ctx := context.Background()
+	if err := app.diffTaskDefinition(ctx, "katsubushi:1", "", td, DiffOption{}); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if err := app.diffTaskDefinition(ctx, "katsubushi:2", "", td, DiffOption{}); err == nil || !strings.Contains(err.Error(), "does not belong to the family katsubushi") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := app.Diff(DiffOption{SinceRevision: aws.Int64(9)}); err == nil || !strings.Contains(err.Error(), "task definition katsubushi:9 is not found") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+type mockDiffAgainstECS struct {
+	mockDiffECS
+	service *ecs.Service
+	arns    []string
+}
+
+func (m *mockDiffAgainstECS) DescribeServicesWithContext(_ aws.Context, _ *ecs.DescribeServicesInput, _ ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	return &ecs.DescribeServicesOutput{Services: []*ecs.Service{m.service}}, nil
+}
+
+func (m *mockDiffAgainstECS) ListTaskDefinitionsWithContext(_ aws.Context, _ *ecs.ListTaskDefinitionsInput, _ ...request.Option) (*ecs.ListTaskDefinitionsOutput, error) {
+	return &ecs.ListTaskDefinitionsOutput{TaskDefinitionArns: aws.StringSlice(m.arns)}, nil
+}
+
+func TestDiffAgainst(t *testing.T) {
+	defer log.SetOutput(os.Stderr)
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revision := func(rev int64) *ecs.TaskDefinition {
+		td, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		td.Revision = aws.Int64(rev)
+		td.ContainerDefinitions[0].Image = aws.String(fmt.Sprintf("katsubushi/katsubushi:v%d", rev))
+		return td
+	}
+	app.ecs = &mockDiffAgainstECS{
+		mockDiffECS: mockDiffECS{
+			taskDefinitions: map[string]*ecs.TaskDefinition{
+				"arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:3": revision(3),
+				"arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:5": revision(5),
+				"katsubushi:4": revision(4),
+			},
+		},
+		service: &ecs.Service{TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:3")},
+		arns: []string{
+			"arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi-worker:9",
+			"arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:5",
+		},
+	}
+
+	for _, c := range []struct {
+		against string
+		header  string
+	}{
+		{against: "running", header: "--- katsubushi:3 (running)"},
+		{against: "latest-active", header: "--- katsubushi:5 (latest-active)"},
+		{against: "revision:4", header: "--- katsubushi:4 (revision:4)"},
+	} {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stdout := os.Stdout
+		os.Stdout = w
+		err = app.Diff(DiffOption{SinceRevision: aws.Int64(0), Against: aws.String(c.against), ShowValues: aws.Bool(false)})
+		os.Stdout = stdout
+		w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), c.header+"\n") {
+			t.Errorf("%s: diff must contain the header %s: %s", c.against, c.header, b)
+		}
+	}
+
+	for _, against := range []string{"current", "revision:0", "revision:x"} {
+		if err := app.Diff(DiffOption{SinceRevision: aws.Int64(0), Against: aws.String(against)}); err == nil {
+			t.Errorf("%s: expected an error", against)
+		}
+	}
+}
+
+func TestDiffTaskDefinitionsMaskValues(t *testing.T) {
+	from := testDiffTaskDefinition(
+		&ecs.KeyValuePair{Name: aws.String("PASSWORD"), Value: aws.String("old-secret")},
+		&ecs.KeyValuePair{Name: aws.String("USER"), Value: aws.String("admin")},
+	)
+	from.ContainerDefinitions[0].Secrets = []*ecs.Secret{
+		{Name: aws.String("TOKEN"), ValueFrom: aws.String("arn:aws:ssm:us-east-1:123456789012:parameter/token")},
+	}
+	to := testDiffTaskDefinition(
+		&ecs.KeyValuePair{Name: aws.String("PASSWORD"), Value: aws.String("new-secret")},
+		&ecs.KeyValuePair{Name: aws.String("USER"), Value: aws.String("admin")},
+	)
+	to.ContainerDefinitions[0].Secrets = from.ContainerDefinitions[0].Secrets
+
+	ds, err := diffTaskDefinitions(from, to, "app:3", "td.json", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"old-secret", "new-secret"} {
+		if strings.Contains(ds, s) {
+			t.Errorf("diff must not contain %s: %s", s, ds)
+		}
+	}
+	for _, s := range []string{`-          "value": "********"`, `+          "value": "******** (changed)"`} {
+		if !strings.Contains(ds, s) {
+			t.Errorf("diff must contain %s: %s", s, ds)
+		}
+	}
+	if strings.Contains(ds, "sha256") {
+		t.Errorf("diff must not contain hashes of values: %s", ds)
+	}
+	if strings.Contains(ds, "admin") || strings.Count(ds, "(changed)") != 1 {
+		t.Errorf("unchanged values must not be marked as changed: %s", ds)
+	}
+	if strings.Contains(ds, "TOKEN") {
+		t.Errorf("unchanged secrets must not be in diff: %s", ds)
+	}
+
+	ds, err = diffTaskDefinitions(from, to, "app:3", "td.json", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`-          "value": "old-secret"`, `+          "value": "new-secret"`} {
+		if !strings.Contains(ds, s) {
+			t.Errorf("diff must contain %s: %s", s, ds)
+		}
+	}
+}
+
+type mockRequireChangeECS struct {
+	*mockMultiClusterECS
+	current *ecs.TaskDefinition
+}
+
+func (m *mockRequireChangeECS) DescribeTaskDefinitionWithContext(_ aws.Context, _ *ecs.DescribeTaskDefinitionInput, _ ...request.Option) (*ecs.DescribeTaskDefinitionOutput, error) {
+	return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: m.current}, nil
+}
+
+func TestDeployRequireChange(t *testing.T) {
+	delayForServiceChanged = 0
+	defer func() { delayForServiceChanged = 3 * time.Second }()
+
+	for _, allowNoChange := range []bool{false, true} {
+		app, err := NewApp(&Config{
+			Region:             "us-east-1",
+			Service:            "test",
+			Cluster:            "default",
+			Timeout:            time.Minute,
+			TaskDefinitionPath: "tests/td.json",
+			RequireChange:      true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		current, err := app.LoadTaskDefinition("tests/td.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &mockRequireChangeECS{
+			mockMultiClusterECS: &mockMultiClusterECS{updated: make(map[string]string)},
+			current:             current,
+		}
+		app.ecs = m
+		app.autoScaling = &mockAutoScaling{}
+		app.sts = &mockSTS{}
+		err = app.Deploy(DeployOption{
+			DryRun:             aws.Bool(false),
+			DesiredCount:       aws.Int64(KeepDesiredCount),
+			SkipTaskDefinition: aws.Bool(false),
+			ForceNewDeployment: aws.Bool(false),
+			NoWait:             aws.Bool(true),
+			UpdateService:      aws.Bool(false),
+			AllowNoChange:      aws.Bool(allowNoChange),
+		})
+		if allowNoChange {
+			if err != nil {
+				t.Errorf("unexpected error with --allow-no-change %s", err)
+			}
+			if m.registered != 1 {
+				t.Error("task definition must be registered with --allow-no-change")
+			}
+			continue
+		}
+		if errors.Cause(err) != ErrNoChange {
+			t.Errorf("deploy must fail without changes: %v", err)
+		}
+		if m.registered != 0 {
+			t.Error("task definition must not be registered without changes")
+		}
+	}
+}
+
+func TestDeployRequireChangeResolvedDigests(t *testing.T) {
+	delayForServiceChanged = 0
+	defer func() { delayForServiceChanged = 3 * time.Second }()
+
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "td.json")
+	src := `{"family": "katsubushi", "memory": "512", "containerDefinitions": [{"name": "app", "image": "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1"}]}`
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app, err := NewApp(&Config{
+		Region:              "us-east-1",
+		Service:             "test",
+		Cluster:             "default",
+		Timeout:             time.Minute,
+		TaskDefinitionPath:  path,
+		RequireChange:       true,
+		ResolveImageDigests: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := app.LoadTaskDefinition(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the current task definition was registered with the resolved digest
+	current.ContainerDefinitions[0].Image = aws.String("123456789012.dkr.ecr.us-east-1.amazonaws.com/app@sha256:0123456789abcdef")
+	m := &mockRequireChangeECS{
+		mockMultiClusterECS: &mockMultiClusterECS{updated: make(map[string]string)},
+		current:             current,
+	}
+	app.ecs = m
+	app.ecr = &mockECR{digests: map[string]string{"app:v1": "sha256:0123456789abcdef"}}
+	app.autoScaling = &mockAutoScaling{}
+	app.sts = &mockSTS{}
+	err = app.Deploy(DeployOption{
+		DryRun:             aws.Bool(false),
+		DesiredCount:       aws.Int64(KeepDesiredCount),
+		SkipTaskDefinition: aws.Bool(false),
+		ForceNewDeployment: aws.Bool(false),
+		NoWait:             aws.Bool(true),
+		UpdateService:      aws.Bool(false),
+		AllowNoChange:      aws.Bool(false),
+	})
+	if errors.Cause(err) != ErrNoChange {
+		t.Errorf("the tag resolved to the same digest must not be a change: %v", err)
+	}
+	if m.registered != 0 {
+		t.Error("task definition must not be registered without changes")
+	}
+}
+
+func TestRequireTaskDefinitionChanged(t *testing.T) {
+	current := testDiffTaskDefinition()
+	app := &App{
+		config: &Config{},
+		ecs: &mockDiffECS{
+			taskDefinitions: map[string]*ecs.TaskDefinition{
+				*current.TaskDefinitionArn: current,
+			},
+		},
+	}
+	ctx := context.Background()
+	td := testDiffTaskDefinition()
+	td.ContainerDefinitions[0].Image = aws.String("app:v2")
+	if err := app.requireTaskDefinitionChanged(ctx, *current.TaskDefinitionArn, td); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	// another family is a change
+	td = testDiffTaskDefinition()
+	td.Family = aws.String("other")
+	if err := app.requireTaskDefinitionChanged(ctx, *current.TaskDefinitionArn, td); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if err := app.requireTaskDefinitionChanged(ctx, *current.TaskDefinitionArn, testDiffTaskDefinition()); errors.Cause(err) != ErrNoChange {
+		t.Errorf("unexpected error %v", err)
+	}
+}
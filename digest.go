@@ -0,0 +1,70 @@
+package ecspresso
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+var ecrImageRegexp = regexp.MustCompile(`^(\d{12})\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?/([^:@]+):([^:@/]+)$`)
+
+type ecrImage struct {
+	registryID string
+	region     string
+	repository string
+	tag        string
+}
+
+func parseECRImage(image string) (*ecrImage, bool) {
+	m := ecrImageRegexp.FindStringSubmatch(image)
+	if m == nil {
+		return nil, false
+	}
+	return &ecrImage{registryID: m[1], region: m[2], repository: m[3], tag: m[4]}, true
+}
+
+// resolveImageDigests rewrites images of containers from tags to digests by ECR.
+// Images which could not be resolved are left untouched with warnings.
+func (d *App) resolveImageDigests(ctx context.Context, td *ecs.TaskDefinition) {
+	for _, c := range td.ContainerDefinitions {
+		image := aws.StringValue(c.Image)
+		if strings.Contains(image, "@") {
+			continue
+		}
+		resolved, err := d.resolveImageDigest(ctx, image)
+		if err != nil {
+			d.Log("[WARNING] image", image, "is not resolved to digest:", err)
+			continue
+		}
+		d.Log("Image", image, "is resolved to", resolved)
+		c.Image = aws.String(resolved)
+	}
+}
+
+func (d *App) resolveImageDigest(ctx context.Context, image string) (string, error) {
+	img, ok := parseECRImage(image)
+	if !ok {
+		return "", errors.New("not an ECR image")
+	}
+	if img.region != d.region {
+		return "", errors.Errorf("ECR in other region %s is not supported", img.region)
+	}
+	out, err := d.ecr.DescribeImagesWithContext(ctx, &ecr.DescribeImagesInput{
+		RegistryId:     aws.String(img.registryID),
+		RepositoryName: aws.String(img.repository),
+		ImageIds:       []*ecr.ImageIdentifier{{ImageTag: aws.String(img.tag)}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(out.ImageDetails) == 0 || out.ImageDetails[0].ImageDigest == nil {
+		return "", errors.New("image is not found")
+	}
+	repo := image[:strings.LastIndex(image, ":")]
+	return repo + "@" + *out.ImageDetails[0].ImageDigest, nil
+}
@@ -0,0 +1,60 @@
+package ecspresso
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+type mockECR struct {
+	ecriface.ECRAPI
+	digests map[string]string
+}
+
+func (m *mockECR) DescribeImagesWithContext(_ aws.Context, in *ecr.DescribeImagesInput, _ ...request.Option) (*ecr.DescribeImagesOutput, error) {
+	digest, ok := m.digests[*in.RepositoryName+":"+*in.ImageIds[0].ImageTag]
+	if !ok {
+		return nil, awserr.New(ecr.ErrCodeImageNotFoundException, "image not found", nil)
+	}
+	return &ecr.DescribeImagesOutput{
+		ImageDetails: []*ecr.ImageDetail{{ImageDigest: aws.String(digest)}},
+	}, nil
+}
+
+func TestResolveImageDigests(t *testing.T) {
+	app := &App{
+		Service: "test",
+		Cluster: "default",
+		config:  &Config{},
+		region:  "ap-northeast-1",
+		ecr: &mockECR{
+			digests: map[string]string{"app:v1": "sha256:0123456789abcdef"},
+		},
+	}
+	images := map[string]string{
+		"123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:v1":                      "123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app@sha256:0123456789abcdef",
+		"123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:v2":                      "123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:v2",
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1":                           "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1",
+		"123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app@sha256:fedcba9876543210": "123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app@sha256:fedcba9876543210",
+		"nginx:1.19": "nginx:1.19",
+	}
+	td := &ecs.TaskDefinition{}
+	for image := range images {
+		td.ContainerDefinitions = append(td.ContainerDefinitions, &ecs.ContainerDefinition{
+			Name:  aws.String(image),
+			Image: aws.String(image),
+		})
+	}
+	app.resolveImageDigests(context.Background(), td)
+	for _, c := range td.ContainerDefinitions {
+		if expected := images[*c.Name]; *c.Image != expected {
+			t.Errorf("image %s expected %s got %s", *c.Name, expected, *c.Image)
+		}
+	}
+}
@@ -4,21 +4,39 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/Songmu/prompter"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling/applicationautoscalingiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
 	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/codedeploy/codedeployiface"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
 	"github.com/kayac/go-config"
 	"github.com/mattn/go-isatty"
 	"github.com/morikuni/aec"
@@ -37,16 +55,33 @@ func taskDefinitionName(t *ecs.TaskDefinition) string {
 }
 
 type App struct {
-	ecs         *ecs.ECS
-	autoScaling *applicationautoscaling.ApplicationAutoScaling
-	codedeploy  *codedeploy.CodeDeploy
-	cwl         *cloudwatchlogs.CloudWatchLogs
+	ecs         ecsiface.ECSAPI
+	autoScaling applicationautoscalingiface.ApplicationAutoScalingAPI
+	codedeploy  codedeployiface.CodeDeployAPI
+	cwl         cloudwatchlogsiface.CloudWatchLogsAPI
+	cw          cloudwatchiface.CloudWatchAPI
+	iam         iamiface.IAMAPI
+	sts         stsiface.STSAPI
+	s3          s3iface.S3API
+	ecr         ecriface.ECRAPI
+	elbv2       elbv2iface.ELBV2API
+	sns         snsiface.SNSAPI
 	Service     string
 	Cluster     string
 	config      *Config
 	Debug       bool
 
-	loader *config.Loader
+	loader        *config.Loader
+	region        string
+	deployID      string
+	accountID     string
+	registeredArn string
+	tracer        tracer
+	logOutput     io.Writer
+	credentials   *credentials.Credentials
+
+	// stdoutReserved is true when STDOUT is reserved for the result of the command (e.g. status --task-definition).
+	stdoutReserved bool
 }
 
 func (d *App) DescribeServicesInput() *ecs.DescribeServicesInput {
@@ -77,22 +112,22 @@ func (d *App) DescribeServiceStatus(ctx context.Context, events int) (*ecs.Servi
 		return nil, errors.Wrap(err, "failed to describe service")
 	}
 	if len(out.Services) == 0 {
-		return nil, errors.New("service is not found")
+		return nil, ErrServiceNotFound
 	}
 	s := out.Services[0]
-	fmt.Println("Service:", *s.ServiceName)
-	fmt.Println("Cluster:", arnToName(*s.ClusterArn))
-	fmt.Println("TaskDefinition:", arnToName(*s.TaskDefinition))
+	fmt.Fprintln(d.stdout(), "Service:", *s.ServiceName)
+	fmt.Fprintln(d.stdout(), "Cluster:", arnToName(*s.ClusterArn))
+	fmt.Fprintln(d.stdout(), "TaskDefinition:", arnToName(*s.TaskDefinition))
 	if len(s.Deployments) > 0 {
-		fmt.Println("Deployments:")
+		fmt.Fprintln(d.stdout(), "Deployments:")
 		for _, dep := range s.Deployments {
-			fmt.Println(spcIndent + formatDeployment(dep))
+			fmt.Fprintln(d.stdout(), spcIndent+formatDeployment(dep))
 		}
 	}
 	if len(s.TaskSets) > 0 {
-		fmt.Println("TaskSets:")
+		fmt.Fprintln(d.stdout(), "TaskSets:")
 		for _, ts := range s.TaskSets {
-			fmt.Println(spcIndent + formatTaskSet(ts))
+			fmt.Fprintln(d.stdout(), spcIndent+formatTaskSet(ts))
 		}
 	}
 
@@ -100,13 +135,13 @@ func (d *App) DescribeServiceStatus(ctx context.Context, events int) (*ecs.Servi
 		return nil, errors.Wrap(err, "failed to describe autoscaling")
 	}
 
-	fmt.Println("Events:")
+	fmt.Fprintln(d.stdout(), "Events:")
 	for i, event := range s.Events {
 		if i >= events {
 			break
 		}
 		for _, line := range formatEvent(event, TerminalWidth) {
-			fmt.Println(line)
+			fmt.Fprintln(d.stdout(), line)
 		}
 	}
 	return s, nil
@@ -134,9 +169,9 @@ func (d *App) describeAutoScaling(s *ecs.Service) error {
 		return nil
 	}
 
-	fmt.Println("AutoScaling:")
+	fmt.Fprintln(d.stdout(), "AutoScaling:")
 	for _, target := range tout.ScalableTargets {
-		fmt.Println(formatScalableTarget(target))
+		fmt.Fprintln(d.stdout(), formatScalableTarget(target))
 	}
 
 	pout, err := d.autoScaling.DescribeScalingPolicies(
@@ -150,37 +185,55 @@ func (d *App) describeAutoScaling(s *ecs.Service) error {
 		return errors.Wrap(err, "failed to describe scaling policies")
 	}
 	for _, policy := range pout.ScalingPolicies {
-		fmt.Println(formatScalingPolicy(policy))
+		fmt.Fprintln(d.stdout(), formatScalingPolicy(policy))
 	}
 	return nil
 }
 
 func (d *App) DescribeServiceDeployments(ctx context.Context, startedAt time.Time) (int, error) {
+	lines, _, err := d.describeServiceDeployments(ctx, startedAt, nil)
+	return lines, err
+}
+
+// describeServiceDeployments prints deployments and events of the service.
+// It returns the number of printed lines, and a state string of the service which is changed by any transitions.
+// When printed is not nil, events already printed are skipped.
+func (d *App) describeServiceDeployments(ctx context.Context, startedAt time.Time, printed *eventBuffer) (int, string, error) {
 	out, err := d.ecs.DescribeServicesWithContext(ctx, d.DescribeServicesInput())
 	if err != nil {
-		return 0, err
+		return 0, "", err
 	}
 	if len(out.Services) == 0 {
-		return 0, nil
+		return 0, "", nil
 	}
 	s := out.Services[0]
 	lines := 0
+	var state strings.Builder
 	for _, dep := range s.Deployments {
 		lines++
-		d.Log(formatDeployment(dep))
+		line := formatDeployment(dep)
+		state.WriteString(line + "\n")
+		d.Log(line)
 	}
 	for _, event := range s.Events {
 		if (*event.CreatedAt).After(startedAt) {
+			state.WriteString(*event.Id + "\n")
+			if printed != nil && !printed.add(event) {
+				continue
+			}
 			for _, line := range formatEvent(event, TerminalWidth) {
-				fmt.Println(line)
+				fmt.Fprintln(d.stdout(), line)
 				lines++
 			}
 		}
 	}
-	return lines, nil
+	if printed != nil {
+		printed.endPoll()
+	}
+	return lines, state.String(), nil
 }
 
-func (d *App) DescribeTask(ctx context.Context, task *ecs.Task) error {
+func (d *App) DescribeTask(ctx context.Context, task *ecs.Task, td *ecs.TaskDefinition) error {
 	out, err := d.ecs.DescribeTasksWithContext(ctx, d.DescribeTasksInput(task))
 	if err != nil {
 		return err
@@ -191,16 +244,70 @@ func (d *App) DescribeTask(ctx context.Context, task *ecs.Task) error {
 		return errors.New(*f.Reason)
 	}
 
-	c := out.Tasks[0].Containers[0]
-	if c.Reason != nil {
-		return errors.New(*c.Reason)
+	t := out.Tasks[0]
+	d.Log("Task stopped. stopCode:", aws.StringValue(t.StopCode), "stoppedReason:", aws.StringValue(t.StoppedReason))
+	for _, c := range t.Containers {
+		d.Log(fmt.Sprintf("Container %s exitCode:%s reason:%s", *c.Name, formatExitCode(c.ExitCode), aws.StringValue(c.Reason)))
+	}
+	return taskExitError(t, essentialContainerNames(td))
+}
+
+// ExitCodeError represents that an essential container of a task exited with non-zero code.
+type ExitCodeError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Message
+}
+
+func formatExitCode(code *int64) string {
+	if code == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*code, 10)
+}
+
+func essentialContainerNames(td *ecs.TaskDefinition) map[string]bool {
+	names := make(map[string]bool)
+	for _, c := range td.ContainerDefinitions {
+		// essential is true by default
+		if c.Essential == nil || *c.Essential {
+			names[*c.Name] = true
+		}
+	}
+	return names
+}
+
+// taskExitError returns an error for the stopped task.
+// When an essential container exited with non-zero code, returns *ExitCodeError with the code.
+// When the task failed to start or any essential container has no exit code (stopped by the platform), returns an error with code 1.
+func taskExitError(t *ecs.Task, essentials map[string]bool) error {
+	if aws.StringValue(t.StopCode) == ecs.TaskStopCodeTaskFailedToStart {
+		return &ExitCodeError{
+			Code:    1,
+			Message: "task failed to start: " + aws.StringValue(t.StoppedReason),
+		}
 	}
-	if c.ExitCode != nil && *c.ExitCode != 0 {
-		msg := "Exit Code: " + strconv.FormatInt(*c.ExitCode, 10)
-		if c.Reason != nil {
-			msg += ", Reason: " + *c.Reason
+	for _, c := range t.Containers {
+		if !essentials[*c.Name] {
+			continue
+		}
+		if c.ExitCode == nil {
+			msg := fmt.Sprintf("essential container %s was stopped without exit code: %s", *c.Name, aws.StringValue(t.StoppedReason))
+			if c.Reason != nil {
+				msg += ", Reason: " + *c.Reason
+			}
+			return &ExitCodeError{Code: 1, Message: msg}
+		}
+		if *c.ExitCode != 0 {
+			msg := fmt.Sprintf("essential container %s exited. Exit Code: %d", *c.Name, *c.ExitCode)
+			if c.Reason != nil {
+				msg += ", Reason: " + *c.Reason
+			}
+			return &ExitCodeError{Code: int(*c.ExitCode), Message: msg}
 		}
-		return errors.New(msg)
 	}
 	return nil
 }
@@ -227,26 +334,33 @@ func (d *App) GetLogEvents(ctx context.Context, logGroup string, logStream strin
 	lines := 0
 	for _, event := range out.Events {
 		for _, line := range formatLogEvent(event, TerminalWidth) {
-			fmt.Println(line)
+			fmt.Fprintln(d.stdout(), line)
 			lines++
 		}
 	}
 	return lines, nil
 }
 
+// NewApp returns an App with a new AWS session by conf.
 func NewApp(conf *Config) (*App, error) {
-	loader := config.New()
+	sess, err := NewSession(conf)
+	if err != nil {
+		return nil, err
+	}
+	return NewAppWithSession(conf, sess)
+}
+
+// NewAppWithSession returns an App using the shared AWS session, to deploy many services in one process.
+// The region and the deploy role in conf are applied to a copy of the session for the App.
+func NewAppWithSession(conf *Config, sess *session.Session) (*App, error) {
 	if err := conf.Validate(); err != nil {
 		return nil, errors.Wrap(err, "invalid configuration")
 	}
-	for _, f := range conf.templateFuncs {
-		loader.Funcs(f)
+	loader := newLoader(conf)
+	sess, err := appSession(conf, sess)
+	if err != nil {
+		return nil, err
 	}
-
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
-		Config:            aws.Config{Region: aws.String(conf.Region)},
-		SharedConfigState: session.SharedConfigEnable,
-	}))
 	d := &App{
 		Service:     conf.Service,
 		Cluster:     conf.Cluster,
@@ -254,14 +368,32 @@ func NewApp(conf *Config) (*App, error) {
 		autoScaling: applicationautoscaling.New(sess),
 		codedeploy:  codedeploy.New(sess),
 		cwl:         cloudwatchlogs.New(sess),
+		cw:          cloudwatch.New(sess),
+		iam:         iam.New(sess),
+		sts:         sts.New(sess),
+		s3:          s3.New(sess),
+		ecr:         ecr.New(sess),
+		elbv2:       elbv2.New(sess),
+		sns:         sns.New(sess),
 		config:      conf,
 		loader:      loader,
+		region:      aws.StringValue(sess.Config.Region),
+		credentials: sess.Config.Credentials,
+	}
+	logOutput, err := newLogOutput(conf)
+	if err != nil {
+		return nil, err
+	}
+	d.logOutput = logOutput
+	if conf.OTLPEndpoint != "" {
+		d.tracer = newOTLPTracer(conf.OTLPEndpoint, os.Getenv("TRACEPARENT"))
 	}
+	loader.Funcs(d.awsTemplateFuncs(d.region))
 	return d, nil
 }
 
 func (d *App) Start() (context.Context, context.CancelFunc) {
-	log.SetOutput(os.Stdout)
+	d.setLogOutput()
 
 	if d.config.Timeout > 0 {
 		return context.WithTimeout(context.Background(), d.config.Timeout)
@@ -271,15 +403,39 @@ func (d *App) Start() (context.Context, context.CancelFunc) {
 }
 
 func (d *App) Status(opt StatusOption) error {
+	if opt.Watch != nil && *opt.Watch > 0 {
+		return d.watchStatus(opt)
+	}
+	if aws.BoolValue(opt.TaskDefinition) {
+		d.stdoutReserved = true
+	}
 	ctx, cancel := d.Start()
 	defer cancel()
+	if aws.BoolValue(opt.TaskDefinition) {
+		return d.printServiceTaskDefinition(ctx)
+	}
+	if opt.Query != nil && *opt.Query != "" {
+		return d.queryServiceStatus(ctx, *opt.Query)
+	}
 	_, err := d.DescribeServiceStatus(ctx, *opt.Events)
 	return err
 }
 
+// printServiceTaskDefinition prints only the name (family:revision) of the task definition of the service to STDOUT,
+// for scripts. Logs are written to STDERR.
+func (d *App) printServiceTaskDefinition(ctx context.Context) error {
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, arnToName(aws.StringValue(sv.TaskDefinition)))
+	return nil
+}
+
 func (d *App) Create(opt CreateOption) error {
 	ctx, cancel := d.Start()
 	defer cancel()
+	d.setDeployID()
 
 	d.Log("Starting create service", opt.DryRunString())
 	svd, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
@@ -290,28 +446,63 @@ func (d *App) Create(opt CreateOption) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to load task definition")
 	}
+	if err := validateLoadBalancers(svd.LoadBalancers, td); err != nil {
+		return errors.Wrap(err, "invalid service definition")
+	}
+	if err := validateServiceConnect(svd.ServiceConnectConfiguration, td); err != nil {
+		return errors.Wrap(err, "invalid service definition")
+	}
+	if err := d.verifySecretsReadable(ctx, td); err != nil {
+		// resource-based policies of secrets may allow the role, so it is not fatal
+		d.Log("[WARNING]", err)
+	}
 
 	if *opt.DesiredCount != 1 {
 		svd.DesiredCount = opt.DesiredCount
 	}
 
+	controller := d.config.DeploymentController
+	if opt.DeploymentController != nil && *opt.DeploymentController != "" {
+		controller = *opt.DeploymentController
+	}
+	if err := setDeploymentController(svd, controller); err != nil {
+		return errors.Wrap(err, "invalid service definition")
+	}
+	if err := validateTags(d.tags()); err != nil {
+		return err
+	}
+	svd.Tags = mergeServiceTags(svd.Tags, d.tags())
+	d.setClientToken(svd)
+	external := svd.DeploymentController != nil && *svd.DeploymentController.Type == ecs.DeploymentControllerTypeExternal
+
 	if *opt.DryRun {
 		d.Log("task definition:", td.String())
 		d.Log("service definition:", svd.String())
-		d.Log("DRY RUN OK")
+		d.ResultLog("DRY RUN OK")
 		return nil
 	}
 
-	newTd, err := d.RegisterTaskDefinition(ctx, td)
-	if err != nil {
-		return errors.Wrap(err, "failed to register task definition")
+	if d.config.CreateClusterIfMissing {
+		if err := d.createClusterIfMissing(ctx); err != nil {
+			return errors.Wrap(err, "failed to create cluster")
+		}
+	}
+
+	if external {
+		// task definitions are specified by task sets for EXTERNAL deployment controller
+		d.Log("Skip registering a task definition for EXTERNAL deployment controller")
+	} else {
+		newTd, err := d.RegisterTaskDefinition(ctx, td)
+		if err != nil {
+			return errors.Wrap(err, "failed to register task definition")
+		}
+		svd.TaskDefinition = newTd.TaskDefinitionArn
 	}
-	svd.TaskDefinition = newTd.TaskDefinitionArn
 
 	if _, err := d.ecs.CreateServiceWithContext(ctx, svd); err != nil {
 		return errors.Wrap(err, "failed to create service")
 	}
-	d.Log("Service is created")
+	d.ResultLog("Service is created")
 
 	if *opt.NoWait {
 		return nil
@@ -323,7 +514,30 @@ func (d *App) Create(opt CreateOption) error {
 		return errors.Wrap(err, "failed to wait service stable")
 	}
 
-	d.Log("Service is stable now. Completed!")
+	d.ResultLog("Service is stable now. Completed!")
+	return nil
+}
+
+// setDeploymentController sets the deployment controller type to the service definition, and validates it.
+// When controller is empty, the deployment controller defined in the service definition is kept.
+func setDeploymentController(svd *ecs.CreateServiceInput, controller string) error {
+	if controller != "" {
+		svd.DeploymentController = &ecs.DeploymentController{Type: aws.String(controller)}
+	}
+	if svd.DeploymentController == nil {
+		return nil
+	}
+	t := aws.StringValue(svd.DeploymentController.Type)
+	switch t {
+	case ecs.DeploymentControllerTypeEcs:
+		return nil
+	case ecs.DeploymentControllerTypeCodeDeploy, ecs.DeploymentControllerTypeExternal:
+	default:
+		return fmt.Errorf("unsupported deployment controller type %s", t)
+	}
+	if dc := svd.DeploymentConfiguration; dc != nil && dc.DeploymentCircuitBreaker != nil && aws.BoolValue(dc.DeploymentCircuitBreaker.Enable) {
+		return fmt.Errorf("deployment circuit breaker is supported only for ECS deployment controller, not %s", t)
+	}
 	return nil
 }
 
@@ -338,13 +552,12 @@ func (d *App) Delete(opt DeleteOption) error {
 	}
 
 	if *opt.DryRun {
-		d.Log("DRY RUN OK")
+		d.ResultLog("DRY RUN OK")
 		return nil
 	}
 
 	if !*opt.Force {
-		service := prompter.Prompt(`Enter the service name to DELETE`, "")
-		if service != *sv.ServiceName {
+		if !d.confirm("Enter the service name to DELETE", *sv.ServiceName, true) {
 			d.Log("Aborted")
 			return errors.New("confirmation failed")
 		}
@@ -357,7 +570,7 @@ func (d *App) Delete(opt DeleteOption) error {
 	if _, err := d.ecs.DeleteServiceWithContext(ctx, dsi); err != nil {
 		return errors.Wrap(err, "failed to delete service")
 	}
-	d.Log("Service is deleted")
+	d.ResultLog("Service is deleted")
 
 	return nil
 }
@@ -374,6 +587,18 @@ func (d *App) Run(opt RunOption) error {
 		}
 	}
 
+	startedBy := aws.StringValue(opt.StartedBy)
+	if startedBy == "" {
+		startedBy = defaultStartedBy(os.Getenv("USER"))
+	}
+	if err := validateStartedBy(startedBy); err != nil {
+		return err
+	}
+	opt.StartedBy = &startedBy
+	if err := validateTags(d.tags()); err != nil {
+		return err
+	}
+
 	sv, err := d.DescribeServiceStatus(ctx, 0)
 	if err != nil {
 		return errors.Wrap(err, "failed to describe service status")
@@ -381,6 +606,7 @@ func (d *App) Run(opt RunOption) error {
 
 	var tdArn string
 	var logConfiguration *ecs.LogConfiguration
+	var runTd *ecs.TaskDefinition
 
 	if *opt.SkipTaskDefinition {
 		td, err := d.DescribeTaskDefinition(ctx, *sv.TaskDefinition)
@@ -389,6 +615,7 @@ func (d *App) Run(opt RunOption) error {
 		}
 		tdArn = *(td.TaskDefinitionArn)
 		logConfiguration = td.ContainerDefinitions[0].LogConfiguration
+		runTd = td
 		if *opt.DryRun {
 			d.Log("task definition:", td.String())
 		}
@@ -419,28 +646,29 @@ func (d *App) Run(opt RunOption) error {
 			}
 			tdArn = *newTd.TaskDefinitionArn
 			logConfiguration = newTd.ContainerDefinitions[0].LogConfiguration
+			runTd = newTd
 		}
 	}
 	if *opt.DryRun {
-		d.Log("DRY RUN OK")
+		d.ResultLog("DRY RUN OK")
 		return nil
 	}
 
-	task, err := d.RunTask(ctx, tdArn, sv, &ov, *opt.Count)
+	task, err := d.RunTask(ctx, tdArn, sv, &ov, opt)
 	if err != nil {
 		return errors.Wrap(err, "failed to run task")
 	}
 	if *opt.NoWait {
-		d.Log("Run task invoked")
+		d.ResultLog("Run task invoked")
 		return nil
 	}
 	if err := d.WaitRunTask(ctx, task, logConfiguration, time.Now()); err != nil {
 		return errors.Wrap(err, "failed to run task")
 	}
-	if err := d.DescribeTask(ctx, task); err != nil {
-		return errors.Wrap(err, "failed to describe task")
+	if err := d.DescribeTask(ctx, task, runTd); err != nil {
+		return errors.Wrap(err, "failed to run task")
 	}
-	d.Log("Run task completed!")
+	d.ResultLog("Run task completed!")
 
 	return nil
 }
@@ -455,7 +683,7 @@ func (d *App) Wait(opt WaitOption) error {
 		return errors.Wrap(err, "the service still unstable")
 	}
 
-	d.Log("Service is stable now. Completed!")
+	d.ResultLog("Service is stable now. Completed!")
 	return nil
 }
 
@@ -494,17 +722,42 @@ func (d *App) Name() string {
 	return fmt.Sprintf("%s/%s", d.Service, d.Cluster)
 }
 
-func (d *App) Log(v ...interface{}) {
+type logLevel int
+
+const (
+	logLevelDebug logLevel = iota
+	logLevelInfo
+	logLevelResult
+)
+
+func (d *App) log(level logLevel, v ...interface{}) {
+	switch {
+	case level == logLevelDebug && !d.Debug:
+		return
+	case level < logLevelResult && d.config.Quiet:
+		return
+	}
 	args := []interface{}{d.Name()}
+	if d.deployID != "" {
+		args = append(args, "deploy_id:"+d.deployID)
+	}
 	args = append(args, v...)
-	log.Println(args...)
+	log.Print(d.Mask(fmt.Sprintln(args...)))
 }
 
+// Log logs progress. It is suppressed in quiet mode.
+func (d *App) Log(v ...interface{}) {
+	d.log(logLevelInfo, v...)
+}
+
+// DebugLog logs only in debug mode.
 func (d *App) DebugLog(v ...interface{}) {
-	if !d.Debug {
-		return
-	}
-	d.Log(v...)
+	d.log(logLevelDebug, v...)
+}
+
+// ResultLog logs a final outcome of commands. It is logged even in quiet mode.
+func (d *App) ResultLog(v ...interface{}) {
+	d.log(logLevelResult, v...)
 }
 
 func (d *App) WaitServiceStable(ctx context.Context, startedAt time.Time) error {
@@ -512,82 +765,156 @@ func (d *App) WaitServiceStable(ctx context.Context, startedAt time.Time) error
 	waitCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	go func() {
-		tick := time.Tick(10 * time.Second)
-		var lines int
-		for {
-			select {
-			case <-waitCtx.Done():
-				return
-			case <-tick:
+	if !d.config.Quiet {
+		var printed *eventBuffer
+		if !isTerminal {
+			// outputs are appended, so print each event only once
+			printed = newEventBuffer(d.config.EventBufferSize)
+		}
+		go func() {
+			var lines int
+			var state string
+			b := newPollBackoff(d.config.PollIntervalMin, d.config.PollIntervalMax)
+			pollWithBackoff(waitCtx, b, time.After, func() bool {
 				if isTerminal {
 					for i := 0; i < lines; i++ {
-						fmt.Print(aec.EraseLine(aec.EraseModes.All), aec.PreviousLine(1))
+						fmt.Fprint(d.stdout(), aec.EraseLine(aec.EraseModes.All), aec.PreviousLine(1))
 					}
 				}
-				lines, _ = d.DescribeServiceDeployments(waitCtx, startedAt)
-			}
+				var newState string
+				lines, newState, _ = d.describeServiceDeployments(waitCtx, startedAt, printed)
+				changed := newState != state
+				state = newState
+				return changed
+			})
+		}()
+	}
+
+	parent := ctx
+	timeout := d.config.Timeout
+	if d.config.WaitTimeout > 0 {
+		timeout = d.config.WaitTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var err error
+	if d.config.WaitForSteadyStateEvent {
+		err = d.waitSteadyStateEvent(ctx, startedAt, time.After)
+	} else {
+		err = d.waitServiceRollout(ctx, time.After)
+	}
+	if err != nil {
+		if isWaitTimeout(err) {
+			err = phaseTimeoutError(parent, ctx, "wait", d.config.WaitTimeout, errors.Wrap(ErrWaitTimeout, err.Error()))
 		}
-	}()
-
-	// Add an option WithWaiterDelay and request.WithWaiterMaxAttempts for a long timeout.
-	// SDK Default is 10 min (MaxAttempts=40 * Delay=15sec) at now.
-	// ref. https://github.com/aws/aws-sdk-go/blob/d57c8d96f72d9475194ccf18d2ba70ac294b0cb3/service/ecs/waiters.go#L82-L83
-	// Explicitly set these options so not being affected by the default setting.
-	const delay = 15 * time.Second
-	attempts := int((d.config.Timeout / delay)) + 1
-	if (d.config.Timeout % delay) > 0 {
-		attempts++
-	}
-	return d.ecs.WaitUntilServicesStableWithContext(
-		ctx, d.DescribeServicesInput(),
-		request.WithWaiterDelay(request.ConstantWaiterDelay(delay)),
-		request.WithWaiterMaxAttempts(attempts),
-	)
+		if reasons := d.diagnoseUnstableService(startedAt); len(reasons) > 0 {
+			return errors.Wrap(err, "likely causes: "+strings.Join(reasons, "; "))
+		}
+		return err
+	}
+	if d.config.MinStableDuration > 0 {
+		if err := d.waitMinStableDuration(ctx); err != nil {
+			return phaseTimeoutError(parent, ctx, "wait", d.config.WaitTimeout, err)
+		}
+	}
+	if d.config.PostStableTargetHealthCheck {
+		if err := d.waitTargetsHealthy(ctx, time.After); err != nil {
+			return phaseTimeoutError(parent, ctx, "wait", d.config.WaitTimeout, err)
+		}
+	}
+	if d.config.PostStableContainerHealthCheck || d.config.PrimaryContainer != "" {
+		if err := d.waitContainersHealthy(ctx, time.After); err != nil {
+			return phaseTimeoutError(parent, ctx, "wait", d.config.WaitTimeout, err)
+		}
+	}
+	if d.config.PostDeployProbeURL != "" {
+		return phaseTimeoutError(parent, ctx, "wait", d.config.WaitTimeout, d.waitProbeSucceeded(ctx, newHTTPProbe(d.config), time.After))
+	}
+	return nil
 }
 
 func (d *App) RegisterTaskDefinition(ctx context.Context, td *ecs.TaskDefinition) (*ecs.TaskDefinition, error) {
+	return d.registerTaskDefinition(ctx, td, d.config.ResolveImageDigests)
+}
+
+func (d *App) registerTaskDefinition(ctx context.Context, td *ecs.TaskDefinition, resolveDigests bool) (*ecs.TaskDefinition, error) {
+	parent := ctx
+	ctx, cancel := phaseContext(ctx, d.config.RegisterTimeout)
+	defer cancel()
+
+	if resolveDigests {
+		d.resolveImageDigests(ctx, td)
+	}
+	if err := d.validateTaskDefinition(td); err != nil {
+		return nil, err
+	}
+	if err := validateTags(d.tags()); err != nil {
+		return nil, err
+	}
 	d.Log("Registering a new task definition...")
 
-	out, err := d.ecs.RegisterTaskDefinitionWithContext(
-		ctx,
-		&ecs.RegisterTaskDefinitionInput{
-			ContainerDefinitions:    td.ContainerDefinitions,
-			Cpu:                     td.Cpu,
-			ExecutionRoleArn:        td.ExecutionRoleArn,
-			Family:                  td.Family,
-			Memory:                  td.Memory,
-			NetworkMode:             td.NetworkMode,
-			PlacementConstraints:    td.PlacementConstraints,
-			RequiresCompatibilities: td.RequiresCompatibilities,
-			TaskRoleArn:             td.TaskRoleArn,
-			ProxyConfiguration:      td.ProxyConfiguration,
-			Volumes:                 td.Volumes,
-		},
-	)
+	in := registerTaskDefinitionInput(td)
+	in.Tags = append(ecsTags(d.tags()), d.deployIDTags()...)
+	d.writeArtifact(in, time.Now())
+	d.DebugLog("register task definition payload:", d.jsonForLog(in))
+	out, err := d.ecs.RegisterTaskDefinitionWithContext(ctx, in)
 	if err != nil {
-		return nil, err
+		return nil, phaseTimeoutError(parent, ctx, "register", d.config.RegisterTimeout, err)
+	}
+	d.ResultLog("Task definition is registered", taskDefinitionName(out.TaskDefinition))
+	if d.config.PrintRevision {
+		fmt.Fprintln(os.Stdout, aws.Int64Value(out.TaskDefinition.Revision))
 	}
-	d.Log("Task definition is registered", taskDefinitionName(out.TaskDefinition))
 	return out.TaskDefinition, nil
 }
 
+func registerTaskDefinitionInput(td *ecs.TaskDefinition) *ecs.RegisterTaskDefinitionInput {
+	return &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions:    td.ContainerDefinitions,
+		Cpu:                     td.Cpu,
+		ExecutionRoleArn:        td.ExecutionRoleArn,
+		Family:                  td.Family,
+		Memory:                  td.Memory,
+		NetworkMode:             td.NetworkMode,
+		PlacementConstraints:    td.PlacementConstraints,
+		RequiresCompatibilities: td.RequiresCompatibilities,
+		TaskRoleArn:             td.TaskRoleArn,
+		ProxyConfiguration:      td.ProxyConfiguration,
+		RuntimePlatform:         td.RuntimePlatform,
+		Volumes:                 td.Volumes,
+	}
+}
+
 func (d *App) LoadTaskDefinition(path string) (*ecs.TaskDefinition, error) {
 	d.Log("Creating a new task definition by", path)
+	src, err := d.readDefinitionFile(path)
+	if err != nil {
+		return nil, err
+	}
 	c := struct {
 		TaskDefinition *ecs.TaskDefinition
 	}{}
-	if err := d.loader.LoadWithEnvJSON(&c, path); err != nil {
-		return nil, err
+	if err := d.loader.LoadWithEnvJSONBytes(&c, src); err != nil {
+		return nil, errors.Wrapf(err, "%s load failed", path)
+	}
+	td := c.TaskDefinition
+	if td == nil {
+		td = &ecs.TaskDefinition{}
+		if err := d.loader.LoadWithEnvJSONBytes(td, src); err != nil {
+			return nil, errors.Wrapf(err, "%s load failed", path)
+		}
 	}
-	if c.TaskDefinition != nil {
-		return c.TaskDefinition, nil
+	applyFamilySuffix(td, d.config.FamilySuffix)
+	if err := d.applyImageManifest(td); err != nil {
+		return nil, err
 	}
-	var td ecs.TaskDefinition
-	if err := d.loader.LoadWithEnvJSON(&td, path); err != nil {
+	if err := applyCommandOverrides(td, d.config.CommandOverrides); err != nil {
 		return nil, err
 	}
-	return &td, nil
+	return td, nil
 }
 
 func (d *App) LoadServiceDefinition(path string) (*ecs.CreateServiceInput, error) {
@@ -595,8 +922,18 @@ func (d *App) LoadServiceDefinition(path string) (*ecs.CreateServiceInput, error
 		return nil, errors.New("service_definition is not defined")
 	}
 
+	src, err := d.readDefinitionFile(path)
+	if err != nil {
+		return nil, err
+	}
 	c := ecs.CreateServiceInput{}
-	if err := d.loader.LoadWithEnvJSON(&c, path); err != nil {
+	if err := d.loadServiceDefinitionBytes(path, src, &c); err != nil {
+		return nil, err
+	}
+	if err := normalizeNetworkConfiguration(c.NetworkConfiguration); err != nil {
+		return nil, errors.Wrap(err, "invalid networkConfiguration")
+	}
+	if err := d.applySpotWithFallback(&c); err != nil {
 		return nil, err
 	}
 
@@ -631,24 +968,27 @@ func (d *App) GetLogInfo(task *ecs.Task, lc *ecs.LogConfiguration) (string, stri
 	return logGroup, logStream
 }
 
-func (d *App) RunTask(ctx context.Context, tdArn string, sv *ecs.Service, ov *ecs.TaskOverride, count int64) (*ecs.Task, error) {
-	d.Log("Running task")
-
-	out, err := d.ecs.RunTaskWithContext(
-		ctx,
-		&ecs.RunTaskInput{
-			Cluster:                  aws.String(d.Cluster),
-			TaskDefinition:           aws.String(tdArn),
-			NetworkConfiguration:     sv.NetworkConfiguration,
-			LaunchType:               sv.LaunchType,
-			Overrides:                ov,
-			Count:                    aws.Int64(count),
-			CapacityProviderStrategy: sv.CapacityProviderStrategy,
-			PlacementConstraints:     sv.PlacementConstraints,
-			PlacementStrategy:        sv.PlacementStrategy,
-			PlatformVersion:          sv.PlatformVersion,
-		},
-	)
+func (d *App) RunTask(ctx context.Context, tdArn string, sv *ecs.Service, ov *ecs.TaskOverride, opt RunOption) (*ecs.Task, error) {
+	d.Log("Running task", "startedBy:", aws.StringValue(opt.StartedBy))
+
+	in := &ecs.RunTaskInput{
+		Cluster:                  aws.String(d.Cluster),
+		TaskDefinition:           aws.String(tdArn),
+		NetworkConfiguration:     sv.NetworkConfiguration,
+		LaunchType:               sv.LaunchType,
+		Overrides:                ov,
+		Count:                    opt.Count,
+		CapacityProviderStrategy: sv.CapacityProviderStrategy,
+		PlacementConstraints:     sv.PlacementConstraints,
+		PlacementStrategy:        sv.PlacementStrategy,
+		PlatformVersion:          sv.PlatformVersion,
+		StartedBy:                opt.StartedBy,
+		Tags:                     ecsTags(d.tags()),
+	}
+	if p := aws.StringValue(opt.PropagateTags); p != "" {
+		in.PropagateTags = aws.String(p)
+	}
+	out, err := d.ecs.RunTaskWithContext(ctx, in)
 	if err != nil {
 		return nil, err
 	}
@@ -686,7 +1026,7 @@ func (d *App) WaitRunTask(ctx context.Context, task *ecs.Task, lc *ecs.LogConfig
 			case <-tick:
 				if isTerminal {
 					for i := 0; i < lines; i++ {
-						fmt.Print(aec.EraseLine(aec.EraseModes.All), aec.PreviousLine(1))
+						fmt.Fprint(d.stdout(), aec.EraseLine(aec.EraseModes.All), aec.PreviousLine(1))
 					}
 				}
 				lines, _ = d.GetLogEvents(waitCtx, logGroup, logStream, startedAt)
@@ -707,7 +1047,7 @@ func (d *App) Register(opt RegisterOption) error {
 	}
 	if *opt.DryRun {
 		d.Log("task definition:", td.String())
-		d.Log("DRY RUN OK")
+		d.ResultLog("DRY RUN OK")
 		return nil
 	}
 
@@ -717,7 +1057,7 @@ func (d *App) Register(opt RegisterOption) error {
 	}
 
 	if *opt.Output {
-		fmt.Println(newTd.String())
+		fmt.Fprintln(d.stdout(), newTd.String())
 	}
 	return nil
 }
@@ -1,6 +1,10 @@
 package ecspresso_test
 
 import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,3 +30,31 @@ func TestLoadTaskDefinition(t *testing.T) {
 		}
 	}
 }
+
+func TestQuietLog(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	app, err := ecspresso.NewApp(&ecspresso.Config{
+		Region:             "ap-northeast-1",
+		Service:            "test",
+		Cluster:            "default",
+		TaskDefinitionPath: "tests/td.json",
+		Quiet:              true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Log("progress")
+	app.DebugLog("debug")
+	app.ResultLog("completed")
+
+	out := buf.String()
+	if strings.Contains(out, "progress") || strings.Contains(out, "debug") {
+		t.Errorf("informational logs must be suppressed: %s", out)
+	}
+	if !strings.Contains(out, "test/default completed") {
+		t.Errorf("result log must be logged: %s", out)
+	}
+}
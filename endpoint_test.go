@@ -0,0 +1,43 @@
+package ecspresso
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func TestEndpointURL(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		TaskDefinitionPath: "tests/td.json",
+		EndpointURL:        "http://localhost:4566",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, endpoint := range map[string]string{
+		"ecs": app.ecs.(*ecs.ECS).Endpoint,
+		"sts": app.sts.(*sts.STS).Endpoint,
+		"ecr": app.ecr.(*ecr.ECR).Endpoint,
+	} {
+		if endpoint != "http://localhost:4566" {
+			t.Errorf("endpoint of %s must be overridden: %s", name, endpoint)
+		}
+	}
+
+	for _, u := range []string{"localhost:4566", "ftp://localhost", "http://"} {
+		_, err := NewApp(&Config{
+			Region:             "us-east-1",
+			TaskDefinitionPath: "tests/td.json",
+			EndpointURL:        u,
+		})
+		if err == nil || !strings.Contains(err.Error(), "is not a valid http(s) URL") {
+			t.Errorf("%s must be invalid: %v", u, err)
+		}
+	}
+}
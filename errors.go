@@ -0,0 +1,110 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrServiceNotFound represents that the service is not found in the cluster.
+	ErrServiceNotFound = errors.New("service is not found")
+	// ErrWaitTimeout represents that the service did not become stable until timeout.
+	ErrWaitTimeout = errors.New("timed out waiting for service stable")
+	// ErrDeploymentFailed represents that rolloutState of the deployment is FAILED.
+	ErrDeploymentFailed = errors.New("deployment failed")
+	// ErrDiffFound represents that plan found differences.
+	ErrDiffFound = errors.New("differences are found")
+	// ErrNoChange represents that the task definition to be deployed is identical to the current one.
+	ErrNoChange = errors.New("task definition is not changed")
+	// ErrPlanDrift represents that the service was changed after the plan.
+	ErrPlanDrift = errors.New("service has drifted since the plan")
+)
+
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+)
+
+// isWaitTimeout reports whether err is returned by waiters and ctx on timeout.
+func isWaitTimeout(err error) bool {
+	if errors.Cause(err) == context.DeadlineExceeded {
+		return true
+	}
+	if aerr, ok := errors.Cause(err).(awserr.Error); ok {
+		switch aerr.Code() {
+		case request.WaiterResourceNotReadyErrorCode, request.CanceledErrorCode:
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorType returns a type name of err for structured outputs.
+func ErrorType(err error) string {
+	switch e := errors.Cause(err); e.(type) {
+	case *ExitCodeError:
+		return "ExitCode"
+	case *ValidationError:
+		return "InvalidTaskDefinition"
+	case *DeployClustersError:
+		return "DeployClustersFailed"
+	default:
+		switch e {
+		case ErrServiceNotFound:
+			return "ServiceNotFound"
+		case ErrWaitTimeout:
+			return "WaitTimeout"
+		case ErrDeploymentFailed:
+			return "DeploymentFailed"
+		case ErrDiffFound:
+			return "DiffFound"
+		case ErrNoChange:
+			return "NoChange"
+		case ErrPlanDrift:
+			return "PlanDrift"
+		}
+		if aerr, ok := e.(awserr.Error); ok {
+			return "AWS." + aerr.Code()
+		}
+		return "Error"
+	}
+}
+
+// ErrorOutput is a structured output of an error in json output format.
+type ErrorOutput struct {
+	Error     string `json:"error"`
+	ErrorType string `json:"error_type"`
+	Service   string `json:"service"`
+	Cluster   string `json:"cluster"`
+	DeployID  string `json:"deploy_id,omitempty"`
+}
+
+// deployClustersErrorOutput is ErrorOutput with the results of the deploy to clusters.
+type deployClustersErrorOutput struct {
+	ErrorOutput
+	Results []ServiceResult `json:"results"`
+}
+
+// ErrorJSON returns JSON of err for json output format.
+func (d *App) ErrorJSON(err error) ([]byte, error) {
+	out := ErrorOutput{
+		Error:     err.Error(),
+		ErrorType: ErrorType(err),
+		Service:   d.Service,
+		Cluster:   d.Cluster,
+		DeployID:  d.deployID,
+	}
+	var v interface{} = out
+	if e, ok := errors.Cause(err).(*DeployClustersError); ok {
+		v = deployClustersErrorOutput{ErrorOutput: out, Results: e.Results}
+	}
+	b, jerr := json.Marshal(v)
+	if jerr != nil {
+		return nil, jerr
+	}
+	return append([]byte(d.Mask(string(b))), '\n'), nil
+}
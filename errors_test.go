@@ -0,0 +1,63 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pkg/errors"
+)
+
+func TestErrorJSON(t *testing.T) {
+	d := &App{
+		Service:  "app",
+		Cluster:  "default",
+		deployID: "d-1",
+	}
+	cases := []struct {
+		err      error
+		wantType string
+	}{
+		{ErrServiceNotFound, "ServiceNotFound"},
+		{errors.Wrap(ErrServiceNotFound, "failed to deploy"), "ServiceNotFound"},
+		{errors.Wrap(ErrWaitTimeout, "exceeded wait attempts"), "WaitTimeout"},
+		{&ExitCodeError{Code: 3, Message: "exit code 3"}, "ExitCode"},
+		{&ValidationError{Errors: []string{"family is not defined"}}, "InvalidTaskDefinition"},
+		{awserr.New("AccessDeniedException", "denied", nil), "AWS.AccessDeniedException"},
+		{errors.New("something wrong"), "Error"},
+	}
+	for _, c := range cases {
+		b, err := d.ErrorJSON(c.err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out ErrorOutput
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatal(err)
+		}
+		want := ErrorOutput{
+			Error:     c.err.Error(),
+			ErrorType: c.wantType,
+			Service:   "app",
+			Cluster:   "default",
+			DeployID:  "d-1",
+		}
+		if out != want {
+			t.Errorf("unexpected output %#v, want %#v", out, want)
+		}
+	}
+}
+
+func TestIsWaitTimeout(t *testing.T) {
+	if !isWaitTimeout(awserr.New(request.WaiterResourceNotReadyErrorCode, "exceeded wait attempts", nil)) {
+		t.Error("waiter error must be a timeout")
+	}
+	if !isWaitTimeout(errors.Wrap(context.DeadlineExceeded, "wait")) {
+		t.Error("deadline exceeded must be a timeout")
+	}
+	if isWaitTimeout(errors.New("failed")) {
+		t.Error("generic error must not be a timeout")
+	}
+}
@@ -0,0 +1,76 @@
+package ecspresso
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// DefaultEventBufferSize is a default number of printed service events remembered while waiting.
+const DefaultEventBufferSize = 1000
+
+// eventBuffer remembers IDs of printed service events up to size in a ring buffer, not to print them again.
+// The oldest entries are evicted, and events created until the evicted ones are also regarded as printed
+// from the next poll, because events not printed yet are always created after the printed ones.
+// Evictions in a poll don't advance the time in the same poll, since events are returned newest first
+// and older events of the poll may be not printed yet when the buffer is smaller than the poll.
+// Instead, IDs evicted in the poll are remembered until the poll ends.
+type eventBuffer struct {
+	ids          []string
+	createdAt    []time.Time
+	next         int
+	index        map[string]struct{}
+	evictedUntil time.Time
+	evicted      time.Time
+	evictedIDs   map[string]struct{}
+}
+
+func newEventBuffer(size int) *eventBuffer {
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+	return &eventBuffer{
+		ids:        make([]string, size),
+		createdAt:  make([]time.Time, size),
+		index:      make(map[string]struct{}, size),
+		evictedIDs: make(map[string]struct{}),
+	}
+}
+
+// add adds the event to the buffer. It reports whether the event is not printed yet.
+func (b *eventBuffer) add(ev *ecs.ServiceEvent) bool {
+	id, createdAt := aws.StringValue(ev.Id), aws.TimeValue(ev.CreatedAt)
+	if _, ok := b.index[id]; ok {
+		return false
+	}
+	if _, ok := b.evictedIDs[id]; ok {
+		return false
+	}
+	if !createdAt.After(b.evictedUntil) {
+		return false
+	}
+	if old := b.ids[b.next]; old != "" {
+		delete(b.index, old)
+		b.evictedIDs[old] = struct{}{}
+		if t := b.createdAt[b.next]; t.After(b.evicted) {
+			b.evicted = t
+		}
+	}
+	b.ids[b.next], b.createdAt[b.next] = id, createdAt
+	b.index[id] = struct{}{}
+	b.next = (b.next + 1) % len(b.ids)
+	return true
+}
+
+// endPoll applies evictions in the poll. It must be called after all events of a poll are added.
+func (b *eventBuffer) endPoll() {
+	if b.evicted.After(b.evictedUntil) {
+		b.evictedUntil = b.evicted
+	}
+	b.evictedIDs = make(map[string]struct{})
+}
+
+func (b *eventBuffer) len() int {
+	return len(b.index)
+}
@@ -0,0 +1,72 @@
+package ecspresso
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestEventBuffer(t *testing.T) {
+	now := time.Date(2017, 11, 9, 23, 33, 23, 0, time.UTC)
+	event := func(i int) *ecs.ServiceEvent {
+		return &ecs.ServiceEvent{
+			Id:        aws.String(fmt.Sprintf("event-%d", i)),
+			CreatedAt: aws.Time(now.Add(time.Duration(i) * time.Second)),
+		}
+	}
+	b := newEventBuffer(3)
+	var printed []string
+	// DescribeServices returns recent events newest first, and new events are added on each poll
+	for poll := 1; poll <= 10; poll++ {
+		for i := poll * 2; i > 0 && i > poll*2-6; i-- {
+			if ev := event(i); b.add(ev) {
+				printed = append(printed, *ev.Id)
+			}
+		}
+		b.endPoll()
+		if b.len() > 3 {
+			t.Errorf("buffer is not bounded: %d", b.len())
+		}
+	}
+	if len(printed) != 20 {
+		t.Errorf("unexpected printed events %d: %v", len(printed), printed)
+	}
+	seen := map[string]bool{}
+	for _, id := range printed {
+		if seen[id] {
+			t.Errorf("%s is printed twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestEventBufferSmallerThanPoll(t *testing.T) {
+	now := time.Date(2017, 11, 9, 23, 33, 23, 0, time.UTC)
+	event := func(i int) *ecs.ServiceEvent {
+		return &ecs.ServiceEvent{
+			Id:        aws.String(fmt.Sprintf("event-%d", i)),
+			CreatedAt: aws.Time(now.Add(time.Duration(i) * time.Second)),
+		}
+	}
+	b := newEventBuffer(3)
+	poll := func(newest, n int) []string {
+		var printed []string
+		for i := newest; i > newest-n; i-- {
+			if ev := event(i); b.add(ev) {
+				printed = append(printed, *ev.Id)
+			}
+		}
+		b.endPoll()
+		return printed
+	}
+	// the first poll returns 10 events, more than the buffer size
+	if printed := poll(10, 10); len(printed) != 10 {
+		t.Errorf("all events of the first poll must be printed: %v", printed)
+	}
+	if printed := poll(12, 10); len(printed) != 2 || printed[0] != "event-12" || printed[1] != "event-11" {
+		t.Errorf("only new events must be printed: %v", printed)
+	}
+}
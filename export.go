@@ -0,0 +1,216 @@
+package ecspresso
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+const (
+	ExportFormatTerraform      = "terraform"
+	ExportFormatCloudFormation = "cloudformation"
+)
+
+// Export prints the rendered task definition as a resource of Terraform or CloudFormation.
+func (d *App) Export(opt ExportOption) error {
+	td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to load task definition")
+	}
+	if err := d.validateTaskDefinition(td); err != nil {
+		return err
+	}
+	var warnings []string
+	switch f := aws.StringValue(opt.Format); f {
+	case ExportFormatTerraform:
+		warnings, err = exportTerraform(os.Stdout, td)
+	case ExportFormatCloudFormation:
+		err = exportCloudFormation(os.Stdout, td)
+	default:
+		return errors.Errorf("unsupported export format %s", f)
+	}
+	for _, w := range warnings {
+		d.Log("[WARNING]", w)
+	}
+	return err
+}
+
+var terraformInvalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// terraformResourceName returns a resource name of Terraform by the family.
+func terraformResourceName(family string) string {
+	name := terraformInvalidNameChars.ReplaceAllString(family, "_")
+	if name == "" || !(name[0] == '_' || ('a' <= name[0] && name[0] <= 'z') || ('A' <= name[0] && name[0] <= 'Z')) {
+		name = "_" + name
+	}
+	return name
+}
+
+// hclString returns a quoted string literal of HCL. Template sequences are escaped.
+func hclString(s string) string {
+	q := strconv.Quote(s)
+	q = strings.Replace(q, "${", "$${", -1)
+	return strings.Replace(q, "%{", "%%{", -1)
+}
+
+// exportTerraform writes an aws_ecs_task_definition resource of Terraform.
+// It returns warnings for fields which are not exported.
+func exportTerraform(w io.Writer, td *ecs.TaskDefinition) ([]string, error) {
+	var warnings []string
+	raw, err := jsonutil.BuildJSON(td.ContainerDefinitions)
+	if err != nil {
+		return nil, err
+	}
+	var cds []interface{}
+	if err := json.Unmarshal(raw, &cds); err != nil {
+		return nil, err
+	}
+	indented, err := encodeJSON(canonicalContainerDefinitions(cds), "  ")
+	if err != nil {
+		return nil, err
+	}
+	// heredoc strings are also templates
+	containerDefinitions := strings.Replace(string(indented), "${", "$${", -1)
+	containerDefinitions = strings.Replace(containerDefinitions, "%{", "%%{", -1)
+
+	b := &bytes.Buffer{}
+	attr := func(indent, key string, value *string) {
+		if v := aws.StringValue(value); v != "" {
+			fmt.Fprintf(b, "%s%s = %s\n", indent, key, hclString(v))
+		}
+	}
+	fmt.Fprintf(b, "resource \"aws_ecs_task_definition\" %s {\n", hclString(terraformResourceName(aws.StringValue(td.Family))))
+	attr("  ", "family", td.Family)
+	attr("  ", "task_role_arn", td.TaskRoleArn)
+	attr("  ", "execution_role_arn", td.ExecutionRoleArn)
+	attr("  ", "network_mode", td.NetworkMode)
+	attr("  ", "cpu", td.Cpu)
+	attr("  ", "memory", td.Memory)
+	if len(td.RequiresCompatibilities) > 0 {
+		cs := make([]string, 0, len(td.RequiresCompatibilities))
+		for _, c := range td.RequiresCompatibilities {
+			cs = append(cs, hclString(aws.StringValue(c)))
+		}
+		fmt.Fprintf(b, "  requires_compatibilities = [%s]\n", strings.Join(cs, ", "))
+	}
+	fmt.Fprintf(b, "  container_definitions = <<EOT\n%s\nEOT\n", containerDefinitions)
+	for _, v := range td.Volumes {
+		fmt.Fprintln(b, "\n  volume {")
+		attr("    ", "name", v.Name)
+		if v.Host != nil {
+			attr("    ", "host_path", v.Host.SourcePath)
+		}
+		if c := v.EfsVolumeConfiguration; c != nil {
+			fmt.Fprintln(b, "    efs_volume_configuration {")
+			attr("      ", "file_system_id", c.FileSystemId)
+			attr("      ", "root_directory", c.RootDirectory)
+			attr("      ", "transit_encryption", c.TransitEncryption)
+			fmt.Fprintln(b, "    }")
+			if c.AuthorizationConfig != nil || c.TransitEncryptionPort != nil {
+				warnings = append(warnings, fmt.Sprintf("volume %s: authorizationConfig and transitEncryptionPort are not exported", aws.StringValue(v.Name)))
+			}
+		}
+		if v.DockerVolumeConfiguration != nil || v.FsxWindowsFileServerVolumeConfiguration != nil {
+			warnings = append(warnings, fmt.Sprintf("volume %s: dockerVolumeConfiguration and fsxWindowsFileServerVolumeConfiguration are not exported", aws.StringValue(v.Name)))
+		}
+		fmt.Fprintln(b, "  }")
+	}
+	for _, pc := range td.PlacementConstraints {
+		fmt.Fprintln(b, "\n  placement_constraints {")
+		attr("    ", "type", pc.Type)
+		attr("    ", "expression", pc.Expression)
+		fmt.Fprintln(b, "  }")
+	}
+	if rp := td.RuntimePlatform; rp != nil {
+		fmt.Fprintln(b, "\n  runtime_platform {")
+		attr("    ", "operating_system_family", rp.OperatingSystemFamily)
+		attr("    ", "cpu_architecture", rp.CpuArchitecture)
+		fmt.Fprintln(b, "  }")
+	}
+	if td.ProxyConfiguration != nil {
+		warnings = append(warnings, "proxyConfiguration is not exported")
+	}
+	fmt.Fprintln(b, "}")
+	_, err = w.Write(b.Bytes())
+	return warnings, err
+}
+
+// cloudFormationKeys are keys which are not simply capitalized in CloudFormation.
+var cloudFormationKeys = map[string]string{
+	"efsVolumeConfiguration":                  "EFSVolumeConfiguration",
+	"fsxWindowsFileServerVolumeConfiguration": "FSxWindowsFileServerVolumeConfiguration",
+	"iam": "IAM",
+}
+
+// cloudFormationMapKeys are keys of objects which have arbitrary keys, such as options of log drivers.
+var cloudFormationMapKeys = map[string]bool{
+	"options":      true,
+	"dockerLabels": true,
+	"driverOpts":   true,
+	"labels":       true,
+}
+
+// toCloudFormation converts keys of the task definition JSON to properties of CloudFormation.
+func toCloudFormation(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		r := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			key, ok := cloudFormationKeys[k]
+			if !ok {
+				key = strings.ToUpper(k[:1]) + k[1:]
+			}
+			if cloudFormationMapKeys[k] {
+				r[key] = value
+			} else {
+				r[key] = toCloudFormation(value)
+			}
+		}
+		return r
+	case []interface{}:
+		r := make([]interface{}, 0, len(v))
+		for _, value := range v {
+			r = append(r, toCloudFormation(value))
+		}
+		return r
+	default:
+		return v
+	}
+}
+
+// exportCloudFormation writes a CloudFormation template which has an AWS::ECS::TaskDefinition resource.
+func exportCloudFormation(w io.Writer, td *ecs.TaskDefinition) error {
+	b, err := jsonutil.BuildJSON(registerTaskDefinitionInput(td))
+	if err != nil {
+		return err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	tmpl := map[string]interface{}{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Resources": map[string]interface{}{
+			"TaskDefinition": map[string]interface{}{
+				"Type":       "AWS::ECS::TaskDefinition",
+				"Properties": canonicalizeTaskDefinition(toCloudFormation(v)),
+			},
+		},
+	}
+	b, err = encodeJSON(tmpl, "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
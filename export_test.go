@@ -0,0 +1,114 @@
+package ecspresso
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func testExportTaskDefinition() *ecs.TaskDefinition {
+	return &ecs.TaskDefinition{
+		Family:                  aws.String("my-app"),
+		Cpu:                     aws.String("256"),
+		Memory:                  aws.String("512"),
+		NetworkMode:             aws.String("awsvpc"),
+		RequiresCompatibilities: aws.StringSlice([]string{"FARGATE"}),
+		ExecutionRoleArn:        aws.String("arn:aws:iam::123456789012:role/ecsTaskExecutionRole"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name:    aws.String("app"),
+				Image:   aws.String("app:v1"),
+				Command: aws.StringSlice([]string{"echo", "${HOME}"}),
+				LogConfiguration: &ecs.LogConfiguration{
+					LogDriver: aws.String("awslogs"),
+					Options:   map[string]*string{"awslogs-group": aws.String("/ecs/app")},
+				},
+			},
+		},
+		Volumes: []*ecs.Volume{
+			{
+				Name:                   aws.String("data"),
+				EfsVolumeConfiguration: &ecs.EFSVolumeConfiguration{FileSystemId: aws.String("fs-1234")},
+			},
+		},
+	}
+}
+
+func TestExportTerraform(t *testing.T) {
+	var buf bytes.Buffer
+	warnings, err := exportTerraform(&buf, testExportTaskDefinition())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	out := buf.String()
+	for _, s := range []string{
+		`resource "aws_ecs_task_definition" "my-app" {`,
+		`  family = "my-app"`,
+		`  cpu = "256"`,
+		`  requires_compatibilities = ["FARGATE"]`,
+		"  container_definitions = <<EOT\n[\n",
+		`"$${HOME}"`,
+		`"awslogs-group": "/ecs/app"`,
+		`      file_system_id = "fs-1234"`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("output must contain %s: %s", s, out)
+		}
+	}
+	if terraformResourceName("1app.v2") != "_1app_v2" {
+		t.Errorf("unexpected resource name %s", terraformResourceName("1app.v2"))
+	}
+}
+
+func TestExportCloudFormation(t *testing.T) {
+	var buf bytes.Buffer
+	td := testExportTaskDefinition()
+	td.Volumes[0].EfsVolumeConfiguration.TransitEncryption = aws.String("ENABLED")
+	td.Volumes[0].EfsVolumeConfiguration.AuthorizationConfig = &ecs.EFSAuthorizationConfig{Iam: aws.String("ENABLED")}
+	if err := exportCloudFormation(&buf, td); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"ContainerDefinitions"`, `"LogDriver"`, `"awslogs-group"`, `"EFSVolumeConfiguration"`, `"FileSystemId"`, `"IAM": "ENABLED"`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("output must contain %s: %s", s, buf.String())
+		}
+	}
+	var tmpl struct {
+		Resources map[string]struct {
+			Type       string
+			Properties struct {
+				Family               string
+				Cpu                  string
+				ContainerDefinitions []struct {
+					Name             string
+					LogConfiguration struct {
+						LogDriver string
+						Options   map[string]string
+					}
+				}
+				Volumes []map[string]interface{}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &tmpl); err != nil {
+		t.Fatal(err)
+	}
+	r := tmpl.Resources["TaskDefinition"]
+	p := r.Properties
+	if r.Type != "AWS::ECS::TaskDefinition" || p.Family != "my-app" || p.Cpu != "256" {
+		t.Errorf("unexpected resource %#v", r)
+	}
+	if c := p.ContainerDefinitions[0]; c.Name != "app" || c.LogConfiguration.LogDriver != "awslogs" || c.LogConfiguration.Options["awslogs-group"] != "/ecs/app" {
+		t.Errorf("unexpected container definition %#v", c)
+	}
+	if _, ok := p.Volumes[0]["EFSVolumeConfiguration"]; !ok {
+		t.Errorf("unexpected volume %#v", p.Volumes[0])
+	}
+}
@@ -0,0 +1,29 @@
+package ecspresso
+
+import (
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// familyNameRegexp is a pattern of family names accepted by RegisterTaskDefinition.
+var familyNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,255}$`)
+
+// applyFamilySuffix appends the suffix to the family of the task definition.
+func applyFamilySuffix(td *ecs.TaskDefinition, suffix string) {
+	if suffix == "" || td.Family == nil {
+		return
+	}
+	td.Family = aws.String(*td.Family + suffix)
+}
+
+// warnFamilyMismatch warns when the family of the task definition to be deployed
+// differs from the family of the task definition of the service.
+func (d *App) warnFamilyMismatch(serviceTdArn string, td *ecs.TaskDefinition) {
+	family, _ := parseTaskDefinitionName(arnToName(serviceTdArn))
+	if family == "" || family == aws.StringValue(td.Family) {
+		return
+	}
+	d.Log("[WARNING] family", aws.StringValue(td.Family), "differs from the family", family, "of the service. check family_suffix in config")
+}
@@ -0,0 +1,57 @@
+package ecspresso
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestFamilySuffix(t *testing.T) {
+	for _, path := range []string{"tests/td.json", "tests/td-plain.json"} {
+		app, err := NewApp(&Config{
+			Region:             "ap-northeast-1",
+			Timeout:            600 * time.Second,
+			Service:            "test",
+			Cluster:            "default",
+			TaskDefinitionPath: path,
+			FamilySuffix:       "-staging",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		td, err := app.LoadTaskDefinition(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f := aws.StringValue(td.Family); f != "katsubushi-staging" {
+			t.Errorf("%s: unexpected family %s", path, f)
+		}
+	}
+}
+
+func TestValidateFamilyName(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1"), Essential: aws.Bool(true)},
+		},
+	}
+	applyFamilySuffix(td, "-production")
+	if _, err := ValidateTaskDefinition(td); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	applyFamilySuffix(td, ".prod")
+	_, err := ValidateTaskDefinition(td)
+	if err == nil || !strings.Contains(err.Error(), "family app-production.prod must be") {
+		t.Errorf("unexpected error %v", err)
+	}
+	td.Family = aws.String(strings.Repeat("a", 250))
+	applyFamilySuffix(td, "-staging")
+	if _, err := ValidateTaskDefinition(td); err == nil {
+		t.Error("too long family must be invalid")
+	}
+}
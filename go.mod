@@ -5,11 +5,15 @@ go 1.12
 require (
 	github.com/Songmu/prompter v0.0.0-20150725163906-b5721e8d5566
 	github.com/alecthomas/kingpin v1.3.8-0.20190930021037-0a108b7f5563
-	github.com/aws/aws-sdk-go v1.30.0
+	github.com/aws/aws-sdk-go v1.44.330
+	github.com/evanphx/json-patch v4.12.0+incompatible
+	github.com/jmespath/go-jmespath v0.4.0
 	github.com/kayac/go-config v0.3.1
 	github.com/kayac/go-config/tfstate v0.0.0-20200331045335-c9a3d0bc9790
 	github.com/mattn/go-isatty v0.0.12
 	github.com/morikuni/aec v0.0.0-20170113033406-39771216ff4c
 	github.com/pkg/errors v0.9.1
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/stretchr/testify v1.5.1 // indirect
 	gopkg.in/yaml.v2 v2.2.8
 )
@@ -0,0 +1,92 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+const (
+	// DeployHistoryTagKey is the tag key of the service to record the last deploy.
+	DeployHistoryTagKey = "ecspresso:last-deploy"
+	// DeployHistoryTagKeyFmt is the format of the numbered tag keys to keep recent deploys (1 is the latest).
+	DeployHistoryTagKeyFmt = "ecspresso:deploy-history-%d"
+
+	maxTagValueLength = 256
+)
+
+// deployHistoryValue returns a tag value which describes the deploy.
+func deployHistoryValue(tdArn, deployID string, now time.Time) string {
+	v := fmt.Sprintf("revision=%s time=%s", arnToName(tdArn), now.UTC().Format(time.RFC3339))
+	if deployID != "" {
+		v += " deploy_id=" + deployID
+	}
+	return truncateTagValue(v)
+}
+
+// truncateTagValue truncates s to the maximum length of tag values with "..." suffix.
+func truncateTagValue(s string) string {
+	if utf8.RuneCountInString(s) <= maxTagValueLength {
+		return s
+	}
+	rs := []rune(s)
+	return string(rs[:maxTagValueLength-3]) + "..."
+}
+
+// deployHistoryTags returns tags to record the deploy described by value.
+// When keep is positive, the numbered history tags in current are shifted and the oldest one is dropped.
+func deployHistoryTags(current map[string]string, value string, keep int) []*ecs.Tag {
+	tags := []*ecs.Tag{{Key: aws.String(DeployHistoryTagKey), Value: aws.String(value)}}
+	for i := 1; i <= keep; i++ {
+		v := value
+		if i > 1 {
+			v = current[fmt.Sprintf(DeployHistoryTagKeyFmt, i-1)]
+		}
+		if v == "" {
+			break
+		}
+		tags = append(tags, &ecs.Tag{Key: aws.String(fmt.Sprintf(DeployHistoryTagKeyFmt, i)), Value: aws.String(v)})
+	}
+	return tags
+}
+
+// recordDeployHistory updates tags of the service to record the deploy.
+// It never fails the deploy, only warns.
+func (d *App) recordDeployHistory(ctx context.Context, serviceArn, tdArn string) {
+	if !d.config.RecordDeployHistory || serviceArn == "" {
+		return
+	}
+	current := make(map[string]string)
+	if d.config.DeployHistoryLimit > 0 {
+		out, err := d.ecs.ListTagsForResourceWithContext(ctx, &ecs.ListTagsForResourceInput{
+			ResourceArn: aws.String(serviceArn),
+		})
+		if err != nil {
+			d.Log("[WARNING] failed to list tags of the service:", err)
+			return
+		}
+		for _, tag := range out.Tags {
+			current[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+	}
+	value := deployHistoryValue(tdArn, d.deployID, time.Now())
+	tags := deployHistoryTags(current, value, d.config.DeployHistoryLimit)
+	_, err := d.ecs.TagResourceWithContext(ctx, &ecs.TagResourceInput{
+		ResourceArn: aws.String(serviceArn),
+		Tags:        tags,
+	})
+	if err != nil {
+		d.Log("[WARNING] failed to record the deploy history to tags of the service:", err)
+		return
+	}
+	keys := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		keys = append(keys, aws.StringValue(tag.Key))
+	}
+	d.DebugLog("deploy history is recorded:", strings.Join(keys, ", "), value)
+}
@@ -0,0 +1,110 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockHistoryECS struct {
+	ecsiface.ECSAPI
+	tags   []*ecs.Tag
+	tagged *ecs.TagResourceInput
+}
+
+func (m *mockHistoryECS) ListTagsForResourceWithContext(_ aws.Context, _ *ecs.ListTagsForResourceInput, _ ...request.Option) (*ecs.ListTagsForResourceOutput, error) {
+	return &ecs.ListTagsForResourceOutput{Tags: m.tags}, nil
+}
+
+func (m *mockHistoryECS) TagResourceWithContext(_ aws.Context, in *ecs.TagResourceInput, _ ...request.Option) (*ecs.TagResourceOutput, error) {
+	m.tagged = in
+	return &ecs.TagResourceOutput{}, nil
+}
+
+const testHistoryServiceArn = "arn:aws:ecs:us-east-1:123456789012:service/default/test"
+
+func TestDeployHistoryValue(t *testing.T) {
+	now := time.Date(2026, 10, 14, 1, 23, 45, 0, time.UTC)
+	v := deployHistoryValue("arn:aws:ecs:us-east-1:123456789012:task-definition/app:3", "abc", now)
+	if v != "revision=app:3 time=2026-10-14T01:23:45Z deploy_id=abc" {
+		t.Errorf("unexpected value %s", v)
+	}
+	v = deployHistoryValue("arn:aws:ecs:us-east-1:123456789012:task-definition/app:3", strings.Repeat("x", 300), now)
+	if utf8.RuneCountInString(v) != maxTagValueLength || !strings.HasSuffix(v, "...") {
+		t.Errorf("long value must be truncated: %s", v)
+	}
+}
+
+func TestRecordDeployHistory(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:              "us-east-1",
+		Service:             "test",
+		Cluster:             "default",
+		TaskDefinitionPath:  "tests/td.json",
+		RecordDeployHistory: true,
+		DeployHistoryLimit:  3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockHistoryECS{tags: []*ecs.Tag{
+		{Key: aws.String("env"), Value: aws.String("prod")},
+		{Key: aws.String(DeployHistoryTagKey), Value: aws.String("revision=app:2")},
+		{Key: aws.String("ecspresso:deploy-history-1"), Value: aws.String("revision=app:2")},
+		{Key: aws.String("ecspresso:deploy-history-2"), Value: aws.String("revision=app:1")},
+		{Key: aws.String("ecspresso:deploy-history-3"), Value: aws.String("revision=app:0")},
+	}}
+	app.ecs = m
+	app.deployID = "abc"
+	app.recordDeployHistory(context.Background(), testHistoryServiceArn, "arn:aws:ecs:us-east-1:123456789012:task-definition/app:3")
+
+	if m.tagged == nil {
+		t.Fatal("TagResource must be called")
+	}
+	if aws.StringValue(m.tagged.ResourceArn) != testHistoryServiceArn {
+		t.Errorf("unexpected resource %s", aws.StringValue(m.tagged.ResourceArn))
+	}
+	tags := make(map[string]string)
+	for _, tag := range m.tagged.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	if len(tags) != 4 {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	latest := tags[DeployHistoryTagKey]
+	if !strings.HasPrefix(latest, "revision=app:3 time=") || !strings.HasSuffix(latest, " deploy_id=abc") {
+		t.Errorf("unexpected last deploy %s", latest)
+	}
+	if tags["ecspresso:deploy-history-1"] != latest {
+		t.Errorf("the latest history must be the last deploy %s", tags["ecspresso:deploy-history-1"])
+	}
+	if tags["ecspresso:deploy-history-2"] != "revision=app:2" || tags["ecspresso:deploy-history-3"] != "revision=app:1" {
+		t.Errorf("histories must be shifted %v", tags)
+	}
+}
+
+func TestRecordDeployHistoryLastOnly(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:              "us-east-1",
+		Service:             "test",
+		Cluster:             "default",
+		TaskDefinitionPath:  "tests/td.json",
+		RecordDeployHistory: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockHistoryECS{}
+	app.ecs = m
+	app.recordDeployHistory(context.Background(), testHistoryServiceArn, "arn:aws:ecs:us-east-1:123456789012:task-definition/app:3")
+	if m.tagged == nil || len(m.tagged.Tags) != 1 || aws.StringValue(m.tagged.Tags[0].Key) != DeployHistoryTagKey {
+		t.Errorf("only the last deploy must be recorded %v", m.tagged)
+	}
+}
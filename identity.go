@@ -0,0 +1,32 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+)
+
+// verifyIdentity logs the account and the region which the credentials point at.
+// It fails when expected_account_id is set and differs from the account of the caller.
+// When expected_account_id is not set, a failure of GetCallerIdentity is only warned.
+func (d *App) verifyIdentity(ctx context.Context) error {
+	expected := d.config.ExpectedAccountID
+	out, err := d.sts.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		if expected != "" {
+			return errors.Wrap(err, "failed to get caller identity to verify expected_account_id")
+		}
+		d.Log("[WARNING] failed to get caller identity:", err)
+		return nil
+	}
+	account := aws.StringValue(out.Account)
+	d.accountID = account
+	d.Log("Account:", account, "Region:", d.region, "Caller:", aws.StringValue(out.Arn))
+	if expected != "" && account != expected {
+		return fmt.Errorf("account %s of the credentials does not match expected_account_id %s", account, expected)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package ecspresso
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestVerifyIdentity(t *testing.T) {
+	for _, expected := range []string{"", "123456789012"} {
+		app, err := NewApp(&Config{
+			Region:             "us-east-1",
+			Service:            "test",
+			Cluster:            "default",
+			Timeout:            time.Minute,
+			TaskDefinitionPath: "tests/td.json",
+			ExpectedAccountID:  expected,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &mockSTS{}
+		app.sts = m
+		if err := app.verifyIdentity(aws.BackgroundContext()); err != nil {
+			t.Errorf("unexpected error for expected account %q: %s", expected, err)
+		}
+		if app.accountID != "123456789012" {
+			t.Errorf("account ID must be cached: %s", app.accountID)
+		}
+	}
+}
+
+func TestDeployAccountMismatch(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+		ExpectedAccountID:  "999999999999",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.sts = &mockSTS{}
+	// ECS must not be called
+	app.ecs = nil
+	err = app.Deploy(DeployOption{
+		DryRun:             aws.Bool(false),
+		DesiredCount:       aws.Int64(KeepDesiredCount),
+		SkipTaskDefinition: aws.Bool(true),
+		ForceNewDeployment: aws.Bool(false),
+		NoWait:             aws.Bool(true),
+	})
+	if err == nil {
+		t.Fatal("deploy must be aborted by the account mismatch")
+	}
+	for _, s := range []string{"123456789012", "999999999999"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error must contain account %s: %s", s, err)
+		}
+	}
+}
+
+func TestConfigExpectedAccountID(t *testing.T) {
+	conf := &Config{TaskDefinitionPath: "tests/td.json", ExpectedAccountID: "1234"}
+	if err := conf.Validate(); err == nil {
+		t.Error("invalid expected_account_id must be rejected")
+	}
+}
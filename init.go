@@ -6,7 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 
-	"github.com/Songmu/prompter"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -23,7 +23,7 @@ func (d *App) Init(opt InitOption) error {
 		return errors.Wrap(err, "failed to describe service")
 	}
 	if len(out.Services) == 0 {
-		return errors.New("service is not found")
+		return ErrServiceNotFound
 	}
 
 	sv := out.Services[0]
@@ -38,7 +38,7 @@ func (d *App) Init(opt InitOption) error {
 		return errors.Wrap(err, "unable to marshal service definition to JSON")
 	} else {
 		d.Log("save service definition to", config.ServiceDefinitionPath)
-		if err := d.saveFile(config.ServiceDefinitionPath, b, CreateFileMode); err != nil {
+		if err := d.saveFile(config.ServiceDefinitionPath, b, CreateFileMode, aws.BoolValue(opt.Force)); err != nil {
 			return errors.Wrap(err, "failed to write file")
 		}
 	}
@@ -49,7 +49,7 @@ func (d *App) Init(opt InitOption) error {
 		return errors.Wrap(err, "unable to marshal task definition to JSON")
 	} else {
 		d.Log("save task definition to", config.TaskDefinitionPath)
-		if err := d.saveFile(config.TaskDefinitionPath, b, CreateFileMode); err != nil {
+		if err := d.saveFile(config.TaskDefinitionPath, b, CreateFileMode, aws.BoolValue(opt.Force)); err != nil {
 			return errors.Wrap(err, "failed to write file")
 		}
 	}
@@ -59,7 +59,7 @@ func (d *App) Init(opt InitOption) error {
 		return errors.Wrap(err, "unable to marshal config to YAML")
 	} else {
 		d.Log("save config to", *opt.ConfigFilePath)
-		if err := d.saveFile(*opt.ConfigFilePath, b, CreateFileMode); err != nil {
+		if err := d.saveFile(*opt.ConfigFilePath, b, CreateFileMode, aws.BoolValue(opt.Force)); err != nil {
 			return errors.Wrap(err, "failed to write file")
 		}
 	}
@@ -93,10 +93,14 @@ func treatmentTaskDefinition(td *ecs.TaskDefinition) *ecs.TaskDefinition {
 	return td
 }
 
-func (d *App) saveFile(path string, b []byte, mode os.FileMode) error {
-	if _, err := os.Stat(path); err == nil {
-		ok := prompter.YN(fmt.Sprintf("Overwrite existing file %s?", path), false)
-		if !ok {
+// saveFile writes b to path. An existing file is overwritten when force is set or confirmed interactively.
+// In non-interactive mode, it fails without force not to exit successfully without writing the file.
+func (d *App) saveFile(path string, b []byte, mode os.FileMode, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		if d.config.NonInteractive {
+			return fmt.Errorf("%s already exists. use --force to overwrite it in non-interactive mode", path)
+		}
+		if !d.confirm(fmt.Sprintf("Overwrite existing file %s?", path), "", true) {
 			d.Log("skip", path)
 			return nil
 		}
@@ -0,0 +1,34 @@
+package ecspresso
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveFileNonInteractive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ecs-task-def.json")
+	d := &App{config: &Config{NonInteractive: true, Quiet: true}}
+
+	if err := d.saveFile(path, []byte("new"), CreateFileMode, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.saveFile(path, []byte("overwritten"), CreateFileMode, false); err == nil {
+		t.Error("an existing file must not be skipped silently in non-interactive mode")
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "new" {
+		t.Errorf("the file must not be overwritten without force: %s", b)
+	}
+	if err := d.saveFile(path, []byte("overwritten"), CreateFileMode, true); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "overwritten" {
+		t.Errorf("the file must be overwritten with force: %s", b)
+	}
+}
@@ -0,0 +1,72 @@
+package ecspresso
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// validateLoadBalancers validates that each of load balancers refers a container and its port in the task definition,
+// and has a well-formed target group ARN. It returns an error including all of the problems found.
+func validateLoadBalancers(lbs []*ecs.LoadBalancer, td *ecs.TaskDefinition) error {
+	ports := make(map[string]map[int64]bool, len(td.ContainerDefinitions))
+	for _, c := range td.ContainerDefinitions {
+		ps := make(map[int64]bool, len(c.PortMappings))
+		for _, pm := range c.PortMappings {
+			ps[aws.Int64Value(pm.ContainerPort)] = true
+		}
+		ports[aws.StringValue(c.Name)] = ps
+	}
+	var problems []string
+	for i, lb := range lbs {
+		if tg := aws.StringValue(lb.TargetGroupArn); tg != "" {
+			if a, err := arn.Parse(tg); err != nil || a.Service != "elasticloadbalancing" || !strings.HasPrefix(a.Resource, "targetgroup/") {
+				problems = append(problems, fmt.Sprintf("loadBalancers[%d]: targetGroupArn %s is not a target group ARN", i, tg))
+			}
+		} else if aws.StringValue(lb.LoadBalancerName) == "" {
+			problems = append(problems, fmt.Sprintf("loadBalancers[%d]: targetGroupArn or loadBalancerName is required", i))
+		}
+		name := aws.StringValue(lb.ContainerName)
+		ps, ok := ports[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("loadBalancers[%d]: container %s is not found in the task definition", i, name))
+			continue
+		}
+		if port := aws.Int64Value(lb.ContainerPort); !ps[port] {
+			problems = append(problems, fmt.Sprintf("loadBalancers[%d]: container %s has no port mapping for %d", i, name, port))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid loadBalancers: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// diffLoadBalancers returns a unified diff of load balancers. It returns an empty string when there are no differences.
+func diffLoadBalancers(from, to []*ecs.LoadBalancer, fromName, toName string) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        formatLoadBalancers(from),
+		B:        formatLoadBalancers(to),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+}
+
+func formatLoadBalancers(lbs []*ecs.LoadBalancer) []string {
+	lines := make([]string, 0, len(lbs))
+	for _, lb := range lbs {
+		target := aws.StringValue(lb.TargetGroupArn)
+		if target == "" {
+			target = "loadBalancerName:" + aws.StringValue(lb.LoadBalancerName)
+		}
+		lines = append(lines, fmt.Sprintf("%s -> %s:%d\n", target, aws.StringValue(lb.ContainerName), aws.Int64Value(lb.ContainerPort)))
+	}
+	sort.Strings(lines)
+	return lines
+}
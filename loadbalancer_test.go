@@ -0,0 +1,157 @@
+package ecspresso
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func testLoadBalancerTaskDefinition() *ecs.TaskDefinition {
+	return &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name:         aws.String("web"),
+				PortMappings: []*ecs.PortMapping{{ContainerPort: aws.Int64(80)}},
+			},
+			{
+				Name:         aws.String("admin"),
+				PortMappings: []*ecs.PortMapping{{ContainerPort: aws.Int64(8080)}},
+			},
+		},
+	}
+}
+
+func TestValidateLoadBalancers(t *testing.T) {
+	td := testLoadBalancerTaskDefinition()
+	lbs := []*ecs.LoadBalancer{
+		{
+			ContainerName:  aws.String("web"),
+			ContainerPort:  aws.Int64(80),
+			TargetGroupArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/public/0123456789abcdef"),
+		},
+		{
+			ContainerName:  aws.String("admin"),
+			ContainerPort:  aws.Int64(8080),
+			TargetGroupArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/internal/0123456789abcdef"),
+		},
+	}
+	if err := validateLoadBalancers(lbs, td); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	lbs[0].ContainerPort = aws.Int64(443)
+	lbs[1].ContainerName = aws.String("api")
+	lbs[1].TargetGroupArn = aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/internal/0123456789abcdef")
+	err := validateLoadBalancers(lbs, td)
+	if err == nil {
+		t.Fatal("must be invalid")
+	}
+	for _, s := range []string{
+		"loadBalancers[0]: container web has no port mapping for 443",
+		"loadBalancers[1]: targetGroupArn arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/internal/0123456789abcdef is not a target group ARN",
+		"loadBalancers[1]: container api is not found in the task definition",
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error must contain %s: %s", s, err)
+		}
+	}
+}
+
+func TestDiffLoadBalancers(t *testing.T) {
+	public := &ecs.LoadBalancer{
+		ContainerName:  aws.String("web"),
+		ContainerPort:  aws.Int64(80),
+		TargetGroupArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/public/0123456789abcdef"),
+	}
+	internal := &ecs.LoadBalancer{
+		ContainerName:  aws.String("admin"),
+		ContainerPort:  aws.Int64(8080),
+		TargetGroupArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/internal/0123456789abcdef"),
+	}
+	ds, err := diffLoadBalancers([]*ecs.LoadBalancer{public, internal}, []*ecs.LoadBalancer{internal, public}, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds != "" {
+		t.Errorf("order of load balancers must be ignored: %s", ds)
+	}
+	ds, err = diffLoadBalancers([]*ecs.LoadBalancer{public}, []*ecs.LoadBalancer{public, internal}, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ds, "+arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/internal/0123456789abcdef -> admin:8080") {
+		t.Errorf("unexpected diff %s", ds)
+	}
+}
+
+func TestDeployLoadBalancerMismatch(t *testing.T) {
+	delayForServiceChanged = 0
+	defer func() { delayForServiceChanged = 3 * time.Second }()
+
+	for _, port := range []int64{11212, 9999} {
+		app, err := NewApp(&Config{
+			Region:             "us-east-1",
+			Service:            "test",
+			Cluster:            "default",
+			Timeout:            time.Minute,
+			TaskDefinitionPath: "tests/td.json",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &mockMultiClusterECS{
+			updated: make(map[string]string),
+			loadBalancers: []*ecs.LoadBalancer{
+				{
+					ContainerName:  aws.String("katsubushi"),
+					ContainerPort:  aws.Int64(port),
+					TargetGroupArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test/0123456789abcdef"),
+				},
+			},
+		}
+		app.ecs = m
+		app.autoScaling = &mockAutoScaling{}
+		app.sts = &mockSTS{}
+		err = app.Deploy(DeployOption{
+			DryRun:             aws.Bool(false),
+			DesiredCount:       aws.Int64(KeepDesiredCount),
+			SkipTaskDefinition: aws.Bool(false),
+			ForceNewDeployment: aws.Bool(false),
+			NoWait:             aws.Bool(true),
+			UpdateService:      aws.Bool(false),
+		})
+		if port == 11212 {
+			if err != nil {
+				t.Errorf("unexpected error %s", err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), "container katsubushi has no port mapping for 9999") {
+			t.Errorf("deploy must fail by the mismatched port: %v", err)
+		}
+		if m.registered != 0 {
+			t.Error("task definition must not be registered")
+		}
+	}
+}
+
+func TestValidateLocalLoadBalancers(t *testing.T) {
+	conf := NewDefaultConfig()
+	conf.Region = "us-east-1"
+	conf.Service = "test"
+	conf.TaskDefinitionPath = "tests/td.json"
+	conf.ServiceDefinitionPath = "tests/sv-lb.json"
+	if err := ValidateLocal(conf); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	// tests/sv.json refers a container not defined in tests/td.json
+	conf.ServiceDefinitionPath = "tests/sv.json"
+	if err := ValidateLocal(conf); err == nil || !strings.Contains(err.Error(), "loadBalancers[0]: container test is not found in the task definition") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
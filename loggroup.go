@@ -0,0 +1,112 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// awslogsGroup is a log group used by containers with the awslogs log driver.
+type awslogsGroup struct {
+	name        string
+	region      string
+	containers  []string
+	createGroup bool
+}
+
+// awslogsGroups returns log groups used by the containers of td, sorted by name.
+func awslogsGroups(td *ecs.TaskDefinition) []*awslogsGroup {
+	groups := map[string]*awslogsGroup{}
+	for _, c := range td.ContainerDefinitions {
+		lc := c.LogConfiguration
+		if lc == nil || aws.StringValue(lc.LogDriver) != "awslogs" {
+			continue
+		}
+		name := aws.StringValue(lc.Options["awslogs-group"])
+		if name == "" {
+			continue
+		}
+		region := aws.StringValue(lc.Options["awslogs-region"])
+		key := region + "/" + name
+		g, ok := groups[key]
+		if !ok {
+			g = &awslogsGroup{name: name, region: region}
+			groups[key] = g
+		}
+		g.containers = append(g.containers, aws.StringValue(c.Name))
+		if strings.EqualFold(aws.StringValue(lc.Options["awslogs-create-group"]), "true") {
+			g.createGroup = true
+		}
+	}
+	gs := make([]*awslogsGroup, 0, len(groups))
+	for _, g := range groups {
+		gs = append(gs, g)
+	}
+	sort.Slice(gs, func(i, j int) bool {
+		if gs[i].name != gs[j].name {
+			return gs[i].name < gs[j].name
+		}
+		return gs[i].region < gs[j].region
+	})
+	return gs
+}
+
+// verifyLogGroups verifies that the log groups of awslogs containers exist, unless awslogs-create-group is true.
+// Tasks fail to start when the log group does not exist.
+// When create is true, log groups which do not exist are created.
+func (d *App) verifyLogGroups(ctx context.Context, td *ecs.TaskDefinition, create bool) error {
+	var missing []string
+	for _, g := range awslogsGroups(td) {
+		if g.createGroup {
+			continue
+		}
+		if g.region != "" && g.region != d.region {
+			d.Log("[WARNING] skip verifying log group", g.name, "in region", g.region)
+			continue
+		}
+		exists, err := d.logGroupExists(ctx, g.name)
+		if err != nil {
+			d.Log("[WARNING] skip verifying log groups: failed to describe log groups:", err)
+			return nil
+		}
+		if exists {
+			continue
+		}
+		if create {
+			d.Log("Creating log group", g.name)
+			if _, err := d.cwl.CreateLogGroupWithContext(ctx, &cloudwatchlogs.CreateLogGroupInput{
+				LogGroupName: aws.String(g.name),
+			}); err != nil {
+				return errors.Wrapf(err, "failed to create log group %s", g.name)
+			}
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("%s (container %s)", g.name, strings.Join(g.containers, ", ")))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("log groups do not exist and awslogs-create-group is not true: %s. use --create-log-groups to create them", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (d *App) logGroupExists(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	err := d.cwl.DescribeLogGroupsPagesWithContext(ctx, &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: aws.String(name),
+	}, func(out *cloudwatchlogs.DescribeLogGroupsOutput, _ bool) bool {
+		for _, g := range out.LogGroups {
+			if aws.StringValue(g.LogGroupName) == name {
+				exists = true
+				return false
+			}
+		}
+		return true
+	})
+	return exists, err
+}
@@ -0,0 +1,87 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+type mockLogGroupsCWL struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+	groups  []string
+	created []string
+}
+
+func (m *mockLogGroupsCWL) DescribeLogGroupsPagesWithContext(_ aws.Context, in *cloudwatchlogs.DescribeLogGroupsInput, fn func(*cloudwatchlogs.DescribeLogGroupsOutput, bool) bool, _ ...request.Option) error {
+	out := &cloudwatchlogs.DescribeLogGroupsOutput{}
+	for _, g := range m.groups {
+		if strings.HasPrefix(g, aws.StringValue(in.LogGroupNamePrefix)) {
+			out.LogGroups = append(out.LogGroups, &cloudwatchlogs.LogGroup{LogGroupName: aws.String(g)})
+		}
+	}
+	fn(out, true)
+	return nil
+}
+
+func (m *mockLogGroupsCWL) CreateLogGroupWithContext(_ aws.Context, in *cloudwatchlogs.CreateLogGroupInput, _ ...request.Option) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	m.created = append(m.created, aws.StringValue(in.LogGroupName))
+	return &cloudwatchlogs.CreateLogGroupOutput{}, nil
+}
+
+func awslogsContainer(name, group string, opts ...string) *ecs.ContainerDefinition {
+	options := map[string]*string{"awslogs-group": aws.String(group)}
+	for i := 0; i+1 < len(opts); i += 2 {
+		options[opts[i]] = aws.String(opts[i+1])
+	}
+	return &ecs.ContainerDefinition{
+		Name: aws.String(name),
+		LogConfiguration: &ecs.LogConfiguration{
+			LogDriver: aws.String("awslogs"),
+			Options:   options,
+		},
+	}
+}
+
+func TestVerifyLogGroups(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			awslogsContainer("app", "/ecs/app"),
+			awslogsContainer("worker", "/ecs/worker"),
+			awslogsContainer("proxy", "/ecs/proxy", "awslogs-create-group", "true"),
+			awslogsContainer("other", "/ecs/other", "awslogs-region", "us-east-1"),
+			{Name: aws.String("nolog")},
+		},
+	}
+
+	cwl := &mockLogGroupsCWL{groups: []string{"/ecs/app", "/ecs/app-old"}}
+	d := &App{config: &Config{}, region: "ap-northeast-1", cwl: cwl}
+	err := d.verifyLogGroups(context.Background(), td, false)
+	if err == nil {
+		t.Fatal("expected an error for the missing log group")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "/ecs/worker (container worker)") || strings.Contains(msg, "/ecs/app") || strings.Contains(msg, "/ecs/proxy") || strings.Contains(msg, "/ecs/other") {
+		t.Errorf("unexpected error %s", msg)
+	}
+	if len(cwl.created) > 0 {
+		t.Errorf("log groups must not be created: %v", cwl.created)
+	}
+
+	if err := d.verifyLogGroups(context.Background(), td, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(cwl.created) != 1 || cwl.created[0] != "/ecs/worker" {
+		t.Errorf("unexpected created log groups %v", cwl.created)
+	}
+
+	cwl = &mockLogGroupsCWL{groups: []string{"/ecs/app", "/ecs/worker"}}
+	d.cwl = cwl
+	if err := d.verifyLogGroups(context.Background(), td, false); err != nil {
+		t.Error(err)
+	}
+}
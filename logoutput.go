@@ -0,0 +1,66 @@
+package ecspresso
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// newLogOutput returns a writer for logs by log_file and log_syslog in config.
+// It returns nil when neither is set. Then logs are written to the outputs of the command,
+// STDOUT (or STDERR when STDOUT is reserved, see (*App).stdout) by default.
+func newLogOutput(conf *Config) (io.Writer, error) {
+	var ws []io.Writer
+	if conf.LogFile != "" {
+		// append only, so that the file can be rotated by logrotate (copytruncate) or others
+		f, err := os.OpenFile(conf.LogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open log_file")
+		}
+		ws = append(ws, f)
+	}
+	if conf.LogSyslog {
+		w, err := newSyslogWriter()
+		if err != nil {
+			return nil, err
+		}
+		ws = append(ws, w)
+	}
+	switch len(ws) {
+	case 0:
+		return nil, nil
+	case 1:
+		return ws[0], nil
+	default:
+		return io.MultiWriter(ws...), nil
+	}
+}
+
+// setLogOutput sets the output of logs for the command.
+func (d *App) setLogOutput() {
+	if d.logOutput == nil {
+		log.SetOutput(d.stdout())
+		return
+	}
+	log.SetOutput(d.logOutput)
+}
+
+// stdoutFor returns a writer for outputs of commands. When print_revision is set in config,
+// STDOUT is reserved for the revision number and other outputs are written to STDERR.
+// Likewise, STDOUT is reserved for JSON documents with output_format: json.
+func stdoutFor(conf *Config) io.Writer {
+	if conf != nil && (conf.PrintRevision || conf.OutputFormat == OutputFormatJSON) {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// stdout returns a writer for outputs of the command, which is STDERR when STDOUT is reserved for the result.
+func (d *App) stdout() io.Writer {
+	if d.stdoutReserved {
+		return os.Stderr
+	}
+	return stdoutFor(d.config)
+}
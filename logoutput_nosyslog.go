@@ -0,0 +1,15 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package ecspresso
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// newSyslogWriter returns an error because syslog is not available on this platform.
+func newSyslogWriter() (io.Writer, error) {
+	return nil, errors.New("log_syslog is not supported on this platform")
+}
@@ -0,0 +1,20 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package ecspresso
+
+import (
+	"io"
+	"log/syslog"
+
+	"github.com/pkg/errors"
+)
+
+// newSyslogWriter returns a writer for the local syslog.
+func newSyslogWriter() (io.Writer, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, "ecspresso")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to syslog")
+	}
+	return w, nil
+}
@@ -0,0 +1,146 @@
+package ecspresso
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestLogFile(t *testing.T) {
+	defer log.SetOutput(os.Stderr)
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ecspresso.log")
+	if err := ioutil.WriteFile(path, []byte("previous log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := NewApp(&Config{
+		Region:             "ap-northeast-1",
+		Timeout:            time.Minute,
+		Service:            "test",
+		Cluster:            "default",
+		TaskDefinitionPath: "tests/td.json",
+		LogFile:            path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, cancel := app.Start()
+	defer cancel()
+	app.Log("progress message")
+	app.ResultLog("result message")
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 || lines[0] != "previous log" {
+		t.Fatalf("log must be appended: %s", string(b))
+	}
+	if !strings.HasSuffix(lines[1], "test/default progress message") || !strings.HasSuffix(lines[2], "test/default result message") {
+		t.Errorf("unexpected log %s", string(b))
+	}
+
+	if _, err := newLogOutput(&Config{LogFile: filepath.Join(dir, "not-found", "ecspresso.log")}); err == nil {
+		t.Error("must fail to open log file in a directory not found")
+	}
+}
+
+func TestPrintRevision(t *testing.T) {
+	defer log.SetOutput(os.Stderr)
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+		PrintRevision:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.ecs = &mockCreateECS{}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	err = app.Register(RegisterOption{DryRun: aws.Bool(false), Output: aws.Bool(true)})
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// logs and the task definition are written to STDERR
+	if string(b) != "1\n" {
+		t.Errorf("only the revision must be printed to STDOUT: %q", string(b))
+	}
+}
+
+type mockJSONOutputECS struct {
+	mockCreateECS
+}
+
+func (m *mockJSONOutputECS) DescribeServicesWithContext(_ aws.Context, _ *ecs.DescribeServicesInput, _ ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	return nil, errors.New("service not found")
+}
+
+func TestJSONOutputFormatStdout(t *testing.T) {
+	defer log.SetOutput(os.Stderr)
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+		OutputFormat:       OutputFormatJSON,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.ecs = &mockJSONOutputECS{}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	err = app.Register(RegisterOption{DryRun: aws.Bool(false), Output: aws.Bool(true)})
+	if err == nil {
+		app.printDeploySummary(time.Now(), "", "arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:1", nil)
+	}
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// logs, outputs and summaries are written to STDERR, to keep STDOUT for JSON documents
+	if len(b) != 0 {
+		t.Errorf("nothing must be printed to STDOUT: %q", string(b))
+	}
+}
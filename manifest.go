@@ -0,0 +1,107 @@
+package ecspresso
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// ImageManifest is a manifest of images written by build systems.
+//
+//	{
+//	  "images": {"app": "v1.2.3", "worker": "ghcr.io/example/worker:v1.2.3"},
+//	  "containers": {"app-sidecar": "app"}
+//	}
+//
+// Images are matched to containers by names, or by the explicit mapping of containers (container name to a name in images).
+// An image without repository (e.g. "v1.2.3") is a tag, which replaces the tag of the image of the container.
+type ImageManifest struct {
+	Images     map[string]string `json:"images"`
+	Containers map[string]string `json:"containers,omitempty"`
+}
+
+// loadImageManifest loads image_manifest in config. Environment variables in the manifest are expanded.
+func (d *App) loadImageManifest() (*ImageManifest, error) {
+	var m ImageManifest
+	if err := d.loader.LoadWithEnvJSON(&m, d.config.ImageManifestPath); err != nil {
+		return nil, errors.Wrapf(err, "failed to load image manifest %s", d.config.ImageManifestPath)
+	}
+	return &m, nil
+}
+
+// applyImageManifest sets images of the containers in the task definition by image_manifest in config.
+func (d *App) applyImageManifest(td *ecs.TaskDefinition) error {
+	if d.config.ImageManifestPath == "" {
+		return nil
+	}
+	m, err := d.loadImageManifest()
+	if err != nil {
+		return err
+	}
+	unmatched, err := m.apply(td)
+	if err != nil {
+		return err
+	}
+	if len(unmatched) == 0 {
+		return nil
+	}
+	if d.config.AllowUnmatchedImages {
+		d.Log("[WARNING] images of containers", strings.Join(unmatched, ", "), "are not found in the image manifest. kept as is")
+		return nil
+	}
+	return fmt.Errorf("images of containers %s are not found in the image manifest %s. use --allow-unmatched to keep them as is", strings.Join(unmatched, ", "), d.config.ImageManifestPath)
+}
+
+// apply sets images of the containers in td, and returns names of containers which are not found in the manifest.
+func (m *ImageManifest) apply(td *ecs.TaskDefinition) ([]string, error) {
+	containers := make(map[string]bool, len(td.ContainerDefinitions))
+	for _, c := range td.ContainerDefinitions {
+		containers[aws.StringValue(c.Name)] = true
+	}
+	var invalid []string
+	for container, name := range m.Containers {
+		if !containers[container] {
+			invalid = append(invalid, fmt.Sprintf("container %s is not found in the task definition", container))
+		} else if _, ok := m.Images[name]; !ok {
+			invalid = append(invalid, fmt.Sprintf("image %s for container %s is not found in images", name, container))
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return nil, fmt.Errorf("invalid image manifest: %s", strings.Join(invalid, ", "))
+	}
+
+	var unmatched []string
+	for _, c := range td.ContainerDefinitions {
+		name := aws.StringValue(c.Name)
+		if n, ok := m.Containers[name]; ok {
+			name = n
+		}
+		image, ok := m.Images[name]
+		if !ok {
+			unmatched = append(unmatched, aws.StringValue(c.Name))
+			continue
+		}
+		c.Image = aws.String(resolveManifestImage(aws.StringValue(c.Image), image))
+	}
+	return unmatched, nil
+}
+
+// resolveManifestImage returns the image to be set. When image is a tag, it replaces the tag (or digest) of current.
+func resolveManifestImage(current, image string) string {
+	if strings.ContainsAny(image, "/:@") {
+		return image
+	}
+	repo := current
+	if i := strings.LastIndex(repo, "@"); i != -1 {
+		repo = repo[:i]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	return repo + ":" + image
+}
@@ -0,0 +1,111 @@
+package ecspresso
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestImageManifest(t *testing.T) {
+	os.Setenv("IMAGE_TAG", "v1.2.3")
+	defer os.Unsetenv("IMAGE_TAG")
+	app, err := NewApp(&Config{
+		Region:             "ap-northeast-1",
+		Timeout:            time.Minute,
+		Service:            "test",
+		Cluster:            "default",
+		TaskDefinitionPath: "tests/td.json",
+		ImageManifestPath:  "tests/images.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	td, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := aws.StringValue(td.ContainerDefinitions[0].Image); image != "katsubushi/katsubushi:v1.2.3" {
+		t.Errorf("unexpected image %s", image)
+	}
+}
+
+func TestImageManifestApply(t *testing.T) {
+	newTd := func() *ecs.TaskDefinition {
+		return &ecs.TaskDefinition{
+			ContainerDefinitions: []*ecs.ContainerDefinition{
+				{Name: aws.String("app"), Image: aws.String("123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:v1")},
+				{Name: aws.String("nginx"), Image: aws.String("localhost:5000/nginx@sha256:0123")},
+				{Name: aws.String("datadog"), Image: aws.String("public.ecr.aws/datadog/agent:7")},
+			},
+		}
+	}
+	m := &ImageManifest{
+		Images:     map[string]string{"app": "v2", "web": "v3"},
+		Containers: map[string]string{"nginx": "web"},
+	}
+	td := newTd()
+	unmatched, err := m.apply(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unmatched) != 1 || unmatched[0] != "datadog" {
+		t.Errorf("unexpected unmatched %v", unmatched)
+	}
+	for i, image := range []string{
+		"123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:v2",
+		"localhost:5000/nginx:v3",
+		"public.ecr.aws/datadog/agent:7",
+	} {
+		if got := aws.StringValue(td.ContainerDefinitions[i].Image); got != image {
+			t.Errorf("unexpected image %s (expected %s)", got, image)
+		}
+	}
+
+	m.Images["datadog"] = "public.ecr.aws/datadog/agent:7.50.0"
+	td = newTd()
+	if unmatched, err := m.apply(td); err != nil || len(unmatched) != 0 {
+		t.Errorf("unexpected unmatched %v %v", unmatched, err)
+	}
+	if got := aws.StringValue(td.ContainerDefinitions[2].Image); got != "public.ecr.aws/datadog/agent:7.50.0" {
+		t.Errorf("unexpected image %s", got)
+	}
+
+	m.Containers["unknown"] = "app"
+	m.Containers["nginx"] = "proxy"
+	_, err = m.apply(newTd())
+	if err == nil {
+		t.Fatal("expected an error for the invalid manifest")
+	}
+	for _, s := range []string{"container unknown is not found in the task definition", "image proxy for container nginx is not found in images"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q does not contain %q", err, s)
+		}
+	}
+}
+
+func TestApplyImageManifestUnmatched(t *testing.T) {
+	os.Setenv("IMAGE_TAG", "v1.2.3")
+	defer os.Unsetenv("IMAGE_TAG")
+	conf := &Config{ImageManifestPath: "tests/images.json"}
+	d := &App{config: conf, loader: newLoader(conf)}
+	td := &ecs.TaskDefinition{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("katsubushi"), Image: aws.String("katsubushi/katsubushi:latest")},
+			{Name: aws.String("sidecar"), Image: aws.String("sidecar:v1")},
+		},
+	}
+	if err := d.applyImageManifest(td); err == nil || !strings.Contains(err.Error(), "images of containers sidecar are not found in the image manifest") {
+		t.Errorf("unexpected error %v", err)
+	}
+	conf.AllowUnmatchedImages = true
+	if err := d.applyImageManifest(td); err != nil {
+		t.Error(err)
+	}
+	if image := aws.StringValue(td.ContainerDefinitions[1].Image); image != "sidecar:v1" {
+		t.Errorf("unexpected image %s", image)
+	}
+}
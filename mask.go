@@ -0,0 +1,21 @@
+package ecspresso
+
+import "regexp"
+
+const maskedAccountID = "****"
+
+var accountIDPattern = regexp.MustCompile(`\b\d{12}\b`)
+
+// maskAccountIDs replaces 12-digit AWS account IDs (e.g. in ARNs) in s.
+func maskAccountIDs(s string) string {
+	return accountIDPattern.ReplaceAllString(s, maskedAccountID)
+}
+
+// Mask returns s with AWS account IDs masked when mask_account_ids is set in config.
+// It is applied only to emitted texts, so internal values are kept unmasked.
+func (d *App) Mask(s string) string {
+	if d.config == nil || !d.config.MaskAccountIDs {
+		return s
+	}
+	return maskAccountIDs(s)
+}
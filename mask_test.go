@@ -0,0 +1,50 @@
+package ecspresso
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMaskAccountIDs(t *testing.T) {
+	for _, c := range [][2]string{
+		{"arn:aws:iam::123456789012:role/ecsTaskRole", "arn:aws:iam::****:role/ecsTaskRole"},
+		{"123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1", "****.dkr.ecr.us-east-1.amazonaws.com/app:v1"},
+		{"account 123456789012 and 210987654321", "account **** and ****"},
+		{"not an account 1234567890123 nor 12345678901", "not an account 1234567890123 nor 12345678901"},
+	} {
+		if got := maskAccountIDs(c[0]); got != c[1] {
+			t.Errorf("unexpected masked %s (expected %s)", got, c[1])
+		}
+	}
+}
+
+func TestMaskLog(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	role := "arn:aws:iam::123456789012:role/ecsTaskRole"
+	d := &App{Service: "app", Cluster: "default", config: &Config{MaskAccountIDs: true}}
+	d.Log("task role:", role)
+	if out := buf.String(); strings.Contains(out, "123456789012") || !strings.Contains(out, "arn:aws:iam::****:role/ecsTaskRole") {
+		t.Errorf("account ID is not masked: %s", out)
+	}
+	b, err := d.ErrorJSON(errors.New("failed to assume " + role))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "123456789012") {
+		t.Errorf("account ID is not masked: %s", b)
+	}
+
+	buf.Reset()
+	d.config.MaskAccountIDs = false
+	d.Log("task role:", role)
+	if out := buf.String(); !strings.Contains(out, role) {
+		t.Errorf("account ID must not be masked: %s", out)
+	}
+}
@@ -0,0 +1,62 @@
+package ecspresso
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+const (
+	DefaultMetricsNamespace = "ecspresso"
+	putMetricsTimeout       = 30 * time.Second
+)
+
+func deployMetricData(service, cluster string, duration time.Duration, success bool, now time.Time) []*cloudwatch.MetricDatum {
+	dimensions := []*cloudwatch.Dimension{
+		{Name: aws.String("Service"), Value: aws.String(service)},
+		{Name: aws.String("Cluster"), Value: aws.String(cluster)},
+	}
+	var successValue float64
+	if success {
+		successValue = 1
+	}
+	return []*cloudwatch.MetricDatum{
+		{
+			MetricName: aws.String("DeployDurationSeconds"),
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(now),
+			Unit:       aws.String(cloudwatch.StandardUnitSeconds),
+			Value:      aws.Float64(duration.Seconds()),
+		},
+		{
+			MetricName: aws.String("DeploySuccess"),
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(now),
+			Unit:       aws.String(cloudwatch.StandardUnitCount),
+			Value:      aws.Float64(successValue),
+		},
+	}
+}
+
+// putDeployMetrics puts the deploy metrics to CloudWatch.
+// This is best-effort, so any errors are logged but not returned.
+func (d *App) putDeployMetrics(startedAt time.Time, success bool) {
+	now := time.Now()
+	namespace := d.config.MetricsNamespace
+	if namespace == "" {
+		namespace = DefaultMetricsNamespace
+	}
+	in := &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(namespace),
+		MetricData: deployMetricData(d.Service, d.Cluster, now.Sub(startedAt), success, now),
+	}
+	d.DebugLog("putting deploy metrics", in.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), putMetricsTimeout)
+	defer cancel()
+	if _, err := d.cw.PutMetricDataWithContext(ctx, in); err != nil {
+		d.Log("[WARNING] failed to put deploy metrics:", err)
+	}
+}
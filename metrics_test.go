@@ -0,0 +1,36 @@
+package ecspresso
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeployMetricData(t *testing.T) {
+	now := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	for _, success := range []bool{true, false} {
+		data := deployMetricData("test", "default", 90*time.Second, success, now)
+		if len(data) != 2 {
+			t.Fatalf("unexpected metric data %v", data)
+		}
+		if *data[0].MetricName != "DeployDurationSeconds" || *data[0].Value != 90 {
+			t.Errorf("unexpected duration metric %s", data[0].String())
+		}
+		expected := 0.0
+		if success {
+			expected = 1
+		}
+		if *data[1].MetricName != "DeploySuccess" || *data[1].Value != expected {
+			t.Errorf("unexpected success metric %s", data[1].String())
+		}
+		for _, datum := range data {
+			if len(datum.Dimensions) != 2 ||
+				*datum.Dimensions[0].Value != "test" ||
+				*datum.Dimensions[1].Value != "default" {
+				t.Errorf("unexpected dimensions %v", datum.Dimensions)
+			}
+			if !datum.Timestamp.Equal(now) {
+				t.Errorf("unexpected timestamp %s", datum.Timestamp)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package ecspresso
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ServiceResultSucceeded = "succeeded"
+	ServiceResultFailed    = "failed"
+	ServiceResultSkipped   = "skipped"
+)
+
+// ServiceResult represents a result of the deploy of the service to a cluster.
+type ServiceResult struct {
+	Service        string `json:"service"`
+	Cluster        string `json:"cluster"`
+	Status         string `json:"status"`
+	TaskDefinition string `json:"task_definition,omitempty"`
+	Error          string `json:"error,omitempty"`
+
+	err error
+}
+
+// DeployClustersError is returned when the deploy to any of the clusters failed.
+// It carries the results of all of the clusters and the individual failures.
+type DeployClustersError struct {
+	Results []ServiceResult
+}
+
+func (e *DeployClustersError) Error() string {
+	var failed []string
+	for _, r := range e.Results {
+		if r.Status == ServiceResultFailed {
+			failed = append(failed, fmt.Sprintf("%s: %s", r.Cluster, r.Error))
+		}
+	}
+	return fmt.Sprintf("failed to deploy to %d/%d clusters: %s", len(failed), len(e.Results), strings.Join(failed, "; "))
+}
+
+// Errors returns the individual failures of the clusters.
+func (e *DeployClustersError) Errors() []error {
+	var errs []error
+	for _, r := range e.Results {
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+	}
+	return errs
+}
+
+// forCluster returns a copy of the App for the cluster.
+func (d *App) forCluster(cluster string) *App {
+	conf := *d.config
+	conf.Cluster = cluster
+	conf.Clusters = nil
+	app := *d
+	app.Cluster = cluster
+	app.config = &conf
+	return &app
+}
+
+// deployToClusters deploys the service to all of the clusters.
+// A task definition is registered once, and shared by all of the clusters.
+// The deploy continues to the rest of the clusters on failures by default. When clusters_fail_fast is set,
+// it stops at the first failure and the rest of the clusters are skipped. A summary of the results is logged at the end.
+func (d *App) deployToClusters(opt DeployOption) error {
+	d.setDeployID()
+	results := make([]ServiceResult, 0, len(d.config.Clusters))
+	failed := false
+	for i, cluster := range d.config.Clusters {
+		r := ServiceResult{Service: d.Service, Cluster: cluster}
+		if failed && d.config.ClustersFailFast {
+			r.Status = ServiceResultSkipped
+			results = append(results, r)
+			continue
+		}
+		app := d.forCluster(cluster)
+		app.Log(fmt.Sprintf("Deploying to cluster %s (%d/%d)", cluster, i+1, len(d.config.Clusters)))
+		err := app.Deploy(opt)
+		d.registeredArn = app.registeredArn
+		if d.registeredArn != "" {
+			r.TaskDefinition = arnToName(d.registeredArn)
+		}
+		if err != nil {
+			app.Log("[WARNING] failed to deploy:", err)
+			r.Status, r.Error, r.err = ServiceResultFailed, err.Error(), err
+			failed = true
+		} else {
+			r.Status = ServiceResultSucceeded
+		}
+		results = append(results, r)
+	}
+	d.logServiceResults(results)
+	if failed {
+		return &DeployClustersError{Results: results}
+	}
+	return nil
+}
+
+func (d *App) logServiceResults(results []ServiceResult) {
+	d.ResultLog("Summary of deploy to clusters:")
+	for _, r := range results {
+		s := fmt.Sprintf("  %s: %s", r.Cluster, r.Status)
+		if r.TaskDefinition != "" && r.Status == ServiceResultSucceeded {
+			s += " (" + r.TaskDefinition + ")"
+		}
+		if r.Error != "" {
+			s += ": " + r.Error
+		}
+		d.ResultLog(s)
+	}
+}
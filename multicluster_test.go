@@ -0,0 +1,146 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockMultiClusterECS struct {
+	ecsiface.ECSAPI
+	registered    int
+	updated       map[string]string
+	desiredCounts map[string]*int64
+	fail          map[string]bool
+	loadBalancers []*ecs.LoadBalancer
+	controller    *ecs.DeploymentController
+}
+
+func (m *mockMultiClusterECS) DescribeServicesWithContext(_ aws.Context, in *ecs.DescribeServicesInput, _ ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	return &ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			{
+				ServiceName:          in.Services[0],
+				ClusterArn:           aws.String("arn:aws:ecs:us-east-1:123456789012:cluster/" + *in.Cluster),
+				TaskDefinition:       aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:1"),
+				DesiredCount:         aws.Int64(4),
+				RunningCount:         aws.Int64(4),
+				LoadBalancers:        m.loadBalancers,
+				DeploymentController: m.controller,
+			},
+		},
+	}, nil
+}
+
+func (m *mockMultiClusterECS) RegisterTaskDefinitionWithContext(_ aws.Context, in *ecs.RegisterTaskDefinitionInput, _ ...request.Option) (*ecs.RegisterTaskDefinitionOutput, error) {
+	m.registered++
+	return &ecs.RegisterTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			Family:            in.Family,
+			Revision:          aws.Int64(2),
+			TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:2"),
+		},
+	}, nil
+}
+
+func (m *mockMultiClusterECS) UpdateServiceWithContext(_ aws.Context, in *ecs.UpdateServiceInput, _ ...request.Option) (*ecs.UpdateServiceOutput, error) {
+	if m.fail[*in.Cluster] {
+		return nil, errors.New("service not found")
+	}
+	m.updated[*in.Cluster] = *in.TaskDefinition
+	if m.desiredCounts != nil {
+		m.desiredCounts[*in.Cluster] = in.DesiredCount
+	}
+	return &ecs.UpdateServiceOutput{}, nil
+}
+
+func TestDeployToClusters(t *testing.T) {
+	delayForServiceChanged = 0
+	defer func() { delayForServiceChanged = 3 * time.Second }()
+
+	for _, failFast := range []bool{false, true} {
+		app, err := NewApp(&Config{
+			Region:             "us-east-1",
+			Service:            "test",
+			Timeout:            time.Minute,
+			TaskDefinitionPath: "tests/td.json",
+			Clusters:           []string{"tokyo", "osaka", "nagoya"},
+			ClustersFailFast:   failFast,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &mockMultiClusterECS{
+			updated: make(map[string]string),
+			fail:    map[string]bool{"osaka": true},
+		}
+		app.ecs = m
+		app.autoScaling = &mockAutoScaling{}
+		app.sts = &mockSTS{}
+		err = app.Deploy(DeployOption{
+			DryRun:             aws.Bool(false),
+			DesiredCount:       aws.Int64(KeepDesiredCount),
+			SkipTaskDefinition: aws.Bool(false),
+			ForceNewDeployment: aws.Bool(false),
+			NoWait:             aws.Bool(true),
+		})
+		if err == nil {
+			t.Fatal("failed cluster must be an error")
+		}
+		if m.registered != 1 {
+			t.Errorf("task definition must be registered once: %d", m.registered)
+		}
+		expected := map[string]string{"tokyo": "arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:2"}
+		if !failFast {
+			expected["nagoya"] = expected["tokyo"]
+		}
+		if len(m.updated) != len(expected) {
+			t.Errorf("unexpected updated clusters %v (fail fast:%t)", m.updated, failFast)
+		}
+		for cluster, arn := range expected {
+			if m.updated[cluster] != arn {
+				t.Errorf("cluster %s must be updated to %s: %v", cluster, arn, m.updated)
+			}
+		}
+
+		e, ok := err.(*DeployClustersError)
+		if !ok {
+			t.Fatalf("unexpected error type %T", err)
+		}
+		var statuses []string
+		for _, r := range e.Results {
+			statuses = append(statuses, r.Cluster+":"+r.Status)
+		}
+		expectedStatuses := "tokyo:succeeded,osaka:failed,nagoya:succeeded"
+		if failFast {
+			expectedStatuses = "tokyo:succeeded,osaka:failed,nagoya:skipped"
+		}
+		if got := strings.Join(statuses, ","); got != expectedStatuses {
+			t.Errorf("unexpected results %s", got)
+		}
+		if errs := e.Errors(); len(errs) != 1 || !strings.Contains(errs[0].Error(), "service not found") {
+			t.Errorf("unexpected individual errors %v", errs)
+		}
+		if !strings.Contains(err.Error(), "failed to deploy to 1/3 clusters: osaka: ") {
+			t.Errorf("unexpected error %s", err)
+		}
+		b, jerr := app.ErrorJSON(err)
+		if jerr != nil {
+			t.Fatal(jerr)
+		}
+		var out deployClustersErrorOutput
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.ErrorType != "DeployClustersFailed" || len(out.Results) != 3 || out.Results[1].Error == "" {
+			t.Errorf("unexpected error output %s", string(b))
+		}
+	}
+}
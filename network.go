@@ -0,0 +1,44 @@
+package ecspresso
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// DefaultAssignPublicIp is a default value of assignPublicIp of awsvpcConfiguration.
+const DefaultAssignPublicIp = ecs.AssignPublicIpDisabled
+
+// normalizeNetworkConfiguration fills the default assignPublicIp and removes empty subnets and security groups
+// (e.g. rendered by templates with empty default values), which are rejected by the API.
+func normalizeNetworkConfiguration(nc *ecs.NetworkConfiguration) error {
+	if nc == nil || nc.AwsvpcConfiguration == nil {
+		return nil
+	}
+	c := nc.AwsvpcConfiguration
+	switch aws.StringValue(c.AssignPublicIp) {
+	case "":
+		c.AssignPublicIp = aws.String(DefaultAssignPublicIp)
+	case ecs.AssignPublicIpEnabled, ecs.AssignPublicIpDisabled:
+	default:
+		return fmt.Errorf("assignPublicIp must be %s or %s: %s", ecs.AssignPublicIpEnabled, ecs.AssignPublicIpDisabled, *c.AssignPublicIp)
+	}
+	c.Subnets = compactStrings(c.Subnets)
+	c.SecurityGroups = compactStrings(c.SecurityGroups)
+	if len(c.Subnets) == 0 {
+		return fmt.Errorf("awsvpcConfiguration requires at least one subnet")
+	}
+	return nil
+}
+
+// compactStrings returns ss without empty strings. It returns nil when no values remain.
+func compactStrings(ss []*string) []*string {
+	var r []*string
+	for _, s := range ss {
+		if aws.StringValue(s) != "" {
+			r = append(r, s)
+		}
+	}
+	return r
+}
@@ -0,0 +1,66 @@
+package ecspresso
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestNormalizeNetworkConfiguration(t *testing.T) {
+	cases := []struct {
+		assignPublicIp *string
+		want           string
+	}{
+		{
+			assignPublicIp: aws.String("ENABLED"),
+			want:           `{"awsvpcConfiguration":{"assignPublicIp":"ENABLED","subnets":["subnet-1"]}}`,
+		},
+		{
+			assignPublicIp: aws.String("DISABLED"),
+			want:           `{"awsvpcConfiguration":{"assignPublicIp":"DISABLED","subnets":["subnet-1"]}}`,
+		},
+		{
+			assignPublicIp: nil,
+			want:           `{"awsvpcConfiguration":{"assignPublicIp":"DISABLED","subnets":["subnet-1"]}}`,
+		},
+		{
+			assignPublicIp: aws.String(""),
+			want:           `{"awsvpcConfiguration":{"assignPublicIp":"DISABLED","subnets":["subnet-1"]}}`,
+		},
+	}
+	for _, c := range cases {
+		nc := &ecs.NetworkConfiguration{
+			AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+				AssignPublicIp: c.assignPublicIp,
+				Subnets:        aws.StringSlice([]string{"subnet-1", ""}),
+				SecurityGroups: aws.StringSlice([]string{""}),
+			},
+		}
+		if err := normalizeNetworkConfiguration(nc); err != nil {
+			t.Fatal(err)
+		}
+		b, err := jsonutil.BuildJSON(nc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != c.want {
+			t.Errorf("unexpected JSON %s, want %s", string(b), c.want)
+		}
+	}
+}
+
+func TestNormalizeNetworkConfigurationInvalid(t *testing.T) {
+	for _, c := range []*ecs.AwsVpcConfiguration{
+		{AssignPublicIp: aws.String("enabled"), Subnets: aws.StringSlice([]string{"subnet-1"})},
+		{Subnets: aws.StringSlice([]string{""})},
+	} {
+		if err := normalizeNetworkConfiguration(&ecs.NetworkConfiguration{AwsvpcConfiguration: c}); err == nil {
+			t.Errorf("%s must be invalid", c)
+		}
+	}
+	if err := normalizeNetworkConfiguration(nil); err != nil {
+		t.Errorf("nil must be valid: %s", err)
+	}
+}
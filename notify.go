@@ -0,0 +1,53 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outcomes of deploy notifications.
+const (
+	DeployOutcomeStarted   = "STARTED"
+	DeployOutcomeSucceeded = "SUCCEEDED"
+	DeployOutcomeFailed    = "FAILED"
+)
+
+// DeployNotification is a structured message of a deploy event for notifiers.
+type DeployNotification struct {
+	Service   string    `json:"service"`
+	Cluster   string    `json:"cluster"`
+	Revision  string    `json:"revision,omitempty"`
+	Outcome   string    `json:"outcome"`
+	DeployID  string    `json:"deploy_id,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// deployNotification builds a notification of the deploy event.
+// The outcome is FAILED when err is not nil.
+func (d *App) deployNotification(outcome, tdArn string, err error, now time.Time) *DeployNotification {
+	n := &DeployNotification{
+		Service:   d.Service,
+		Cluster:   d.Cluster,
+		Outcome:   outcome,
+		DeployID:  d.deployID,
+		Timestamp: now,
+	}
+	if tdArn != "" {
+		n.Revision = arnToName(tdArn)
+	}
+	if err != nil {
+		n.Outcome = DeployOutcomeFailed
+		n.Error = err.Error()
+	}
+	return n
+}
+
+// JSON returns the notification in JSON.
+func (n *DeployNotification) JSON() (string, error) {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
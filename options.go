@@ -1,5 +1,7 @@
 package ecspresso
 
+import "time"
+
 const dryRunStr = "DRY RUN"
 
 type DryRunnable interface {
@@ -7,9 +9,10 @@ type DryRunnable interface {
 }
 
 type CreateOption struct {
-	DryRun       *bool
-	DesiredCount *int64
-	NoWait       *bool
+	DryRun               *bool
+	DesiredCount         *int64
+	NoWait               *bool
+	DeploymentController *string
 }
 
 func (opt CreateOption) DryRunString() string {
@@ -28,6 +31,8 @@ type DeployOption struct {
 	SuspendAutoScaling *bool
 	RollbackEvents     *string
 	UpdateService      *bool
+	ExitCodeOnDiff     *bool
+	AllowNoChange      *bool
 }
 
 func (opt DeployOption) DryRunString() string {
@@ -38,13 +43,81 @@ func (opt DeployOption) DryRunString() string {
 }
 
 type StatusOption struct {
-	Events *int
+	Events         *int
+	Query          *string
+	Watch          *time.Duration
+	TaskDefinition *bool
+}
+
+type TasksOption struct {
+	DesiredStatus *string
+	StartedBy     *string
+}
+
+type CreateTaskSetOption struct {
+	DryRun     *bool
+	Scale      *float64
+	ExternalID *string
+	Promote    *bool
+}
+
+func (opt CreateTaskSetOption) DryRunString() string {
+	if *opt.DryRun {
+		return dryRunStr
+	}
+	return ""
+}
+
+type PromoteTaskSetOption struct {
+	DryRun *bool
+	ID     *string
+}
+
+func (opt PromoteTaskSetOption) DryRunString() string {
+	if *opt.DryRun {
+		return dryRunStr
+	}
+	return ""
+}
+
+type PruneDeploymentsOption struct {
+	DryRun    *bool
+	Fix       *bool
+	Threshold *time.Duration
+}
+
+type StopTaskOption struct {
+	DryRun *bool
+	ID     *string
+	Reason *string
+}
+
+func (opt StopTaskOption) DryRunString() string {
+	if *opt.DryRun {
+		return dryRunStr
+	}
+	return ""
+}
+
+type RevisionsOption struct {
+	DryRun *bool
+	NoWait *bool
+	Limit  *int64
+	Select *bool
+}
+
+type AbortOption struct {
+	DryRun *bool
+	NoWait *bool
 }
 
 type RollbackOption struct {
 	DryRun                   *bool
 	DeregisterTaskDefinition *bool
 	NoWait                   *bool
+	ToRevision               *int64
+	ToArn                    *string
+	Force                    *bool
 }
 
 func (opt RollbackOption) DryRunString() string {
@@ -73,6 +146,8 @@ type RunOption struct {
 	TaskOverrideStr    *string
 	SkipTaskDefinition *bool
 	Count              *int64
+	StartedBy          *string
+	PropagateTags      *string
 }
 
 func (opt RunOption) DryRunString() string {
@@ -97,6 +172,42 @@ func (opt RegisterOption) DryRunString() string {
 	return ""
 }
 
+type DiffOption struct {
+	SinceRevision *int64
+	Against       *string
+	ShowValues    *bool
+	Format        *string
+}
+
+type PlanOption struct {
+	ShowValues *bool
+	Out        *string
+}
+
+type ApplyOption struct {
+	PlanFile *string
+	NoWait   *bool
+}
+
+type ValidateOption struct {
+	Local           *bool
+	CreateLogGroups *bool
+}
+
+type ImportComposeOption struct {
+	ComposeFile *string
+	Family      *string
+	Output      *string
+	Force       *bool
+}
+
+type RenderOption struct {
+}
+
+type ExportOption struct {
+	Format *string
+}
+
 type InitOption struct {
 	Region                *string
 	Cluster               *string
@@ -104,4 +215,5 @@ type InitOption struct {
 	TaskDefinitionPath    *string
 	ServiceDefinitionPath *string
 	ConfigFilePath        *string
+	Force                 *bool
 }
@@ -0,0 +1,58 @@
+package ecspresso
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// orphanRevisionsLimit is a number of recent revisions checked by WarnOrphanRevisions.
+const orphanRevisionsLimit = 20
+
+// WarnOrphanRevisions warns about revisions registered within orphan_window in config
+// which are not used by the service (e.g. registered by CI, but the deploy step did not run).
+// It is informational, so failures are also logged as warnings.
+func (d *App) WarnOrphanRevisions(ctx context.Context) {
+	if d.config.OrphanWindow <= 0 {
+		return
+	}
+	orphans, err := d.orphanRevisions(ctx, time.Now().Add(-d.config.OrphanWindow))
+	if err != nil {
+		d.Log("[WARNING] failed to check orphan revisions:", err)
+		return
+	}
+	for _, td := range orphans {
+		d.Log("[WARNING]", taskDefinitionName(td), "was registered at",
+			aws.TimeValue(td.RegisteredAt).In(timezone).Format("2006/01/02 15:04:05"), "but is not deployed to the service")
+	}
+	if len(orphans) > 0 {
+		d.Log("[WARNING] deregister orphan revisions (e.g. by aws ecs deregister-task-definition) if not needed")
+	}
+}
+
+// orphanRevisions returns revisions of the family of the service registered after since,
+// which are neither the task definition of the service nor of its deployments.
+func (d *App) orphanRevisions(ctx context.Context, since time.Time) ([]*ecs.TaskDefinition, error) {
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tds, err := d.listRevisions(ctx, aws.StringValue(sv.TaskDefinition), orphanRevisionsLimit)
+	if err != nil {
+		return nil, err
+	}
+	inUse := map[string]bool{aws.StringValue(sv.TaskDefinition): true}
+	for _, dep := range sv.Deployments {
+		inUse[aws.StringValue(dep.TaskDefinition)] = true
+	}
+	var orphans []*ecs.TaskDefinition
+	for _, td := range tds {
+		if inUse[aws.StringValue(td.TaskDefinitionArn)] || !aws.TimeValue(td.RegisteredAt).After(since) {
+			continue
+		}
+		orphans = append(orphans, td)
+	}
+	return orphans, nil
+}
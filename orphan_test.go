@@ -0,0 +1,71 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+type mockOrphanECS struct {
+	mockRevisionsECS
+	service      *ecs.Service
+	registeredAt map[string]time.Time
+}
+
+func (m *mockOrphanECS) DescribeServicesWithContext(_ aws.Context, _ *ecs.DescribeServicesInput, _ ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	return &ecs.DescribeServicesOutput{Services: []*ecs.Service{m.service}}, nil
+}
+
+func (m *mockOrphanECS) DescribeTaskDefinitionWithContext(ctx aws.Context, in *ecs.DescribeTaskDefinitionInput, opts ...request.Option) (*ecs.DescribeTaskDefinitionOutput, error) {
+	out, err := m.mockRevisionsECS.DescribeTaskDefinitionWithContext(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out.TaskDefinition.RegisteredAt = aws.Time(m.registeredAt[arnToName(*in.TaskDefinition)])
+	return out, nil
+}
+
+func TestOrphanRevisions(t *testing.T) {
+	arn := func(name string) string {
+		return "arn:aws:ecs:us-east-1:123456789012:task-definition/" + name
+	}
+	now := time.Now()
+	d := &App{
+		config: &Config{Quiet: true},
+		ecs: &mockOrphanECS{
+			mockRevisionsECS: mockRevisionsECS{
+				pages: [][]string{{arn("app:6"), arn("app:5"), arn("app:4"), arn("app:3"), arn("app:2")}},
+			},
+			service: &ecs.Service{
+				TaskDefinition: aws.String(arn("app:4")),
+				Deployments: []*ecs.Deployment{
+					{Status: aws.String("PRIMARY"), TaskDefinition: aws.String(arn("app:4"))},
+					{Status: aws.String("ACTIVE"), TaskDefinition: aws.String(arn("app:5"))},
+				},
+			},
+			registeredAt: map[string]time.Time{
+				"app:6": now.Add(-10 * time.Minute), // registered but not deployed
+				"app:5": now.Add(-20 * time.Minute), // being replaced by the active deployment
+				"app:4": now.Add(-30 * time.Minute), // of the service
+				"app:3": now.Add(-40 * time.Minute), // registered but not deployed
+				"app:2": now.Add(-48 * time.Hour),   // out of the window
+			},
+		},
+	}
+	orphans, err := d.orphanRevisions(context.Background(), now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, td := range orphans {
+		names = append(names, taskDefinitionName(td))
+	}
+	if got := strings.Join(names, ","); got != "app:6,app:3" {
+		t.Errorf("unexpected orphan revisions %s", got)
+	}
+}
@@ -0,0 +1,68 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+)
+
+// PatchTaskDefinition creates a new task definition by applying the patch file to the task definition tdArn.
+func (d *App) PatchTaskDefinition(ctx context.Context, tdArn string, path string) (*ecs.TaskDefinition, error) {
+	d.Log("Creating a new task definition by", arnToName(tdArn), "patched with", path)
+	td, err := d.DescribeTaskDefinition(ctx, tdArn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe task definition")
+	}
+	var patch json.RawMessage
+	if err := d.loader.LoadWithEnvJSON(&patch, path); err != nil {
+		return nil, err
+	}
+	newTd, err := patchTaskDefinition(td, patch)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.applyImageManifest(newTd); err != nil {
+		return nil, err
+	}
+	if err := applyCommandOverrides(newTd, d.config.CommandOverrides); err != nil {
+		return nil, err
+	}
+	if err := d.validateTaskDefinition(newTd); err != nil {
+		return nil, err
+	}
+	return newTd, nil
+}
+
+// patchTaskDefinition applies the patch to td.
+// A patch is treated as JSON Patch(RFC 6902) when it is a JSON array, otherwise as JSON Merge Patch(RFC 7386).
+func patchTaskDefinition(td *ecs.TaskDefinition, patch []byte) (*ecs.TaskDefinition, error) {
+	doc, err := MarshalJSON(treatmentTaskDefinition(td))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal task definition to JSON")
+	}
+
+	var patched []byte
+	if p := bytes.TrimSpace(patch); len(p) > 0 && p[0] == '[' {
+		jp, err := jsonpatch.DecodePatch(p)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid JSON patch")
+		}
+		if patched, err = jp.Apply(doc); err != nil {
+			return nil, errors.Wrap(err, "failed to apply JSON patch")
+		}
+	} else {
+		if patched, err = jsonpatch.MergePatch(doc, p); err != nil {
+			return nil, errors.Wrap(err, "failed to apply JSON merge patch")
+		}
+	}
+
+	var newTd ecs.TaskDefinition
+	if err := json.Unmarshal(patched, &newTd); err != nil {
+		return nil, errors.Wrap(err, "failed to parse patched task definition")
+	}
+	return &newTd, nil
+}
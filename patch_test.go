@@ -0,0 +1,82 @@
+package ecspresso
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func testPatchBaseTaskDefinition() *ecs.TaskDefinition {
+	return &ecs.TaskDefinition{
+		Family:            aws.String("app"),
+		Memory:            aws.String("512"),
+		Revision:          aws.Int64(3),
+		TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/app:3"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name:  aws.String("app"),
+				Image: aws.String("app:v1"),
+				Environment: []*ecs.KeyValuePair{
+					{Name: aws.String("FOO"), Value: aws.String("foo")},
+				},
+			},
+			{
+				Name:  aws.String("nginx"),
+				Image: aws.String("nginx:1.17"),
+			},
+		},
+	}
+}
+
+func TestPatchTaskDefinitionJSONPatch(t *testing.T) {
+	patch := `[
+	  {"op": "replace", "path": "/containerDefinitions/0/image", "value": "app:v2"},
+	  {"op": "add", "path": "/containerDefinitions/0/environment/-", "value": {"name": "BAR", "value": "bar"}}
+	]`
+	td, err := patchTaskDefinition(testPatchBaseTaskDefinition(), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := td.ContainerDefinitions[0]
+	if *c.Image != "app:v2" {
+		t.Errorf("unexpected image %s", *c.Image)
+	}
+	if len(c.Environment) != 2 || *c.Environment[1].Name != "BAR" {
+		t.Errorf("unexpected environment %v", c.Environment)
+	}
+	if *td.ContainerDefinitions[1].Image != "nginx:1.17" {
+		t.Errorf("unexpected image %s", *td.ContainerDefinitions[1].Image)
+	}
+	if td.Revision != nil || td.TaskDefinitionArn != nil {
+		t.Errorf("read only fields must be removed %s", td.String())
+	}
+	if _, err := ValidateTaskDefinition(td); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPatchTaskDefinitionMergePatch(t *testing.T) {
+	patch := `{"cpu": "512", "taskRoleArn": "arn:aws:iam::123456789012:role/app"}`
+	td, err := patchTaskDefinition(testPatchBaseTaskDefinition(), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *td.Cpu != "512" || *td.TaskRoleArn != "arn:aws:iam::123456789012:role/app" {
+		t.Errorf("unexpected task definition %s", td.String())
+	}
+	if len(td.ContainerDefinitions) != 2 {
+		t.Errorf("unexpected container definitions %v", td.ContainerDefinitions)
+	}
+}
+
+func TestPatchTaskDefinitionInvalid(t *testing.T) {
+	patch := `{"containerDefinitions": [{"name": "app"}]}`
+	td, err := patchTaskDefinition(testPatchBaseTaskDefinition(), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateTaskDefinition(td); err == nil {
+		t.Error("container without image must be invalid")
+	}
+}
@@ -0,0 +1,112 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+)
+
+type permissionCheck struct {
+	action   string
+	resource string
+}
+
+// deployPermissionChecks returns permissions required to deploy.
+// td is nil when a new task definition is not registered.
+func deployPermissionChecks(td *ecs.TaskDefinition) []permissionCheck {
+	checks := []permissionCheck{
+		{action: "ecs:DescribeServices", resource: "*"},
+		{action: "ecs:UpdateService", resource: "*"},
+	}
+	if td == nil {
+		return checks
+	}
+	checks = append(checks, permissionCheck{action: "ecs:RegisterTaskDefinition", resource: "*"})
+	for _, role := range []*string{td.TaskRoleArn, td.ExecutionRoleArn} {
+		if r := aws.StringValue(role); r != "" {
+			checks = append(checks, permissionCheck{action: "iam:PassRole", resource: r})
+		}
+	}
+	return checks
+}
+
+// callerPrincipalArn returns an IAM principal ARN for the caller which can be simulated.
+func (d *App) callerPrincipalArn(ctx context.Context) (string, error) {
+	id, err := d.sts.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get caller identity")
+	}
+	a, err := arn.Parse(*id.Arn)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case a.Service == "iam" && strings.HasPrefix(a.Resource, "user/"):
+		return *id.Arn, nil
+	case a.Service == "sts" && strings.HasPrefix(a.Resource, "assumed-role/"):
+		// assumed-role/{RoleName}/{SessionName}
+		roleName := strings.Split(a.Resource, "/")[1]
+		out, err := d.iam.GetRoleWithContext(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get role "+roleName)
+		}
+		return *out.Role.Arn, nil
+	default:
+		return "", fmt.Errorf("could not simulate policies for %s", *id.Arn)
+	}
+}
+
+// checkPermissions simulates that the caller has permissions to perform checks.
+// It returns an error when any permissions are missing.
+func (d *App) checkPermissions(ctx context.Context, checks []permissionCheck) error {
+	principal, err := d.callerPrincipalArn(ctx)
+	if err != nil {
+		d.Log("[WARNING] skip checking permissions:", err)
+		return nil
+	}
+	d.Log("Checking permissions of", principal)
+
+	missing, err := d.simulatePermissions(ctx, principal, checks)
+	if err != nil {
+		d.Log("[WARNING] skip checking permissions: failed to simulate principal policy:", err)
+		return nil
+	}
+	if len(missing) > 0 {
+		for _, m := range missing {
+			d.Log("Permission denied:", m)
+		}
+		return fmt.Errorf("%d permissions are missing: %s", len(missing), strings.Join(missing, ", "))
+	}
+	d.Log("All permissions are allowed")
+	return nil
+}
+
+// simulatePermissions simulates checks for the principal, and returns denied permissions.
+func (d *App) simulatePermissions(ctx context.Context, principal string, checks []permissionCheck) ([]string, error) {
+	var missing []string
+	for _, c := range checks {
+		out, err := d.iam.SimulatePrincipalPolicyWithContext(ctx, &iam.SimulatePrincipalPolicyInput{
+			PolicySourceArn: aws.String(principal),
+			ActionNames:     []*string{aws.String(c.action)},
+			ResourceArns:    []*string{aws.String(c.resource)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range out.EvaluationResults {
+			decision := aws.StringValue(r.EvalDecision)
+			d.DebugLog(c.action, c.resource, decision)
+			if decision != iam.PolicyEvaluationDecisionTypeAllowed {
+				missing = append(missing, fmt.Sprintf("%s on %s (%s)", c.action, c.resource, decision))
+			}
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,104 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+)
+
+// PlanResult represents a result of plan.
+type PlanResult struct {
+	Service                  string          `json:"service"`
+	Cluster                  string          `json:"cluster"`
+	CurrentTaskDefinition    string          `json:"current_task_definition"`
+	CurrentTaskDefinitionArn string          `json:"current_task_definition_arn"`
+	HasDiff                  bool            `json:"has_diff"`
+	Diff                     string          `json:"diff"`
+	TaskDefinition           json.RawMessage `json:"task_definition"`
+}
+
+// Plan shows the diff against the task definition of the service and the rendered task definition
+// without any changes. It returns ErrDiffFound when there are differences.
+func (d *App) Plan(opt PlanOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	res, err := d.plan(ctx, opt)
+	if err != nil {
+		return err
+	}
+	if err := d.printPlan(os.Stdout, res); err != nil {
+		return err
+	}
+	if out := aws.StringValue(opt.Out); out != "" {
+		if err := writePlanFile(out, res); err != nil {
+			return errors.Wrap(err, "failed to write plan")
+		}
+		d.Log("Plan is saved to", out, "apply it by ecspresso apply", out)
+	}
+	if res.HasDiff {
+		return ErrDiffFound
+	}
+	return nil
+}
+
+func (d *App) plan(ctx context.Context, opt PlanOption) (*PlanResult, error) {
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remoteArn := aws.StringValue(sv.TaskDefinition)
+	td, err := d.loadTaskDefinitionFor(ctx, remoteArn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load task definition")
+	}
+	if aws.StringValue(opt.Out) != "" && d.config.ResolveImageDigests {
+		// apply ships images resolved at the plan
+		d.resolveImageDigests(ctx, td)
+	}
+	if err := d.validateTaskDefinition(td); err != nil {
+		return nil, err
+	}
+	ds, err := d.diffRemoteTaskDefinition(ctx, remoteArn, "", td, aws.BoolValue(opt.ShowValues))
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := marshalTaskDefinitionCanonical(td)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal task definition to JSON")
+	}
+	return &PlanResult{
+		Service:                  d.Service,
+		Cluster:                  d.Cluster,
+		CurrentTaskDefinition:    arnToName(remoteArn),
+		CurrentTaskDefinitionArn: remoteArn,
+		HasDiff:                  ds != "",
+		Diff:                     ds,
+		TaskDefinition:           rendered,
+	}, nil
+}
+
+func (d *App) printPlan(w io.Writer, res *PlanResult) error {
+	if d.config.OutputFormat == OutputFormatJSON {
+		b, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+	fmt.Fprintf(w, "### Diff from %s\n\n", res.CurrentTaskDefinition)
+	if res.HasDiff {
+		fmt.Fprint(w, res.Diff)
+	} else {
+		fmt.Fprintln(w, "No differences")
+	}
+	fmt.Fprintf(w, "\n### Rendered task definition\n\n")
+	_, err := w.Write(res.TaskDefinition)
+	return err
+}
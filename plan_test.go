@@ -0,0 +1,102 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+type mockPlanECS struct {
+	mockDiffECS
+	service *ecs.Service
+}
+
+func (m *mockPlanECS) DescribeServicesWithContext(_ aws.Context, _ *ecs.DescribeServicesInput, _ ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	return &ecs.DescribeServicesOutput{Services: []*ecs.Service{m.service}}, nil
+}
+
+func TestPlan(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	td, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.ContainerDefinitions[0].Image = aws.String("katsubushi/katsubushi:v0.0.1")
+	m := &mockPlanECS{
+		mockDiffECS: mockDiffECS{
+			taskDefinitions: map[string]*ecs.TaskDefinition{
+				"katsubushi:1": td,
+				"katsubushi:2": old,
+			},
+		},
+		service: &ecs.Service{TaskDefinition: aws.String("katsubushi:1")},
+	}
+	app.ecs = m
+	ctx := context.Background()
+
+	res, err := app.plan(ctx, PlanOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.HasDiff || res.Diff != "" {
+		t.Errorf("unexpected diff %s", res.Diff)
+	}
+	var buf bytes.Buffer
+	if err := app.printPlan(&buf, res); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"### Diff from katsubushi:1", "No differences", "### Rendered task definition", `"family": "katsubushi"`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("output must contain %s: %s", s, buf.String())
+		}
+	}
+
+	m.service.TaskDefinition = aws.String("katsubushi:2")
+	res, err = app.plan(ctx, PlanOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.HasDiff || !strings.Contains(res.Diff, "katsubushi:v0.0.1") {
+		t.Errorf("unexpected diff %s", res.Diff)
+	}
+
+	app.config.OutputFormat = OutputFormatJSON
+	buf.Reset()
+	if err := app.printPlan(&buf, res); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		CurrentTaskDefinition string `json:"current_task_definition"`
+		HasDiff               bool   `json:"has_diff"`
+		Diff                  string `json:"diff"`
+		TaskDefinition        struct {
+			Family string `json:"family"`
+		} `json:"task_definition"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.CurrentTaskDefinition != "katsubushi:2" || !out.HasDiff || out.Diff != res.Diff || out.TaskDefinition.Family != "katsubushi" {
+		t.Errorf("unexpected output %#v", out)
+	}
+}
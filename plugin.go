@@ -12,6 +12,20 @@ type ConfigPlugin struct {
 	Config map[string]interface{} `yaml:"config"`
 }
 
+// setupPlugins sets up the plugins of the config unless they are already set up.
+func (c *Config) setupPlugins() error {
+	if c.pluginsSetUp {
+		return nil
+	}
+	for _, p := range c.Plugins {
+		if err := p.Setup(c); err != nil {
+			return err
+		}
+	}
+	c.pluginsSetUp = true
+	return nil
+}
+
 func (p ConfigPlugin) Setup(c *Config) error {
 	switch p.Name {
 	case "tfstate":
@@ -19,7 +33,6 @@ func (p ConfigPlugin) Setup(c *Config) error {
 	default:
 		return fmt.Errorf("plugin %s is not available", p.Name)
 	}
-	return nil
 }
 
 func setupPluginTFState(p ConfigPlugin, c *Config) error {
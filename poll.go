@@ -0,0 +1,47 @@
+package ecspresso
+
+import (
+	"context"
+	"time"
+)
+
+// pollBackoff is an adaptive poll interval.
+// It starts at min and doubles toward max while nothing changes, and resets to min on any change.
+type pollBackoff struct {
+	min, max time.Duration
+	current  time.Duration
+}
+
+func newPollBackoff(min, max time.Duration) *pollBackoff {
+	if min <= 0 {
+		min = DefaultPollIntervalMin
+	}
+	if max < min {
+		max = min
+	}
+	return &pollBackoff{min: min, max: max, current: min}
+}
+
+// next returns the next interval.
+func (b *pollBackoff) next(changed bool) time.Duration {
+	if changed {
+		b.current = b.min
+	} else if b.current *= 2; b.current > b.max {
+		b.current = b.max
+	}
+	return b.current
+}
+
+// pollWithBackoff calls poll repeatedly until ctx is done.
+// poll returns whether the state has changed since the previous call.
+func pollWithBackoff(ctx context.Context, b *pollBackoff, after func(time.Duration) <-chan time.Time, poll func() bool) {
+	interval := b.current
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-after(interval):
+			interval = b.next(poll())
+		}
+	}
+}
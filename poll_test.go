@@ -0,0 +1,41 @@
+package ecspresso
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPollWithBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var waited []time.Duration
+	fakeAfter := func(d time.Duration) <-chan time.Time {
+		waited = append(waited, d)
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}
+	changes := []bool{false, false, false, false, false, true, false}
+	var n int
+	pollWithBackoff(ctx, newPollBackoff(2*time.Second, 30*time.Second), fakeAfter, func() bool {
+		if n >= len(changes) {
+			// select may choose after() even if ctx is done
+			return false
+		}
+		changed := changes[n]
+		n++
+		if n == len(changes) {
+			cancel()
+		}
+		return changed
+	})
+
+	s := time.Second
+	expected := []time.Duration{2 * s, 4 * s, 8 * s, 16 * s, 30 * s, 30 * s, 2 * s}
+	if !reflect.DeepEqual(waited[:len(expected)], expected) {
+		t.Errorf("unexpected intervals %v expected %v", waited, expected)
+	}
+}
@@ -0,0 +1,72 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const probeRequestTimeout = 10 * time.Second
+
+// httpProbe is an end-to-end HTTP probe to the service (e.g. via the DNS name of the load balancer).
+type httpProbe struct {
+	url    string
+	host   string
+	status int
+	client *http.Client
+}
+
+func newHTTPProbe(conf *Config) *httpProbe {
+	status := conf.PostDeployProbeStatus
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &httpProbe{
+		url:    conf.PostDeployProbeURL,
+		host:   conf.PostDeployProbeHost,
+		status: status,
+		client: &http.Client{Timeout: probeRequestTimeout},
+	}
+}
+
+// do sends a GET request, and returns an error when the response status is not the expected one.
+func (p *httpProbe) do(ctx context.Context) error {
+	req, err := http.NewRequest(http.MethodGet, p.url, nil)
+	if err != nil {
+		return err
+	}
+	if p.host != "" {
+		req.Host = p.host
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != p.status {
+		return fmt.Errorf("status %d (expected %d)", resp.StatusCode, p.status)
+	}
+	return nil
+}
+
+// waitProbeSucceeded retries the probe until it succeeds within ctx.
+func (d *App) waitProbeSucceeded(ctx context.Context, p *httpProbe, after func(time.Duration) <-chan time.Time) error {
+	d.Log("Probing", p.url)
+	b := newPollBackoff(d.config.PollIntervalMin, d.config.PollIntervalMax)
+	for {
+		err := p.do(ctx)
+		if err == nil {
+			d.Log("Probe succeeded:", p.url)
+			return nil
+		}
+		d.DebugLog("probe failed:", err)
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ErrWaitTimeout, "probe to %s failed: %s", p.url, err)
+		case <-after(b.next(false)):
+		}
+	}
+}
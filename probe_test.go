@@ -0,0 +1,78 @@
+package ecspresso
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestWaitProbeSucceeded(t *testing.T) {
+	var requests int
+	var hosts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		hosts = append(hosts, r.Host)
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	d := &App{config: &Config{
+		PollIntervalMin:       time.Second,
+		PollIntervalMax:       time.Second,
+		PostDeployProbeURL:    ts.URL + "/health",
+		PostDeployProbeStatus: http.StatusNoContent,
+		PostDeployProbeHost:   "app.example.com",
+	}}
+	after := func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+	if err := d.waitProbeSucceeded(context.Background(), newHTTPProbe(d.config), after); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("unexpected requests %d", requests)
+	}
+	for _, h := range hosts {
+		if h != "app.example.com" {
+			t.Errorf("unexpected host %s", h)
+		}
+	}
+
+	// the expected status is 200 by default
+	d.config.PostDeployProbeStatus = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := d.waitProbeSucceeded(ctx, newHTTPProbe(d.config), after)
+	if errors.Cause(err) != ErrWaitTimeout {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.Contains(err.Error(), "probe to "+ts.URL+"/health failed") {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestHTTPProbeStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	err := newHTTPProbe(&Config{PostDeployProbeURL: ts.URL}).do(context.Background())
+	if err == nil || err.Error() != "status 404 (expected 200)" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := newHTTPProbe(&Config{PostDeployProbeURL: ts.URL, PostDeployProbeStatus: 404}).do(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
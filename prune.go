@@ -0,0 +1,69 @@
+package ecspresso
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// DefaultStuckDeploymentThreshold is a default duration to regard non-primary deployments as stuck.
+const DefaultStuckDeploymentThreshold = 30 * time.Minute
+
+// PruneDeployments reports deployments of the service stuck in non-primary state
+// (e.g. left after a failed rollback by the deployment circuit breaker), and clears them by
+// a force new deployment when opt.Fix is set.
+func (d *App) PruneDeployments(opt PruneDeploymentsOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	threshold := DefaultStuckDeploymentThreshold
+	if opt.Threshold != nil && *opt.Threshold > 0 {
+		threshold = *opt.Threshold
+	}
+	stuck := stuckDeployments(sv, threshold, time.Now())
+	if len(stuck) == 0 {
+		d.ResultLog("No stuck deployments")
+		return nil
+	}
+	for _, dep := range stuck {
+		d.ResultLog(fmt.Sprintf("Stuck deployment %s: %s", aws.StringValue(dep.Id), formatDeployment(dep)))
+	}
+	if !aws.BoolValue(opt.Fix) {
+		d.ResultLog("Run with --fix (or `ecspresso refresh`) to clear them by a force new deployment")
+		return nil
+	}
+	if aws.BoolValue(opt.DryRun) {
+		d.ResultLog("DRY RUN OK")
+		return nil
+	}
+	if err := d.UpdateServiceTasks(ctx, aws.StringValue(sv.TaskDefinition), nil, DeployOption{ForceNewDeployment: aws.Bool(true)}); err != nil {
+		return errors.Wrap(err, "failed to update service")
+	}
+	d.ResultLog("Force new deployment is started to clear stuck deployments")
+	return nil
+}
+
+// stuckDeployments returns deployments that are not PRIMARY and not updated for longer than threshold.
+func stuckDeployments(sv *ecs.Service, threshold time.Duration, now time.Time) []*ecs.Deployment {
+	var stuck []*ecs.Deployment
+	for _, dep := range sv.Deployments {
+		if aws.StringValue(dep.Status) == "PRIMARY" {
+			continue
+		}
+		updatedAt := aws.TimeValue(dep.UpdatedAt)
+		if updatedAt.IsZero() {
+			updatedAt = aws.TimeValue(dep.CreatedAt)
+		}
+		if now.Sub(updatedAt) > threshold {
+			stuck = append(stuck, dep)
+		}
+	}
+	return stuck
+}
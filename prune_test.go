@@ -0,0 +1,99 @@
+package ecspresso
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockPruneECS struct {
+	ecsiface.ECSAPI
+	service *ecs.Service
+	updated *ecs.UpdateServiceInput
+}
+
+func (m *mockPruneECS) DescribeServicesWithContext(_ aws.Context, _ *ecs.DescribeServicesInput, _ ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	return &ecs.DescribeServicesOutput{Services: []*ecs.Service{m.service}}, nil
+}
+
+func (m *mockPruneECS) UpdateServiceWithContext(_ aws.Context, in *ecs.UpdateServiceInput, _ ...request.Option) (*ecs.UpdateServiceOutput, error) {
+	m.updated = in
+	return &ecs.UpdateServiceOutput{}, nil
+}
+
+func testStuckDeploymentService(now time.Time) *ecs.Service {
+	dep := func(id, status, td string, updatedAt time.Time) *ecs.Deployment {
+		return &ecs.Deployment{
+			Id:             aws.String(id),
+			Status:         aws.String(status),
+			TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/" + td),
+			DesiredCount:   aws.Int64(1),
+			PendingCount:   aws.Int64(0),
+			RunningCount:   aws.Int64(1),
+			CreatedAt:      aws.Time(updatedAt),
+			UpdatedAt:      aws.Time(updatedAt),
+		}
+	}
+	return &ecs.Service{
+		ServiceName:    aws.String("test"),
+		TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/app:3"),
+		Deployments: []*ecs.Deployment{
+			dep("ecs-svc/3", "PRIMARY", "app:3", now.Add(-2*time.Hour)),
+			dep("ecs-svc/2", "ACTIVE", "app:2", now.Add(-time.Hour)),
+			dep("ecs-svc/1", "ACTIVE", "app:1", now.Add(-10*time.Minute)),
+		},
+	}
+}
+
+func TestStuckDeployments(t *testing.T) {
+	now := time.Now()
+	stuck := stuckDeployments(testStuckDeploymentService(now), 30*time.Minute, now)
+	if len(stuck) != 1 || *stuck[0].Id != "ecs-svc/2" {
+		t.Errorf("unexpected stuck deployments %v", stuck)
+	}
+	if stuck := stuckDeployments(testStuckDeploymentService(now), 5*time.Minute, now); len(stuck) != 2 {
+		t.Errorf("unexpected stuck deployments %v", stuck)
+	}
+}
+
+func TestPruneDeploymentsFix(t *testing.T) {
+	delayForServiceChanged = 0
+	defer func() { delayForServiceChanged = 3 * time.Second }()
+
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockPruneECS{service: testStuckDeploymentService(time.Now())}
+	app.ecs = m
+	threshold := 30 * time.Minute
+	opt := PruneDeploymentsOption{
+		DryRun:    aws.Bool(false),
+		Fix:       aws.Bool(false),
+		Threshold: &threshold,
+	}
+	if err := app.PruneDeployments(opt); err != nil {
+		t.Fatal(err)
+	}
+	if m.updated != nil {
+		t.Error("must not update the service without --fix")
+	}
+
+	opt.Fix = aws.Bool(true)
+	if err := app.PruneDeployments(opt); err != nil {
+		t.Fatal(err)
+	}
+	if m.updated == nil || !aws.BoolValue(m.updated.ForceNewDeployment) || aws.StringValue(m.updated.TaskDefinition) != *m.service.TaskDefinition {
+		t.Errorf("unexpected update service input %v", m.updated)
+	}
+}
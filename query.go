@@ -0,0 +1,55 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/jmespath/go-jmespath"
+	"github.com/pkg/errors"
+)
+
+// queryServiceStatus prints the result of JMESPath query to the describe-services output, like aws cli's --query.
+// A string result is printed as is, otherwise printed as JSON.
+func (d *App) queryServiceStatus(ctx context.Context, query string) error {
+	jp, err := jmespath.Compile(query)
+	if err != nil {
+		return errors.Wrapf(err, "invalid query %q", query)
+	}
+	out, err := d.ecs.DescribeServicesWithContext(ctx, d.DescribeServicesInput())
+	if err != nil {
+		return errors.Wrap(err, "failed to describe service")
+	}
+	if len(out.Services) == 0 {
+		return ErrServiceNotFound
+	}
+
+	result, err := searchJSON(jp, out)
+	if err != nil {
+		return errors.Wrapf(err, "failed to query %q", query)
+	}
+	switch r := result.(type) {
+	case string:
+		fmt.Println(r)
+	default:
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	}
+	return nil
+}
+
+func searchJSON(jp *jmespath.JMESPath, v interface{}) (interface{}, error) {
+	b, err := jsonutil.BuildJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return jp.Search(data)
+}
@@ -0,0 +1,79 @@
+package ecspresso
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/jmespath/go-jmespath"
+)
+
+func TestSearchJSON(t *testing.T) {
+	out := &ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			{
+				ServiceName:    aws.String("test"),
+				TaskDefinition: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:42"),
+				DesiredCount:   aws.Int64(2),
+			},
+		},
+	}
+	for query, expected := range map[string]interface{}{
+		"services[0].taskDefinition": "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:42",
+		"services[0].desiredCount":   float64(2),
+		"services[0].runningCount":   nil,
+	} {
+		result, err := searchJSON(jmespath.MustCompile(query), out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != expected {
+			t.Errorf("query %s expected %v got %v", query, expected, result)
+		}
+	}
+}
+
+func TestStatusTaskDefinition(t *testing.T) {
+	defer log.SetOutput(os.Stderr)
+	// STDOUT is replaced before NewApp, to catch writers for logs chosen at NewApp
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	d, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.ecs = &mockPruneECS{
+		service: &ecs.Service{
+			ServiceName:    aws.String("test"),
+			TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/test:3"),
+		},
+	}
+
+	err = d.Status(StatusOption{Events: aws.Int(2), TaskDefinition: aws.Bool(true)})
+	d.Log("logs must not be written to STDOUT")
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "test:3\n" {
+		t.Errorf("only the task definition must be printed to STDOUT: %q", string(b))
+	}
+}
@@ -0,0 +1,59 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// rampDesiredCounts returns desired counts to be set in sequence.
+// Steps at or below the current desired count are dropped, not to reduce running tasks.
+// The final count is appended when it differs from the last step.
+func rampDesiredCounts(steps []int64, current, final int64) []int64 {
+	counts := make([]int64, 0, len(steps)+1)
+	for _, s := range steps {
+		if s > current {
+			counts = append(counts, s)
+		}
+	}
+	if len(counts) == 0 || counts[len(counts)-1] != final {
+		counts = append(counts, final)
+	}
+	return counts
+}
+
+// runRampSteps calls f for each count in sequence, and aborts at the first failure.
+func (d *App) runRampSteps(ctx context.Context, counts []int64, f func(ctx context.Context, step int, count int64) error) error {
+	for i, count := range counts {
+		d.Log(fmt.Sprintf("Ramp step %d/%d: desired count %d", i+1, len(counts), count))
+		if err := f(ctx, i, count); err != nil {
+			return errors.Wrapf(err, "ramp step %d/%d (desired count %d) failed", i+1, len(counts), count)
+		}
+	}
+	return nil
+}
+
+// deployWithRamp updates the service to ramp_steps desired counts in sequence, waiting for the service stable between steps.
+func (d *App) deployWithRamp(ctx context.Context, tdArn string, current, final int64, opt DeployOption) error {
+	counts := rampDesiredCounts(d.config.RampSteps, current, final)
+	if len(d.config.RampSteps) > 0 && d.config.RampSteps[0] <= current {
+		d.Log(fmt.Sprintf("Ramp steps at or below the current desired count %d are skipped", current))
+	}
+	return d.runRampSteps(ctx, counts, func(ctx context.Context, step int, count int64) error {
+		stepOpt := opt
+		if step > 0 {
+			// a new deployment is started by the first step only
+			f := false
+			stepOpt.ForceNewDeployment = &f
+		}
+		if err := d.UpdateServiceTasks(ctx, tdArn, &count, stepOpt); err != nil {
+			return errors.Wrap(err, "failed to update service tasks")
+		}
+		if *opt.NoWait && step == len(counts)-1 {
+			return nil
+		}
+		return d.WaitServiceStable(ctx, time.Now())
+	})
+}
@@ -0,0 +1,55 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRampDesiredCounts(t *testing.T) {
+	if got := rampDesiredCounts([]int64{1, 5, 20}, 0, 20); !reflect.DeepEqual(got, []int64{1, 5, 20}) {
+		t.Errorf("unexpected counts %v", got)
+	}
+	if got := rampDesiredCounts([]int64{1, 5}, 0, 10); !reflect.DeepEqual(got, []int64{1, 5, 10}) {
+		t.Errorf("unexpected counts %v", got)
+	}
+	// the running tasks must not be reduced
+	if got := rampDesiredCounts([]int64{1, 5, 20}, 5, 20); !reflect.DeepEqual(got, []int64{20}) {
+		t.Errorf("steps at or below the current desired count must be dropped %v", got)
+	}
+	if got := rampDesiredCounts([]int64{1, 5, 20}, 20, 20); !reflect.DeepEqual(got, []int64{20}) {
+		t.Errorf("only the final count must be set %v", got)
+	}
+}
+
+func TestRunRampSteps(t *testing.T) {
+	app := &App{Service: "test", Cluster: "default", config: &Config{}}
+
+	var called []int64
+	err := app.runRampSteps(context.Background(), []int64{1, 5, 20}, func(_ context.Context, _ int, count int64) error {
+		called = append(called, count)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(called, []int64{1, 5, 20}) {
+		t.Errorf("unexpected sequence %v", called)
+	}
+
+	called = nil
+	err = app.runRampSteps(context.Background(), []int64{1, 5, 20}, func(_ context.Context, step int, count int64) error {
+		called = append(called, count)
+		if step == 1 {
+			return errors.New("unstable")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "ramp step 2/3 (desired count 5) failed: unstable" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(called, []int64{1, 5}) {
+		t.Errorf("ramp must be aborted at the failed step %v", called)
+	}
+}
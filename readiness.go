@@ -0,0 +1,126 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// validatePrimaryContainer returns an error when the primary container is not defined in the task definition.
+func validatePrimaryContainer(name string, td *ecs.TaskDefinition) error {
+	if name == "" {
+		return nil
+	}
+	for _, cd := range td.ContainerDefinitions {
+		if aws.StringValue(cd.Name) == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("primary_container: container %s is not defined in the task definition", name)
+}
+
+// readinessContainers returns the container definitions which must be ready in the task.
+// When primary is specified, only the primary container is watched. Otherwise all essential containers are.
+func readinessContainers(td *ecs.TaskDefinition, primary string) []*ecs.ContainerDefinition {
+	var cds []*ecs.ContainerDefinition
+	for _, cd := range td.ContainerDefinitions {
+		if primary != "" {
+			if aws.StringValue(cd.Name) == primary {
+				cds = append(cds, cd)
+			}
+			continue
+		}
+		if cd.Essential == nil || aws.BoolValue(cd.Essential) {
+			cds = append(cds, cd)
+		}
+	}
+	return cds
+}
+
+// unreadyContainers returns descriptions of the watched containers which are not ready in the task.
+// A container having a health check is ready when it is HEALTHY, and others are ready when RUNNING.
+func unreadyContainers(task *ecs.Task, td *ecs.TaskDefinition, primary string) []string {
+	containers := make(map[string]*ecs.Container, len(task.Containers))
+	for _, c := range task.Containers {
+		containers[aws.StringValue(c.Name)] = c
+	}
+	taskID := arnToName(aws.StringValue(task.TaskArn))
+	var unready []string
+	for _, cd := range readinessContainers(td, primary) {
+		name := aws.StringValue(cd.Name)
+		c, ok := containers[name]
+		switch {
+		case !ok:
+			unready = append(unready, fmt.Sprintf("%s/%s not found", taskID, name))
+		case cd.HealthCheck != nil:
+			if h := aws.StringValue(c.HealthStatus); h != ecs.HealthStatusHealthy {
+				unready = append(unready, fmt.Sprintf("%s/%s %s", taskID, name, h))
+			}
+		case aws.StringValue(c.LastStatus) != ecs.DesiredStatusRunning:
+			unready = append(unready, fmt.Sprintf("%s/%s %s", taskID, name, aws.StringValue(c.LastStatus)))
+		}
+	}
+	return unready
+}
+
+// waitContainersHealthy waits until the watched containers of the running tasks of the task definition of the service are ready.
+func (d *App) waitContainersHealthy(ctx context.Context, after func(time.Duration) <-chan time.Time) error {
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	tdArn := aws.StringValue(sv.TaskDefinition)
+	td, err := d.DescribeTaskDefinition(ctx, tdArn)
+	if err != nil {
+		return err
+	}
+	if p := d.config.PrimaryContainer; p != "" {
+		d.Log("Waiting for the primary container", p, "of the new tasks to be healthy")
+	} else {
+		d.Log("Waiting for the essential containers of the new tasks to be healthy")
+	}
+
+	b := newPollBackoff(d.config.PollIntervalMin, d.config.PollIntervalMax)
+	var unready []string
+	for {
+		unready, err = d.describeUnreadyContainers(ctx, td, tdArn)
+		if err != nil {
+			return err
+		}
+		if len(unready) == 0 {
+			d.Log("All watched containers of the new tasks are healthy")
+			return nil
+		}
+		d.DebugLog("unready containers:", strings.Join(unready, ", "))
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ErrWaitTimeout, "containers are not healthy: %s", strings.Join(unready, ", "))
+		case <-after(b.next(false)):
+		}
+	}
+}
+
+func (d *App) describeUnreadyContainers(ctx context.Context, td *ecs.TaskDefinition, tdArn string) ([]string, error) {
+	all, err := d.listTasks(ctx, ecs.DesiredStatusRunning, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tasks")
+	}
+	var found bool
+	var unready []string
+	for _, task := range all {
+		if aws.StringValue(task.TaskDefinitionArn) != tdArn {
+			continue
+		}
+		found = true
+		unready = append(unready, unreadyContainers(task, td, d.config.PrimaryContainer)...)
+	}
+	if !found {
+		return []string{"no running tasks of " + arnToName(tdArn)}, nil
+	}
+	return unready, nil
+}
@@ -0,0 +1,127 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+var testReadinessTaskDefinition = &ecs.TaskDefinition{
+	ContainerDefinitions: []*ecs.ContainerDefinition{
+		{
+			Name:        aws.String("app"),
+			Essential:   aws.Bool(true),
+			HealthCheck: &ecs.HealthCheck{Command: aws.StringSlice([]string{"CMD", "true"})},
+		},
+		{
+			Name:        aws.String("envoy"),
+			HealthCheck: &ecs.HealthCheck{Command: aws.StringSlice([]string{"CMD", "true"})},
+		},
+		{
+			Name:      aws.String("log-router"),
+			Essential: aws.Bool(false),
+		},
+	},
+}
+
+func testReadinessTask(id, tdRev string, containers map[string][2]string) *ecs.Task {
+	task := testAwsvpcTask(id, tdRev, "10.0.0.1")
+	for name, st := range containers {
+		c := &ecs.Container{Name: aws.String(name), LastStatus: aws.String(st[0])}
+		if st[1] != "" {
+			c.HealthStatus = aws.String(st[1])
+		}
+		task.Containers = append(task.Containers, c)
+	}
+	return task
+}
+
+func TestUnreadyContainers(t *testing.T) {
+	task := testReadinessTask("new1", "2", map[string][2]string{
+		"app":        {"RUNNING", "HEALTHY"},
+		"envoy":      {"RUNNING", "UNKNOWN"},
+		"log-router": {"PENDING", ""},
+	})
+
+	unready := unreadyContainers(task, testReadinessTaskDefinition, "")
+	if len(unready) != 1 || unready[0] != "new1/envoy UNKNOWN" {
+		t.Errorf("essential containers must be watched by default: %v", unready)
+	}
+	if unready := unreadyContainers(task, testReadinessTaskDefinition, "app"); len(unready) != 0 {
+		t.Errorf("only the primary container must be watched: %v", unready)
+	}
+	unready = unreadyContainers(task, testReadinessTaskDefinition, "envoy")
+	if len(unready) != 1 || unready[0] != "new1/envoy UNKNOWN" {
+		t.Errorf("the primary container must be watched: %v", unready)
+	}
+	unready = unreadyContainers(task, testReadinessTaskDefinition, "log-router")
+	if len(unready) != 1 || unready[0] != "new1/log-router PENDING" {
+		t.Errorf("a container without health checks must be RUNNING: %v", unready)
+	}
+}
+
+func TestValidatePrimaryContainer(t *testing.T) {
+	if err := validatePrimaryContainer("", testReadinessTaskDefinition); err != nil {
+		t.Error(err)
+	}
+	if err := validatePrimaryContainer("app", testReadinessTaskDefinition); err != nil {
+		t.Error(err)
+	}
+	if err := validatePrimaryContainer("web", testReadinessTaskDefinition); err == nil {
+		t.Error("an undefined primary container must be invalid")
+	}
+}
+
+type mockReadinessECS struct {
+	mockTargetHealthECS
+}
+
+func (m *mockReadinessECS) DescribeTaskDefinitionWithContext(_ aws.Context, _ *ecs.DescribeTaskDefinitionInput, _ ...request.Option) (*ecs.DescribeTaskDefinitionOutput, error) {
+	return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: testReadinessTaskDefinition}, nil
+}
+
+func TestWaitContainersHealthyPrimary(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+		PrimaryContainer:   "app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.ecs = &mockReadinessECS{mockTargetHealthECS{tasks: []*ecs.Task{
+		testReadinessTask("new1", "2", map[string][2]string{
+			"app":   {"RUNNING", "HEALTHY"},
+			"envoy": {"RUNNING", "UNKNOWN"},
+		}),
+		// old task being drained
+		testReadinessTask("old1", "1", map[string][2]string{
+			"app": {"RUNNING", "UNHEALTHY"},
+		}),
+	}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := app.waitContainersHealthy(ctx, noWait); err != nil {
+		t.Errorf("sidecars must not block the primary container: %s", err)
+	}
+
+	app.config.PrimaryContainer = ""
+	err = app.waitContainersHealthy(ctx, func(time.Duration) <-chan time.Time { return nil })
+	if err == nil {
+		t.Fatal("must fail with unhealthy essential containers")
+	}
+	if ErrorType(err) != "WaitTimeout" {
+		t.Errorf("unexpected error type %s", ErrorType(err))
+	}
+	if !strings.Contains(err.Error(), "new1/envoy UNKNOWN") || strings.Contains(err.Error(), "old1") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,25 @@
+package ecspresso
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Render prints the task definition to be registered as JSON to stdout.
+// It does not call any AWS API.
+func (d *App) Render(opt RenderOption) error {
+	td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to load task definition")
+	}
+	if err := d.validateTaskDefinition(td); err != nil {
+		return err
+	}
+	b, err := marshalTaskDefinitionCanonical(td)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal task definition to JSON")
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
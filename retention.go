@@ -0,0 +1,69 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// warnRollbackRetention warns when the current task definition of the service, which is the rollback target
+// after the deploy, is at risk of being deregistered by a cleanup keeping keep_active_revisions in config.
+// It is informational, so failures are also logged as warnings.
+func (d *App) warnRollbackRetention(ctx context.Context, currentArn string) {
+	keep := d.config.KeepActiveRevisions
+	if keep <= 0 {
+		return
+	}
+	newer, err := d.countNewerRevisions(ctx, currentArn)
+	if err != nil {
+		d.Log("[WARNING] failed to check retention of the rollback target:", err)
+		return
+	}
+	// the revision to be registered is also newer than the current one
+	if newer++; newer >= keep {
+		d.Log(fmt.Sprintf(
+			"[WARNING] %s (the rollback target) will have %d newer active revisions after the deploy, so it may be deregistered by the cleanup keeping %d revisions (keep_active_revisions) and rollback may not be possible",
+			arnToName(currentArn), newer, keep,
+		))
+		return
+	}
+	d.DebugLog(arnToName(currentArn), "(the rollback target) is kept by keep_active_revisions", keep)
+}
+
+// countNewerRevisions returns the number of the active revisions of the family newer than the task definition.
+func (d *App) countNewerRevisions(ctx context.Context, taskDefinitionArn string) (int, error) {
+	family, rev := parseTaskDefinitionName(arnToName(taskDefinitionArn))
+	var n int
+	var nextToken *string
+	for {
+		out, err := d.ecs.ListTaskDefinitionsWithContext(ctx,
+			&ecs.ListTaskDefinitionsInput{
+				NextToken:    nextToken,
+				FamilyPrefix: aws.String(family),
+				Status:       aws.String(ecs.TaskDefinitionStatusActive),
+				MaxResults:   aws.Int64(100),
+				Sort:         aws.String("DESC"),
+			},
+		)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to list taskdefinitions")
+		}
+		for _, tdArn := range out.TaskDefinitionArns {
+			// FamilyPrefix also matches other families having the prefix
+			f, r := parseTaskDefinitionName(arnToName(*tdArn))
+			if f != family {
+				continue
+			}
+			if r <= rev {
+				return n, nil
+			}
+			n++
+		}
+		if nextToken = out.NextToken; nextToken == nil {
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWarnRollbackRetention(t *testing.T) {
+	arn := func(name string) string {
+		return "arn:aws:ecs:us-east-1:123456789012:task-definition/" + name
+	}
+	m := &mockRevisionsECS{
+		pages: [][]string{
+			{arn("app:5"), arn("app-worker:9"), arn("app:4")},
+			{arn("app:3"), arn("app:2"), arn("app:1")},
+		},
+	}
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	for _, c := range []struct {
+		current string
+		keep    int
+		warn    bool
+	}{
+		{current: "app:5", keep: 0, warn: false},
+		{current: "app:5", keep: 1, warn: true},
+		{current: "app:5", keep: 2, warn: false},
+		{current: "app:3", keep: 3, warn: true},
+		{current: "app:3", keep: 4, warn: false},
+		{current: "app:1", keep: 5, warn: true},
+	} {
+		buf.Reset()
+		d := &App{config: &Config{KeepActiveRevisions: c.keep}, ecs: m}
+		d.warnRollbackRetention(context.Background(), arn(c.current))
+		if warned := strings.Contains(buf.String(), "[WARNING] "+c.current+" (the rollback target)"); warned != c.warn {
+			t.Errorf("current %s keep %d: unexpected warning %v: %s", c.current, c.keep, warned, buf.String())
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// DefaultRevisionsLimit is a default number of revisions listed by revisions.
+const DefaultRevisionsLimit = 10
+
+// Revisions lists recent revisions of the task definition family of the service.
+// When opt.Select is set in interactive mode, it rolls back (or forward) the service to the selected revision.
+func (d *App) Revisions(opt RevisionsOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	currentArn := aws.StringValue(sv.TaskDefinition)
+	limit := aws.Int64Value(opt.Limit)
+	if limit <= 0 {
+		limit = DefaultRevisionsLimit
+	}
+	tds, err := d.listRevisions(ctx, currentArn, limit)
+	if err != nil {
+		return err
+	}
+	for _, td := range tds {
+		fmt.Println(formatRevision(td, aws.StringValue(td.TaskDefinitionArn) == currentArn))
+	}
+	if !aws.BoolValue(opt.Select) {
+		return nil
+	}
+	if d.config.NonInteractive {
+		d.Log("[WARNING] revision is not selected in non-interactive mode")
+		return nil
+	}
+	input := strings.TrimSpace(promptText("Enter a revision to deploy (empty to cancel)", ""))
+	if input == "" {
+		d.Log("Canceled")
+		return nil
+	}
+	rev, err := strconv.ParseInt(input, 10, 64)
+	if err != nil || rev <= 0 {
+		return errors.Errorf("invalid revision %q", input)
+	}
+	cancel()
+	force := true // the revision is selected explicitly, even if newer than current
+	return d.Rollback(RollbackOption{
+		DryRun:                   opt.DryRun,
+		DeregisterTaskDefinition: aws.Bool(false),
+		NoWait:                   opt.NoWait,
+		ToRevision:               &rev,
+		ToArn:                    aws.String(""),
+		Force:                    &force,
+	})
+}
+
+// listRevisions returns up to limit revisions of the family of the task definition, newest first.
+func (d *App) listRevisions(ctx context.Context, taskDefinitionArn string, limit int64) ([]*ecs.TaskDefinition, error) {
+	family, _ := parseTaskDefinitionName(arnToName(taskDefinitionArn))
+	var arns []string
+	var nextToken *string
+	for int64(len(arns)) < limit {
+		out, err := d.ecs.ListTaskDefinitionsWithContext(ctx,
+			&ecs.ListTaskDefinitionsInput{
+				NextToken:    nextToken,
+				FamilyPrefix: aws.String(family),
+				MaxResults:   aws.Int64(100),
+				Sort:         aws.String("DESC"),
+			},
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list taskdefinitions")
+		}
+		for _, tdArn := range out.TaskDefinitionArns {
+			// FamilyPrefix also matches other families having the prefix
+			if f, _ := parseTaskDefinitionName(arnToName(*tdArn)); f == family && int64(len(arns)) < limit {
+				arns = append(arns, *tdArn)
+			}
+		}
+		if nextToken = out.NextToken; nextToken == nil {
+			break
+		}
+	}
+	tds := make([]*ecs.TaskDefinition, 0, len(arns))
+	for _, tdArn := range arns {
+		td, err := d.DescribeTaskDefinition(ctx, tdArn)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to describe task definition %s", arnToName(tdArn))
+		}
+		tds = append(tds, td)
+	}
+	return tds, nil
+}
+
+// formatRevision returns a line of the revision with its registration time and images of containers.
+// The current revision of the service is marked by "*".
+func formatRevision(td *ecs.TaskDefinition, current bool) string {
+	mark := " "
+	if current {
+		mark = "*"
+	}
+	var registeredAt string
+	if td.RegisteredAt != nil {
+		registeredAt = td.RegisteredAt.In(timezone).Format("2006/01/02 15:04:05")
+	}
+	images := make([]string, 0, len(td.ContainerDefinitions))
+	for _, cd := range td.ContainerDefinitions {
+		images = append(images, aws.StringValue(cd.Name)+"="+imageTag(aws.StringValue(cd.Image)))
+	}
+	return strings.Join([]string{
+		mark + " " + taskDefinitionName(td),
+		registeredAt,
+		strings.Join(images, ","),
+	}, "\t")
+}
+
+// imageTag returns a short reference of the image: a tag, or a short digest.
+func imageTag(image string) string {
+	if i := strings.LastIndex(image, "@"); i != -1 {
+		digest := image[i+1:]
+		if len(digest) > 19 {
+			// sha256: + 12 chars
+			digest = digest[:19]
+		}
+		return digest
+	}
+	name := image
+	if i := strings.LastIndex(image, "/"); i != -1 {
+		name = image[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i != -1 {
+		return name[i+1:]
+	}
+	return "latest"
+}
@@ -0,0 +1,87 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockRevisionsECS struct {
+	ecsiface.ECSAPI
+	pages [][]string
+}
+
+func (m *mockRevisionsECS) ListTaskDefinitionsWithContext(_ aws.Context, in *ecs.ListTaskDefinitionsInput, _ ...request.Option) (*ecs.ListTaskDefinitionsOutput, error) {
+	i := 0
+	if in.NextToken != nil {
+		i = 1
+	}
+	out := &ecs.ListTaskDefinitionsOutput{TaskDefinitionArns: aws.StringSlice(m.pages[i])}
+	if i+1 < len(m.pages) {
+		out.NextToken = aws.String("next")
+	}
+	return out, nil
+}
+
+func (m *mockRevisionsECS) DescribeTaskDefinitionWithContext(_ aws.Context, in *ecs.DescribeTaskDefinitionInput, _ ...request.Option) (*ecs.DescribeTaskDefinitionOutput, error) {
+	family, rev := parseTaskDefinitionName(arnToName(*in.TaskDefinition))
+	return &ecs.DescribeTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			Family:            aws.String(family),
+			Revision:          aws.Int64(rev),
+			TaskDefinitionArn: in.TaskDefinition,
+		},
+	}, nil
+}
+
+func TestListRevisions(t *testing.T) {
+	arn := func(name string) string {
+		return "arn:aws:ecs:us-east-1:123456789012:task-definition/" + name
+	}
+	d := &App{
+		config: &Config{Quiet: true},
+		ecs: &mockRevisionsECS{
+			pages: [][]string{
+				{arn("app:5"), arn("app-worker:9"), arn("app:4")},
+				{arn("app:3"), arn("app:2"), arn("app:1")},
+			},
+		},
+	}
+	tds, err := d.listRevisions(context.Background(), arn("app:4"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, td := range tds {
+		names = append(names, taskDefinitionName(td))
+	}
+	if got := strings.Join(names, ","); got != "app:5,app:4,app:3,app:2" {
+		t.Errorf("unexpected revisions %s", got)
+	}
+}
+
+func TestFormatRevision(t *testing.T) {
+	registeredAt := time.Date(2020, 1, 2, 3, 4, 5, 0, timezone)
+	td := &ecs.TaskDefinition{
+		Family:       aws.String("app"),
+		Revision:     aws.Int64(3),
+		RegisteredAt: aws.Time(registeredAt),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1.2.3")},
+			{Name: aws.String("proxy"), Image: aws.String("nginx")},
+			{Name: aws.String("sidecar"), Image: aws.String("localhost:5000/sidecar@sha256:0123456789abcdef0123456789abcdef")},
+		},
+	}
+	if got, expected := formatRevision(td, true), "* app:3\t2020/01/02 03:04:05\tapp=v1.2.3,proxy=latest,sidecar=sha256:0123456789ab"; got != expected {
+		t.Errorf("unexpected format\n%q\n%q", got, expected)
+	}
+	if got := formatRevision(td, false); !strings.HasPrefix(got, "  app:3\t") {
+		t.Errorf("unexpected format %q", got)
+	}
+}
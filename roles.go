@@ -0,0 +1,134 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+const ecsTasksPrincipal = "ecs-tasks.amazonaws.com"
+
+// verifyTaskRoles verifies that taskRoleArn and executionRoleArn of the task definition exist
+// and their trust policies allow ecs-tasks.amazonaws.com to assume them.
+// Otherwise tasks fail to launch with "ECS was unable to assume the role".
+func (d *App) verifyTaskRoles(ctx context.Context, td *ecs.TaskDefinition) error {
+	var problems []string
+	for _, r := range []struct {
+		key  string
+		role *string
+	}{
+		{"taskRoleArn", td.TaskRoleArn},
+		{"executionRoleArn", td.ExecutionRoleArn},
+	} {
+		roleArn := aws.StringValue(r.role)
+		if roleArn == "" {
+			continue
+		}
+		a, err := arn.Parse(roleArn)
+		if err != nil || !strings.HasPrefix(a.Resource, "role/") {
+			problems = append(problems, fmt.Sprintf("%s %s is not an ARN of IAM role", r.key, roleArn))
+			continue
+		}
+		roleName := a.Resource[strings.LastIndex(a.Resource, "/")+1:]
+		out, err := d.iam.GetRoleWithContext(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+				problems = append(problems, fmt.Sprintf("%s %s does not exist", r.key, roleArn))
+				continue
+			}
+			d.Log("[WARNING] skip verifying", r.key, roleArn+": failed to get role:", err)
+			continue
+		}
+		ok, err := trustsPrincipal(aws.StringValue(out.Role.AssumeRolePolicyDocument), ecsTasksPrincipal)
+		if err != nil {
+			d.Log("[WARNING] skip verifying trust policy of", r.key, roleArn+":", err)
+			continue
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s %s does not allow %s to assume it in the trust policy", r.key, roleArn, ecsTasksPrincipal))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid roles of the task definition: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+type trustPolicy struct {
+	Statement []struct {
+		Effect    string
+		Action    stringOrSlice
+		Principal json.RawMessage
+	}
+}
+
+// stringOrSlice is a value of policy documents which is a string or a list of strings.
+type stringOrSlice []string
+
+func (s *stringOrSlice) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err == nil {
+		*s = []string{v}
+		return nil
+	}
+	var vs []string
+	if err := json.Unmarshal(b, &vs); err != nil {
+		return err
+	}
+	*s = vs
+	return nil
+}
+
+// trustsPrincipal reports whether the trust policy document (URL-encoded JSON, as returned by GetRole)
+// allows the service principal to sts:AssumeRole.
+func trustsPrincipal(document, service string) (bool, error) {
+	doc, err := url.QueryUnescape(document)
+	if err != nil {
+		return false, err
+	}
+	var p trustPolicy
+	if err := json.Unmarshal([]byte(doc), &p); err != nil {
+		return false, err
+	}
+	for _, st := range p.Statement {
+		if st.Effect != "Allow" || !matchAction(st.Action, "sts:AssumeRole") {
+			continue
+		}
+		var principal string
+		if err := json.Unmarshal(st.Principal, &principal); err == nil {
+			if principal == "*" {
+				return true, nil
+			}
+			continue
+		}
+		var principals struct {
+			Service stringOrSlice
+		}
+		if err := json.Unmarshal(st.Principal, &principals); err != nil {
+			return false, err
+		}
+		for _, s := range principals.Service {
+			if s == service {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func matchAction(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == "*" || strings.EqualFold(a, action) || strings.EqualFold(a, "sts:*") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package ecspresso
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+)
+
+type mockRolesIAM struct {
+	iamiface.IAMAPI
+	trustPolicies map[string]string
+}
+
+func (m *mockRolesIAM) GetRoleWithContext(_ aws.Context, in *iam.GetRoleInput, _ ...request.Option) (*iam.GetRoleOutput, error) {
+	doc, ok := m.trustPolicies[*in.RoleName]
+	if !ok {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "role not found", nil)
+	}
+	return &iam.GetRoleOutput{
+		Role: &iam.Role{
+			RoleName:                 in.RoleName,
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(doc)),
+		},
+	}, nil
+}
+
+func TestVerifyTaskRoles(t *testing.T) {
+	role := func(name string) *string {
+		return aws.String("arn:aws:iam::123456789012:role/" + name)
+	}
+	d := &App{
+		config: &Config{},
+		iam: &mockRolesIAM{
+			trustPolicies: map[string]string{
+				"ecsTaskRole":      `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ecs-tasks.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+				"ecsExecutionRole": `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["ec2.amazonaws.com","ecs-tasks.amazonaws.com"]},"Action":["sts:AssumeRole"]}]}`,
+				"ec2Role":          `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+				"denyRole":         `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Principal":{"Service":"ecs-tasks.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+			},
+		},
+	}
+	ctx := context.Background()
+	if err := d.verifyTaskRoles(ctx, &ecs.TaskDefinition{}); err != nil {
+		t.Error(err)
+	}
+	if err := d.verifyTaskRoles(ctx, &ecs.TaskDefinition{TaskRoleArn: role("ecsTaskRole"), ExecutionRoleArn: role("service-role/ecsExecutionRole")}); err != nil {
+		t.Error(err)
+	}
+
+	err := d.verifyTaskRoles(ctx, &ecs.TaskDefinition{TaskRoleArn: role("ec2Role"), ExecutionRoleArn: role("ecsExecRole")})
+	if err == nil {
+		t.Fatal("expected an error for invalid roles")
+	}
+	for _, s := range []string{
+		"taskRoleArn arn:aws:iam::123456789012:role/ec2Role does not allow ecs-tasks.amazonaws.com to assume it",
+		"executionRoleArn arn:aws:iam::123456789012:role/ecsExecRole does not exist",
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q does not contain %q", err, s)
+		}
+	}
+
+	if err := d.verifyTaskRoles(ctx, &ecs.TaskDefinition{TaskRoleArn: role("denyRole")}); err == nil {
+		t.Error("expected an error for the role denying ecs-tasks.amazonaws.com")
+	}
+}
@@ -1,8 +1,13 @@
 package ecspresso
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/pkg/errors"
 )
@@ -22,13 +27,21 @@ func (d *App) Rollback(opt RollbackOption) error {
 	}
 
 	currentArn := *sv.TaskDefinition
-	targetArn, err := d.FindRollbackTarget(ctx, currentArn)
+	var targetArn string
+	if aws.StringValue(opt.ToArn) != "" && aws.Int64Value(opt.ToRevision) > 0 {
+		return errors.New("--to-arn and --to-revision are mutually exclusive")
+	}
+	if aws.StringValue(opt.ToArn) != "" || aws.Int64Value(opt.ToRevision) > 0 {
+		targetArn, err = d.findRollbackTargetRevision(ctx, currentArn, opt)
+	} else {
+		targetArn, err = d.FindRollbackTarget(ctx, currentArn)
+	}
 	if err != nil {
 		return errors.Wrap(err, "failed to find rollback target")
 	}
 	d.Log("Rollbacking to", arnToName(targetArn))
 	if *opt.DryRun {
-		d.Log("DRY RUN OK")
+		d.ResultLog("DRY RUN OK")
 		return nil
 	}
 
@@ -43,7 +56,7 @@ func (d *App) Rollback(opt RollbackOption) error {
 	}
 
 	if *opt.NoWait {
-		d.Log("Service is rollbacked.")
+		d.ResultLog("Service is rollbacked.")
 		return nil
 	}
 
@@ -52,7 +65,7 @@ func (d *App) Rollback(opt RollbackOption) error {
 		return errors.Wrap(err, "failed to wait service stable")
 	}
 
-	d.Log("Service is stable now. Completed!")
+	d.ResultLog("Service is stable now. Completed!")
 
 	if *opt.DeregisterTaskDefinition {
 		d.Log("Deregistering rolled back task definition", arnToName(currentArn))
@@ -65,8 +78,51 @@ func (d *App) Rollback(opt RollbackOption) error {
 		if err != nil {
 			return errors.Wrap(err, "failed to deregister task definition")
 		}
-		d.Log(arnToName(currentArn), "was deregistered successfully")
+		d.ResultLog(arnToName(currentArn), "was deregistered successfully")
 	}
 
 	return nil
 }
+
+// findRollbackTargetRevision returns the task definition ARN specified by --to-arn or --to-revision.
+func (d *App) findRollbackTargetRevision(ctx context.Context, currentArn string, opt RollbackOption) (string, error) {
+	target := aws.StringValue(opt.ToArn)
+	if target == "" {
+		family, _ := parseTaskDefinitionName(arnToName(currentArn))
+		target = fmt.Sprintf("%s:%d", family, *opt.ToRevision)
+	}
+	td, err := d.DescribeTaskDefinition(ctx, target)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe task definition %s", target)
+	}
+	if err := validateRollbackTarget(currentArn, td, aws.BoolValue(opt.Force)); err != nil {
+		return "", err
+	}
+	return *td.TaskDefinitionArn, nil
+}
+
+func validateRollbackTarget(currentArn string, target *ecs.TaskDefinition, force bool) error {
+	family, revision := parseTaskDefinitionName(arnToName(currentArn))
+	name := taskDefinitionName(target)
+	switch {
+	case aws.StringValue(target.Status) != ecs.TaskDefinitionStatusActive:
+		return errors.Errorf("%s is not ACTIVE but %s", name, aws.StringValue(target.Status))
+	case *target.Family != family:
+		return errors.Errorf("%s does not belong to the family %s of the service", name, family)
+	case *target.Revision == revision:
+		return errors.Errorf("%s is the current revision", name)
+	case *target.Revision > revision && !force:
+		return errors.Errorf("%s is newer than the current revision %d. use --force to roll back to it", name, revision)
+	}
+	return nil
+}
+
+// parseTaskDefinitionName parses "family:revision".
+func parseTaskDefinitionName(name string) (string, int64) {
+	i := strings.LastIndex(name, ":")
+	if i == -1 {
+		return name, 0
+	}
+	rev, _ := strconv.ParseInt(name[i+1:], 10, 64)
+	return name[:i], rev
+}
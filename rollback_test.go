@@ -0,0 +1,43 @@
+package ecspresso
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestValidateRollbackTarget(t *testing.T) {
+	current := "arn:aws:ecs:us-east-1:123456789012:task-definition/app:10"
+	target := func(family string, rev int64, status string) *ecs.TaskDefinition {
+		return &ecs.TaskDefinition{
+			Family:   aws.String(family),
+			Revision: aws.Int64(rev),
+			Status:   aws.String(status),
+		}
+	}
+	for _, c := range []struct {
+		target *ecs.TaskDefinition
+		force  bool
+		err    string
+	}{
+		{target: target("app", 7, "ACTIVE")},
+		{target: target("app", 7, "INACTIVE"), err: "app:7 is not ACTIVE but INACTIVE"},
+		{target: target("other", 7, "ACTIVE"), err: "other:7 does not belong to the family app"},
+		{target: target("app", 10, "ACTIVE"), force: true, err: "app:10 is the current revision"},
+		{target: target("app", 11, "ACTIVE"), err: "app:11 is newer than the current revision 10"},
+		{target: target("app", 11, "ACTIVE"), force: true},
+	} {
+		err := validateRollbackTarget(current, c.target, c.force)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("unexpected error %s", err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("error must contain %s: %v", c.err, err)
+		}
+	}
+}
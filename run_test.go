@@ -0,0 +1,74 @@
+package ecspresso
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestTaskExitError(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app")},
+			{Name: aws.String("sidecar"), Essential: aws.Bool(false)},
+		},
+	}
+	essentials := essentialContainerNames(td)
+
+	for _, c := range []struct {
+		name string
+		task *ecs.Task
+		code int
+	}{
+		{
+			name: "success",
+			task: &ecs.Task{
+				StopCode: aws.String("EssentialContainerExited"),
+				Containers: []*ecs.Container{
+					{Name: aws.String("sidecar"), ExitCode: aws.Int64(143)},
+					{Name: aws.String("app"), ExitCode: aws.Int64(0)},
+				},
+			},
+			code: 0,
+		},
+		{
+			name: "nonzero exit",
+			task: &ecs.Task{
+				StopCode: aws.String("EssentialContainerExited"),
+				Containers: []*ecs.Container{
+					{Name: aws.String("sidecar"), ExitCode: aws.Int64(143)},
+					{Name: aws.String("app"), ExitCode: aws.Int64(3)},
+				},
+			},
+			code: 3,
+		},
+		{
+			name: "failed to start",
+			task: &ecs.Task{
+				StopCode:      aws.String("TaskFailedToStart"),
+				StoppedReason: aws.String("CannotPullContainerError"),
+				Containers: []*ecs.Container{
+					{Name: aws.String("app")},
+				},
+			},
+			code: 1,
+		},
+	} {
+		err := taskExitError(c.task, essentials)
+		if c.code == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error %s", c.name, err)
+			}
+			continue
+		}
+		e, ok := err.(*ExitCodeError)
+		if !ok {
+			t.Errorf("%s: unexpected error %#v", c.name, err)
+			continue
+		}
+		if e.Code != c.code {
+			t.Errorf("%s: unexpected exit code %d expected %d", c.name, e.Code, c.code)
+		}
+	}
+}
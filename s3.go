@@ -0,0 +1,51 @@
+package ecspresso
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+)
+
+// readDefinitionFile reads a definition file from a local path or s3://bucket/key URL.
+func (d *App) readDefinitionFile(path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "s3://") {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s read failed", path)
+		}
+		return b, nil
+	}
+
+	u, err := url.Parse(path)
+	if err != nil || u.Host == "" || strings.TrimPrefix(u.Path, "/") == "" {
+		return nil, fmt.Errorf("invalid S3 URL %s: must be s3://bucket/key", path)
+	}
+	d.DebugLog("get object from", path)
+	out, err := d.s3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(u.Host),
+		Key:    aws.String(strings.TrimPrefix(u.Path, "/")),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok {
+			switch awsErr.Code() {
+			case s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchBucket:
+				return nil, errors.Wrapf(err, "%s is not found", path)
+			case "AccessDenied":
+				return nil, errors.Wrapf(err, "access denied to %s. requires s3:GetObject", path)
+			}
+		}
+		return nil, errors.Wrapf(err, "failed to get %s", path)
+	}
+	defer out.Body.Close()
+	b, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s read failed", path)
+	}
+	return b, nil
+}
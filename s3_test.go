@@ -0,0 +1,58 @@
+package ecspresso
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	config "github.com/kayac/go-config"
+)
+
+type mockS3 struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func (m *mockS3) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	b, ok := m.objects[*in.Bucket+"/"+*in.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(b))}, nil
+}
+
+func TestLoadTaskDefinitionFromS3(t *testing.T) {
+	src, err := ioutil.ReadFile("tests/td.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("TAG", "v1")
+	app := &App{
+		Service: "test",
+		Cluster: "default",
+		config:  &Config{},
+		loader:  config.New(),
+		s3: &mockS3{
+			objects: map[string][]byte{"my-bucket/path/to/td.json": src},
+		},
+	}
+	td, err := app.LoadTaskDefinition("s3://my-bucket/path/to/td.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := *td.ContainerDefinitions[0].Image; image != "katsubushi/katsubushi:v1" {
+		t.Errorf("unexpected image %s", image)
+	}
+
+	if _, err := app.LoadTaskDefinition("s3://my-bucket/not-found.json"); err == nil || !strings.Contains(err.Error(), "is not found") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := app.LoadTaskDefinition("s3://my-bucket"); err == nil || !strings.Contains(err.Error(), "invalid S3 URL") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// secretPermissionChecks returns permissions required for the execution role to read secrets of the task definition.
+// A name of SSM parameter (not an ARN) is resolved in the partition, the region and the account of the execution role.
+func secretPermissionChecks(td *ecs.TaskDefinition, partition, region, accountID string) []permissionCheck {
+	var checks []permissionCheck
+	seen := make(map[string]bool)
+	for _, c := range td.ContainerDefinitions {
+		for _, s := range c.Secrets {
+			check := secretPermissionCheck(aws.StringValue(s.ValueFrom), partition, region, accountID)
+			if key := check.action + " " + check.resource; !seen[key] {
+				seen[key] = true
+				checks = append(checks, check)
+			}
+		}
+	}
+	return checks
+}
+
+func secretPermissionCheck(valueFrom, partition, region, accountID string) permissionCheck {
+	a, err := arn.Parse(valueFrom)
+	if err != nil {
+		// a name of SSM parameter
+		name := valueFrom
+		if !strings.HasPrefix(name, "/") {
+			name = "/" + name
+		}
+		return permissionCheck{
+			action:   "ssm:GetParameters",
+			resource: fmt.Sprintf("arn:%s:ssm:%s:%s:parameter%s", partition, region, accountID, name),
+		}
+	}
+	if a.Service == "secretsmanager" {
+		// arn:partition:secretsmanager:region:account:secret:name[:json-key:version-stage:version-id]
+		resource := valueFrom
+		if parts := strings.SplitN(valueFrom, ":", 8); len(parts) >= 7 {
+			resource = strings.Join(parts[:7], ":")
+		}
+		return permissionCheck{
+			action:   "secretsmanager:GetSecretValue",
+			resource: resource,
+		}
+	}
+	return permissionCheck{action: "ssm:GetParameters", resource: valueFrom}
+}
+
+// verifySecretsReadable verifies that the execution role of the task definition can read all of the secrets,
+// by simulating the policies of the role. Resource-based policies of secrets and KMS key policies are not considered,
+// so deploy and create only warn the error, and validate fails by it.
+// It returns an error when some secrets are not readable.
+func (d *App) verifySecretsReadable(ctx context.Context, td *ecs.TaskDefinition) error {
+	var n int
+	for _, c := range td.ContainerDefinitions {
+		n += len(c.Secrets)
+	}
+	if n == 0 {
+		return nil
+	}
+	role := aws.StringValue(td.ExecutionRoleArn)
+	if role == "" {
+		return fmt.Errorf("executionRoleArn is required to use secrets")
+	}
+	a, err := arn.Parse(role)
+	if err != nil {
+		return fmt.Errorf("executionRoleArn %s is not an ARN", role)
+	}
+	checks := secretPermissionChecks(td, a.Partition, d.region, a.AccountID)
+	d.Log("Verifying", len(checks), "secrets are readable by", role)
+	missing, err := d.simulatePermissions(ctx, role, checks)
+	if err != nil {
+		d.Log("[WARNING] skip verifying secrets: failed to simulate principal policy:", err)
+		return nil
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("execution role %s can not read secrets: %s", role, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// secretReferenceMismatches returns secrets of the task definition referring ARNs in other accounts as problems,
+// and ones in other regions as warnings (cross-region references may be intentional).
+// Secrets referred by names (not ARNs) are resolved in the region and the account, so they always match.
+func secretReferenceMismatches(td *ecs.TaskDefinition, region, accountID string) (problems, warnings []string) {
+	for _, c := range td.ContainerDefinitions {
+		for _, s := range c.Secrets {
+			a, err := arn.Parse(aws.StringValue(s.ValueFrom))
+			if err != nil {
+				continue
+			}
+			name := fmt.Sprintf("container %s: secret %s", aws.StringValue(c.Name), aws.StringValue(s.Name))
+			if accountID != "" && a.AccountID != accountID {
+				problems = append(problems, fmt.Sprintf("%s refers %s in account %s, not in %s", name, a.String(), a.AccountID, accountID))
+			}
+			if region != "" && a.Region != region {
+				warnings = append(warnings, fmt.Sprintf("%s refers %s in region %s, not in %s", name, a.String(), a.Region, region))
+			}
+		}
+	}
+	return problems, warnings
+}
+
+// verifySecretReferences verifies that ARNs of secrets refer the region and the account of the deploy target,
+// to catch ARNs copied from other environments. Secrets in other regions are only warned.
+func (d *App) verifySecretReferences(td *ecs.TaskDefinition) error {
+	problems, warnings := secretReferenceMismatches(td, d.region, d.accountID)
+	for _, w := range warnings {
+		d.Log("[WARNING]", w)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("secrets refer other accounts: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
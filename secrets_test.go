@@ -0,0 +1,152 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+)
+
+type mockSimulateIAM struct {
+	iamiface.IAMAPI
+	allowed    map[string]bool
+	principals []string
+}
+
+func (m *mockSimulateIAM) SimulatePrincipalPolicyWithContext(_ aws.Context, in *iam.SimulatePrincipalPolicyInput, _ ...request.Option) (*iam.SimulatePolicyResponse, error) {
+	m.principals = append(m.principals, *in.PolicySourceArn)
+	decision := iam.PolicyEvaluationDecisionTypeImplicitDeny
+	if m.allowed[*in.ActionNames[0]+" "+*in.ResourceArns[0]] {
+		decision = iam.PolicyEvaluationDecisionTypeAllowed
+	}
+	return &iam.SimulatePolicyResponse{
+		EvaluationResults: []*iam.EvaluationResult{
+			{EvalActionName: in.ActionNames[0], EvalResourceName: in.ResourceArns[0], EvalDecision: aws.String(decision)},
+		},
+	}, nil
+}
+
+func TestVerifySecretsReadable(t *testing.T) {
+	role := "arn:aws:iam::123456789012:role/ecsTaskExecutionRole"
+	td := &ecs.TaskDefinition{
+		ExecutionRoleArn: aws.String(role),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name: aws.String("app"),
+				Secrets: []*ecs.Secret{
+					{Name: aws.String("DB_PASSWORD"), ValueFrom: aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:db-AbCdEf:password::")},
+					{Name: aws.String("API_KEY"), ValueFrom: aws.String("/app/api_key")},
+				},
+			},
+			{
+				Name: aws.String("sidecar"),
+				Secrets: []*ecs.Secret{
+					{Name: aws.String("TOKEN"), ValueFrom: aws.String("arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/token")},
+				},
+			},
+		},
+	}
+	m := &mockSimulateIAM{allowed: map[string]bool{
+		"secretsmanager:GetSecretValue arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:db-AbCdEf": true,
+		"ssm:GetParameters arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/api_key":                   true,
+	}}
+	d := &App{config: &Config{Quiet: true}, iam: m, region: "ap-northeast-1"}
+	ctx := context.Background()
+
+	err := d.verifySecretsReadable(ctx, td)
+	if err == nil || !strings.Contains(err.Error(), "can not read secrets: ssm:GetParameters on arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/token (implicitDeny)") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if len(m.principals) != 3 || m.principals[0] != role {
+		t.Errorf("unexpected simulated principals %v", m.principals)
+	}
+
+	m.allowed["ssm:GetParameters arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/token"] = true
+	if err := d.verifySecretsReadable(ctx, td); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	td.ExecutionRoleArn = nil
+	if err := d.verifySecretsReadable(ctx, td); err == nil || !strings.Contains(err.Error(), "executionRoleArn is required") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// skipped without secrets
+	m.principals = nil
+	if err := d.verifySecretsReadable(ctx, &ecs.TaskDefinition{ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("app")}}}); err != nil || len(m.principals) != 0 {
+		t.Errorf("must be skipped without secrets: %v %v", err, m.principals)
+	}
+}
+
+func TestSecretPermissionCheck(t *testing.T) {
+	for valueFrom, want := range map[string]string{
+		"arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:db-AbCdEf":                      "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:db-AbCdEf",
+		"arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:db-AbCdEf:password:AWSCURRENT:": "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:db-AbCdEf",
+		// malformed ARNs must not panic
+		"arn:aws:secretsmanager:ap-northeast-1:123456789012:secret": "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret",
+		"arn:aws:secretsmanager:ap-northeast-1:123456789012:":       "arn:aws:secretsmanager:ap-northeast-1:123456789012:",
+	} {
+		c := secretPermissionCheck(valueFrom, "aws", "ap-northeast-1", "123456789012")
+		if c.action != "secretsmanager:GetSecretValue" || c.resource != want {
+			t.Errorf("unexpected check for %s: %#v", valueFrom, c)
+		}
+	}
+}
+
+func TestSecretPermissionCheckPartition(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		ExecutionRoleArn: aws.String("arn:aws-cn:iam::123456789012:role/ecsTaskExecutionRole"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name:    aws.String("app"),
+				Secrets: []*ecs.Secret{{Name: aws.String("API_KEY"), ValueFrom: aws.String("app/api_key")}},
+			},
+		},
+	}
+	m := &mockSimulateIAM{allowed: map[string]bool{
+		"ssm:GetParameters arn:aws-cn:ssm:cn-north-1:123456789012:parameter/app/api_key": true,
+	}}
+	d := &App{config: &Config{Quiet: true}, iam: m, region: "cn-north-1"}
+	if err := d.verifySecretsReadable(context.Background(), td); err != nil {
+		t.Errorf("names of SSM parameters must be resolved in the partition of the execution role: %s", err)
+	}
+}
+
+func TestVerifySecretReferences(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name: aws.String("app"),
+				Secrets: []*ecs.Secret{
+					{Name: aws.String("DB_PASSWORD"), ValueFrom: aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:db-AbCdEf")},
+					{Name: aws.String("API_KEY"), ValueFrom: aws.String("arn:aws:ssm:us-east-1:123456789012:parameter/api-key")},
+					{Name: aws.String("TOKEN"), ValueFrom: aws.String("token")},
+				},
+			},
+		},
+	}
+	problems, warnings := secretReferenceMismatches(td, "ap-northeast-1", "123456789012")
+	if len(problems) != 0 {
+		t.Errorf("unexpected problems %v", problems)
+	}
+	if len(warnings) != 1 || warnings[0] != "container app: secret API_KEY refers arn:aws:ssm:us-east-1:123456789012:parameter/api-key in region us-east-1, not in ap-northeast-1" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	d := &App{config: &Config{}, region: "ap-northeast-1", accountID: "210987654321"}
+	err := d.verifySecretReferences(td)
+	if err == nil || !strings.Contains(err.Error(), "container app: secret DB_PASSWORD refers arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:db-AbCdEf in account 123456789012, not in 210987654321") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// the account is unknown when the caller identity is not available
+	d.accountID = ""
+	if err := d.verifySecretReferences(td); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,45 @@
+package ecspresso
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// validateServiceConnect validates that each of services of the service connect configuration refers
+// a named port mapping in the task definition. It returns an error including all of the problems found.
+// The configuration is not validated when it is not defined or disabled.
+func validateServiceConnect(sc *ecs.ServiceConnectConfiguration, td *ecs.TaskDefinition) error {
+	if sc == nil || !aws.BoolValue(sc.Enabled) {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, c := range td.ContainerDefinitions {
+		for _, pm := range c.PortMappings {
+			if n := aws.StringValue(pm.Name); n != "" {
+				names[n] = true
+			}
+		}
+	}
+	var problems []string
+	for i, s := range sc.Services {
+		portName := aws.StringValue(s.PortName)
+		switch {
+		case portName == "":
+			problems = append(problems, fmt.Sprintf("services[%d]: portName is required", i))
+		case !names[portName]:
+			problems = append(problems, fmt.Sprintf("services[%d]: portName %s is not defined in portMappings of the task definition", i, portName))
+		}
+		for j, ca := range s.ClientAliases {
+			if p := aws.Int64Value(ca.Port); p < 1 || p > 65535 {
+				problems = append(problems, fmt.Sprintf("services[%d].clientAliases[%d]: port %d is out of range", i, j, p))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid serviceConnectConfiguration: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
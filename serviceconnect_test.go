@@ -0,0 +1,153 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+const testServiceConnectDefinition = `{
+  "launchType": "FARGATE",
+  "serviceConnectConfiguration": {
+    "enabled": true,
+    "namespace": "internal",
+    "services": [
+      {
+        "portName": "http",
+        "discoveryName": "web",
+        "clientAliases": [{"dnsName": "web.internal", "port": 80}]
+      }
+    ]
+  }
+}`
+
+func testServiceConnectTaskDefinition() *ecs.TaskDefinition {
+	return &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name: aws.String("web"),
+				PortMappings: []*ecs.PortMapping{
+					{Name: aws.String("http"), ContainerPort: aws.Int64(8080)},
+				},
+			},
+		},
+	}
+}
+
+func TestServiceConnectConfigurationJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sv.json")
+	if err := ioutil.WriteFile(path, []byte(testServiceConnectDefinition), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svd, err := app.LoadServiceDefinition(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired := desiredServiceAttributes(&ecs.Service{}, svd, nil)
+	b, err := marshalJSONSorted(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		ServiceConnectConfiguration struct {
+			Enabled   bool   `json:"enabled"`
+			Namespace string `json:"namespace"`
+			Services  []struct {
+				PortName      string `json:"portName"`
+				DiscoveryName string `json:"discoveryName"`
+				ClientAliases []struct {
+					DNSName string `json:"dnsName"`
+					Port    int64  `json:"port"`
+				} `json:"clientAliases"`
+			} `json:"services"`
+		} `json:"serviceConnectConfiguration"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	sc := v.ServiceConnectConfiguration
+	if !sc.Enabled || sc.Namespace != "internal" || len(sc.Services) != 1 {
+		t.Fatalf("unexpected serviceConnectConfiguration %s", string(b))
+	}
+	if s := sc.Services[0]; s.PortName != "http" || s.DiscoveryName != "web" ||
+		len(s.ClientAliases) != 1 || s.ClientAliases[0].DNSName != "web.internal" || s.ClientAliases[0].Port != 80 {
+		t.Errorf("unexpected services %s", string(b))
+	}
+
+	// omitted when unset
+	b, err = marshalJSONSorted(desiredServiceAttributes(&ecs.Service{}, &ecs.CreateServiceInput{}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "serviceConnectConfiguration") {
+		t.Errorf("serviceConnectConfiguration must be omitted: %s", string(b))
+	}
+}
+
+func TestValidateServiceConnect(t *testing.T) {
+	td := testServiceConnectTaskDefinition()
+	sc := &ecs.ServiceConnectConfiguration{
+		Enabled:   aws.Bool(true),
+		Namespace: aws.String("internal"),
+		Services: []*ecs.ServiceConnectService{
+			{
+				PortName: aws.String("http"),
+				ClientAliases: []*ecs.ServiceConnectClientAlias{
+					{DnsName: aws.String("web.internal"), Port: aws.Int64(80)},
+				},
+			},
+		},
+	}
+	if err := validateServiceConnect(sc, td); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if err := validateServiceConnect(nil, td); err != nil {
+		t.Errorf("unset configuration must be valid: %s", err)
+	}
+
+	sc.Services[0].PortName = aws.String("grpc")
+	sc.Services[0].ClientAliases[0].Port = aws.Int64(0)
+	sc.Services = append(sc.Services, &ecs.ServiceConnectService{})
+	err := validateServiceConnect(sc, td)
+	if err == nil {
+		t.Fatal("must be invalid")
+	}
+	for _, s := range []string{
+		"services[0]: portName grpc is not defined in portMappings of the task definition",
+		"services[0].clientAliases[0]: port 0 is out of range",
+		"services[1]: portName is required",
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error must contain %s: %s", s, err)
+		}
+	}
+
+	// disabled configuration is not validated
+	sc.Enabled = aws.Bool(false)
+	if err := validateServiceConnect(sc, td); err != nil {
+		t.Errorf("disabled configuration must be valid: %s", err)
+	}
+}
@@ -0,0 +1,209 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// isYAMLPath reports whether the definition file is written in YAML by its extension.
+func isYAMLPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// loadServiceDefinitionBytes loads the service definition in JSON or YAML (by the extension of path) into v.
+// Keys of YAML are the same as JSON (e.g. desiredCount).
+func (d *App) loadServiceDefinitionBytes(path string, src []byte, v interface{}) error {
+	if !isYAMLPath(path) {
+		if err := d.loader.LoadWithEnvJSONBytes(v, src); err != nil {
+			return errors.Wrapf(err, "%s load failed", path)
+		}
+		return nil
+	}
+	var y interface{}
+	if err := d.loader.LoadWithEnvBytes(&y, src); err != nil {
+		return errors.Wrapf(err, "%s load failed", path)
+	}
+	j, err := yamlToJSONValue(y)
+	if err != nil {
+		return errors.Wrapf(err, "%s load failed", path)
+	}
+	b, err := json.Marshal(j)
+	if err != nil {
+		return errors.Wrapf(err, "%s load failed", path)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errors.Wrapf(err, "%s load failed", path)
+	}
+	return nil
+}
+
+// yamlToJSONValue converts maps decoded by yaml.v2 to maps which can be encoded to JSON.
+func yamlToJSONValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		r := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("key %v must be a string", k)
+			}
+			j, err := yamlToJSONValue(value)
+			if err != nil {
+				return nil, err
+			}
+			r[key] = j
+		}
+		return r, nil
+	case []interface{}:
+		r := make([]interface{}, 0, len(v))
+		for _, value := range v {
+			j, err := yamlToJSONValue(value)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, j)
+		}
+		return r, nil
+	default:
+		return v, nil
+	}
+}
+
+// serviceAttributes returns the service-level settings of the live service which are updated by deploy.
+func serviceAttributes(sv *ecs.Service) *ecs.UpdateServiceInput {
+	var sc *ecs.ServiceConnectConfiguration
+	for _, dep := range sv.Deployments {
+		// service connect configuration is available only in deployments
+		if aws.StringValue(dep.Status) == "PRIMARY" {
+			sc = dep.ServiceConnectConfiguration
+		}
+	}
+	return &ecs.UpdateServiceInput{
+		DesiredCount:                  sv.DesiredCount,
+		DeploymentConfiguration:       sv.DeploymentConfiguration,
+		CapacityProviderStrategy:      sv.CapacityProviderStrategy,
+		NetworkConfiguration:          sv.NetworkConfiguration,
+		HealthCheckGracePeriodSeconds: sv.HealthCheckGracePeriodSeconds,
+		PlatformVersion:               sv.PlatformVersion,
+		PlacementConstraints:          sv.PlacementConstraints,
+		PlacementStrategy:             sv.PlacementStrategy,
+		LoadBalancers:                 sv.LoadBalancers,
+		ServiceConnectConfiguration:   sc,
+	}
+}
+
+// desiredServiceAttributes returns the service-level settings after deploy, in the same way as UpdateServiceAttributes.
+// Settings which are not defined in svd (or svd is nil) are left as the live service.
+func desiredServiceAttributes(sv *ecs.Service, svd *ecs.CreateServiceInput, count *int64) *ecs.UpdateServiceInput {
+	in := serviceAttributes(sv)
+	if count != nil {
+		in.DesiredCount = count
+	}
+	if svd == nil {
+		return in
+	}
+	if svd.DeploymentConfiguration != nil {
+		in.DeploymentConfiguration = svd.DeploymentConfiguration
+	}
+	if svd.CapacityProviderStrategy != nil {
+		in.CapacityProviderStrategy = svd.CapacityProviderStrategy
+	}
+	if svd.NetworkConfiguration != nil {
+		in.NetworkConfiguration = svd.NetworkConfiguration
+	}
+	if svd.HealthCheckGracePeriodSeconds != nil {
+		in.HealthCheckGracePeriodSeconds = svd.HealthCheckGracePeriodSeconds
+	}
+	if svd.PlatformVersion != nil {
+		in.PlatformVersion = svd.PlatformVersion
+	}
+	if svd.PlacementConstraints != nil {
+		in.PlacementConstraints = svd.PlacementConstraints
+	}
+	if svd.PlacementStrategy != nil {
+		in.PlacementStrategy = svd.PlacementStrategy
+	}
+	if svd.ServiceConnectConfiguration != nil {
+		in.ServiceConnectConfiguration = svd.ServiceConnectConfiguration
+	}
+	if dc := svd.DeploymentController; (dc == nil || aws.StringValue(dc.Type) == ecs.DeploymentControllerTypeEcs) && svd.LoadBalancers != nil {
+		in.LoadBalancers = svd.LoadBalancers
+	}
+	return in
+}
+
+// serviceAttributesFor returns the service-level settings of the service after deploy.
+// When useServiceDefinition is true, settings in the service definition are used if defined.
+func (d *App) serviceAttributesFor(sv *ecs.Service, useServiceDefinition bool) (*ecs.UpdateServiceInput, error) {
+	var svd *ecs.CreateServiceInput
+	if useServiceDefinition && d.config.ServiceDefinitionPath != "" {
+		var err error
+		if svd, err = d.LoadServiceDefinition(d.config.ServiceDefinitionPath); err != nil {
+			return nil, errors.Wrap(err, "failed to load service definition")
+		}
+	}
+	return desiredServiceAttributes(sv, svd, nil), nil
+}
+
+// normalizeServiceAttributes returns JSON of the service-level settings.
+// Subnets and security groups are sorted because their order is not meaningful.
+func normalizeServiceAttributes(in *ecs.UpdateServiceInput) ([]byte, error) {
+	c := *in
+	if nc := c.NetworkConfiguration; nc != nil && nc.AwsvpcConfiguration != nil {
+		vpc := *nc.AwsvpcConfiguration
+		vpc.Subnets = sortedStringPtrs(vpc.Subnets)
+		vpc.SecurityGroups = sortedStringPtrs(vpc.SecurityGroups)
+		c.NetworkConfiguration = &ecs.NetworkConfiguration{AwsvpcConfiguration: &vpc}
+	}
+	if len(c.LoadBalancers) > 0 {
+		lbs := make([]*ecs.LoadBalancer, len(c.LoadBalancers))
+		copy(lbs, c.LoadBalancers)
+		sort.SliceStable(lbs, func(i, j int) bool {
+			return aws.StringValue(lbs[i].TargetGroupArn)+aws.StringValue(lbs[i].ContainerName) <
+				aws.StringValue(lbs[j].TargetGroupArn)+aws.StringValue(lbs[j].ContainerName)
+		})
+		c.LoadBalancers = lbs
+	}
+	return marshalJSONSorted(&c)
+}
+
+func sortedStringPtrs(ss []*string) []*string {
+	r := make([]*string, len(ss))
+	copy(r, ss)
+	sort.SliceStable(r, func(i, j int) bool {
+		return aws.StringValue(r[i]) < aws.StringValue(r[j])
+	})
+	return r
+}
+
+// diffServiceAttributes returns a unified diff of normalized service-level settings.
+// It returns an empty string when there are no differences.
+func diffServiceAttributes(from, to *ecs.UpdateServiceInput, fromName, toName string) (string, error) {
+	a, err := normalizeServiceAttributes(from)
+	if err != nil {
+		return "", err
+	}
+	b, err := normalizeServiceAttributes(to)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(a)),
+		B:        difflib.SplitLines(string(b)),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+}
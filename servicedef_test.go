@@ -0,0 +1,129 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func testLiveService() *ecs.Service {
+	return &ecs.Service{
+		ServiceName:    aws.String("test"),
+		TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:1"),
+		DesiredCount:   aws.Int64(2),
+		LoadBalancers: []*ecs.LoadBalancer{
+			{
+				ContainerName:  aws.String("katsubushi"),
+				ContainerPort:  aws.Int64(11212),
+				TargetGroupArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:1111111111:targetgroup/test/12345678"),
+			},
+		},
+		NetworkConfiguration: &ecs.NetworkConfiguration{
+			AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+				// order of subnets and security groups is not meaningful
+				Subnets:        []*string{aws.String("subnet-abcdef01"), aws.String("subnet-abcdef00")},
+				SecurityGroups: []*string{aws.String("sg-23456789"), aws.String("sg-12345678")},
+				AssignPublicIp: aws.String("ENABLED"),
+			},
+		},
+	}
+}
+
+func TestDiffServiceAttributes(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svd, err := app.LoadServiceDefinition("tests/sv-lb.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv := testLiveService()
+	ds, err := diffServiceAttributes(serviceAttributes(sv), desiredServiceAttributes(sv, svd, nil), "live", "sv.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds != "" {
+		t.Errorf("unexpected diff\n%s", ds)
+	}
+
+	sv.NetworkConfiguration.AwsvpcConfiguration.AssignPublicIp = aws.String("DISABLED")
+	sv.DeploymentConfiguration = &ecs.DeploymentConfiguration{MinimumHealthyPercent: aws.Int64(50)}
+	ds, err = diffServiceAttributes(serviceAttributes(sv), desiredServiceAttributes(sv, svd, aws.Int64(5)), "live", "sv.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`-  "desiredCount": 2,`,
+		`+  "desiredCount": 5,`,
+		`-      "assignPublicIp": "DISABLED",`,
+		`+      "assignPublicIp": "ENABLED",`,
+	} {
+		if !strings.Contains(ds, s) {
+			t.Errorf("diff must contain %s\n%s", s, ds)
+		}
+	}
+	if strings.Contains(ds, `-    "minimumHealthyPercent"`) || strings.Contains(ds, `+    "minimumHealthyPercent"`) {
+		t.Errorf("settings not defined in service definition must be unchanged\n%s", ds)
+	}
+}
+
+func TestDryRunDiff(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:                "us-east-1",
+		Service:               "test",
+		Cluster:               "default",
+		Timeout:               time.Minute,
+		TaskDefinitionPath:    "tests/td.json",
+		ServiceDefinitionPath: "tests/sv-lb.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	td, err := app.LoadTaskDefinition("tests/td.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.ecs = &mockDiffECS{
+		taskDefinitions: map[string]*ecs.TaskDefinition{
+			"arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:1": td,
+		},
+	}
+	ctx := context.Background()
+	opt := DeployOption{UpdateService: aws.Bool(true)}
+
+	found, err := app.dryRunDiff(ctx, testLiveService(), td, nil, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("no differences must be found")
+	}
+
+	sv := testLiveService()
+	sv.LoadBalancers[0].ContainerPort = aws.Int64(8080)
+	if found, err = app.dryRunDiff(ctx, sv, td, nil, opt); err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Error("differences of load balancers must be found")
+	}
+
+	// the service definition is not applied without --update-service
+	if found, err = app.dryRunDiff(ctx, sv, td, nil, DeployOption{UpdateService: aws.Bool(false)}); err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("no differences must be found without --update-service")
+	}
+}
@@ -0,0 +1,60 @@
+package ecspresso
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+)
+
+// NewSession returns a new AWS session by region and endpoint_url in conf.
+// The session can be shared by multiple Apps by NewAppWithSession.
+func NewSession(conf *Config) (*session.Session, error) {
+	awsConfig := aws.Config{Region: aws.String(conf.Region)}
+	if conf.EndpointURL != "" {
+		// for LocalStack or other AWS compatible endpoints
+		awsConfig.Endpoint = aws.String(conf.EndpointURL)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create a new session")
+	}
+	return sess, nil
+}
+
+// appSession returns a session for the App based on the shared session.
+// region, endpoint_url and deploy_role_arn in conf are applied to a copy of the shared session,
+// so the shared session is never modified and its credentials (and the cache) are reused.
+func appSession(conf *Config, sess *session.Session) (*session.Session, error) {
+	var awsConfig aws.Config
+	changed := false
+	if conf.Region != "" && conf.Region != aws.StringValue(sess.Config.Region) {
+		awsConfig.Region = aws.String(conf.Region)
+		changed = true
+	}
+	if conf.EndpointURL != "" && conf.EndpointURL != aws.StringValue(sess.Config.Endpoint) {
+		awsConfig.Endpoint = aws.String(conf.EndpointURL)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+		changed = true
+	}
+	if changed {
+		sess = sess.Copy(&awsConfig)
+	}
+	if conf.DeployRoleARN != "" {
+		// each App has its own credentials of the role, assumed by the shared credentials
+		sess = sess.Copy(&aws.Config{
+			Credentials: stscreds.NewCredentials(sess, conf.DeployRoleARN),
+		})
+		if _, err := sess.Config.Credentials.Get(); err != nil {
+			return nil, errors.Wrapf(err, "failed to assume role %s", conf.DeployRoleARN)
+		}
+	}
+	// copy not to add handlers to the shared session
+	sess = sess.Copy()
+	sess.Handlers.Build.PushBackNamed(userAgentHandler(conf))
+	return sess, nil
+}
@@ -0,0 +1,49 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+const publishSNSTimeout = 30 * time.Second
+
+// publishDeployNotification publishes the deploy event to notify_sns_topic_arn in config.
+// This is best-effort, so any errors are logged but not returned.
+func (d *App) publishDeployNotification(outcome, tdArn string, err error) {
+	topic := d.config.NotifySNSTopicARN
+	if topic == "" {
+		return
+	}
+	in, jerr := deployNotificationPublishInput(topic, d.deployNotification(outcome, tdArn, err, time.Now()))
+	if jerr != nil {
+		d.Log("[WARNING] failed to build deploy notification:", jerr)
+		return
+	}
+	d.DebugLog("publishing deploy notification", in.String())
+
+	// the context of deploy may be already expired
+	ctx, cancel := context.WithTimeout(context.Background(), publishSNSTimeout)
+	defer cancel()
+	if _, err := d.sns.PublishWithContext(ctx, in); err != nil {
+		d.Log("[WARNING] failed to publish deploy notification:", err)
+	}
+}
+
+func deployNotificationPublishInput(topic string, n *DeployNotification) (*sns.PublishInput, error) {
+	msg, err := n.JSON()
+	if err != nil {
+		return nil, err
+	}
+	return &sns.PublishInput{
+		TopicArn: aws.String(topic),
+		Subject:  aws.String(fmt.Sprintf("ecspresso deploy %s: %s/%s", n.Outcome, n.Service, n.Cluster)),
+		Message:  aws.String(msg),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"outcome": {DataType: aws.String("String"), StringValue: aws.String(n.Outcome)},
+		},
+	}, nil
+}
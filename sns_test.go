@@ -0,0 +1,53 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestDeployNotificationPublishInput(t *testing.T) {
+	now := time.Date(2017, 11, 9, 23, 33, 23, 0, time.UTC)
+	d := &App{Service: "app", Cluster: "default", deployID: "0123"}
+	tdArn := "arn:aws:ecs:us-east-1:123456789012:task-definition/app:2"
+	for _, c := range []struct {
+		outcome string
+		err     error
+		expect  DeployNotification
+	}{
+		{
+			outcome: DeployOutcomeStarted,
+			expect:  DeployNotification{Service: "app", Cluster: "default", Revision: "app:2", Outcome: "STARTED", DeployID: "0123", Timestamp: now},
+		},
+		{
+			outcome: DeployOutcomeSucceeded,
+			expect:  DeployNotification{Service: "app", Cluster: "default", Revision: "app:2", Outcome: "SUCCEEDED", DeployID: "0123", Timestamp: now},
+		},
+		{
+			outcome: DeployOutcomeSucceeded,
+			err:     errors.New("deployment failed"),
+			expect:  DeployNotification{Service: "app", Cluster: "default", Revision: "app:2", Outcome: "FAILED", DeployID: "0123", Error: "deployment failed", Timestamp: now},
+		},
+	} {
+		in, err := deployNotificationPublishInput("arn:aws:sns:us-east-1:123456789012:deploy", d.deployNotification(c.outcome, tdArn, c.err, now))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n DeployNotification
+		if err := json.Unmarshal([]byte(aws.StringValue(in.Message)), &n); err != nil {
+			t.Fatal(err)
+		}
+		if n != c.expect {
+			t.Errorf("unexpected notification %#v", n)
+		}
+		if s := aws.StringValue(in.Subject); s != "ecspresso deploy "+c.expect.Outcome+": app/default" {
+			t.Errorf("unexpected subject %s", s)
+		}
+		if o := aws.StringValue(in.MessageAttributes["outcome"].StringValue); o != c.expect.Outcome {
+			t.Errorf("unexpected outcome attribute %s", o)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ecspresso
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+const (
+	capacityProviderFargate     = "FARGATE"
+	capacityProviderFargateSpot = "FARGATE_SPOT"
+
+	maxCapacityProviderBase   = 100000
+	maxCapacityProviderWeight = 1000
+)
+
+// SpotWithFallback is a shorthand of capacityProviderStrategy preferring FARGATE_SPOT with FARGATE as a fallback.
+// Base tasks run on FARGATE, and the rest are distributed by the weights.
+type SpotWithFallback struct {
+	Base           int64 `yaml:"base,omitempty"`
+	Weight         int64 `yaml:"weight,omitempty"`
+	OnDemandWeight int64 `yaml:"on_demand_weight,omitempty"`
+}
+
+func (s *SpotWithFallback) weight() int64 {
+	if s.Weight == 0 {
+		return 1
+	}
+	return s.Weight
+}
+
+// Validate validates the base and the weights are in the range accepted by ECS.
+func (s *SpotWithFallback) Validate() error {
+	if s.Base < 0 || s.Base > maxCapacityProviderBase {
+		return fmt.Errorf("base must be between 0 and %d", maxCapacityProviderBase)
+	}
+	for _, w := range []int64{s.weight(), s.OnDemandWeight} {
+		if w < 0 || w > maxCapacityProviderWeight {
+			return fmt.Errorf("weight and on_demand_weight must be between 0 and %d", maxCapacityProviderWeight)
+		}
+	}
+	return nil
+}
+
+// CapacityProviderStrategy expands s into capacityProviderStrategy.
+func (s *SpotWithFallback) CapacityProviderStrategy() []*ecs.CapacityProviderStrategyItem {
+	return []*ecs.CapacityProviderStrategyItem{
+		{
+			CapacityProvider: aws.String(capacityProviderFargate),
+			Base:             aws.Int64(s.Base),
+			Weight:           aws.Int64(s.OnDemandWeight),
+		},
+		{
+			CapacityProvider: aws.String(capacityProviderFargateSpot),
+			Weight:           aws.Int64(s.weight()),
+		},
+	}
+}
+
+// applySpotWithFallback sets capacityProviderStrategy of the service definition by spot_with_fallback in config.
+func (d *App) applySpotWithFallback(svd *ecs.CreateServiceInput) error {
+	s := d.config.SpotWithFallback
+	if s == nil {
+		return nil
+	}
+	if len(svd.CapacityProviderStrategy) > 0 || aws.StringValue(svd.LaunchType) != "" {
+		return fmt.Errorf("spot_with_fallback in config can not be used with capacityProviderStrategy or launchType of the service definition")
+	}
+	svd.CapacityProviderStrategy = s.CapacityProviderStrategy()
+	d.DebugLog("capacityProviderStrategy by spot_with_fallback:", svd.CapacityProviderStrategy)
+	return nil
+}
@@ -0,0 +1,74 @@
+package ecspresso
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestSpotWithFallback(t *testing.T) {
+	s := &SpotWithFallback{Base: 2, Weight: 3}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := MarshalJSON(s.CapacityProviderStrategy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[
+  {
+    "base": 2,
+    "capacityProvider": "FARGATE",
+    "weight": 0
+  },
+  {
+    "capacityProvider": "FARGATE_SPOT",
+    "weight": 3
+  }
+]
+`
+	if string(b) != expected {
+		t.Errorf("unexpected strategy %s", b)
+	}
+
+	// weight of FARGATE_SPOT is 1 by default
+	strategy := (&SpotWithFallback{OnDemandWeight: 1}).CapacityProviderStrategy()
+	if w := aws.Int64Value(strategy[1].Weight); w != 1 {
+		t.Errorf("unexpected weight %d", w)
+	}
+
+	for _, s := range []*SpotWithFallback{{Base: -1}, {Base: 100001}, {Weight: 1001}, {OnDemandWeight: -1}} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("%#v must be invalid", s)
+		}
+	}
+}
+
+func TestApplySpotWithFallback(t *testing.T) {
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+		SpotWithFallback:   &SpotWithFallback{Base: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svd := &ecs.CreateServiceInput{}
+	if err := app.applySpotWithFallback(svd); err != nil {
+		t.Fatal(err)
+	}
+	if len(svd.CapacityProviderStrategy) != 2 || aws.StringValue(svd.CapacityProviderStrategy[1].CapacityProvider) != "FARGATE_SPOT" {
+		t.Errorf("unexpected strategy %v", svd.CapacityProviderStrategy)
+	}
+
+	// tests/sv.json has launchType
+	if _, err := app.LoadServiceDefinition("tests/sv.json"); err == nil || !strings.Contains(err.Error(), "spot_with_fallback in config can not be used with") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
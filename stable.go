@@ -0,0 +1,153 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// isServiceStable is the same predicate as the ServicesStable waiter of ECS.
+func isServiceStable(sv *ecs.Service) bool {
+	return len(sv.Deployments) == 1 && aws.Int64Value(sv.RunningCount) == aws.Int64Value(sv.DesiredCount)
+}
+
+const (
+	waitStrategyRolloutState = "rolloutState"
+	waitStrategyCount        = "count"
+)
+
+// steadyStateEventMessage is a part of the event message emitted by ECS when the service has reached a steady state.
+const steadyStateEventMessage = "has reached a steady state"
+
+// hasSteadyStateEvent reports whether the service has the steady state event emitted after since.
+// Events emitted before since (e.g. by the previous deployment) are ignored.
+func hasSteadyStateEvent(sv *ecs.Service, since time.Time) bool {
+	for _, ev := range sv.Events {
+		if aws.TimeValue(ev.CreatedAt).After(since) && strings.Contains(aws.StringValue(ev.Message), steadyStateEventMessage) {
+			return true
+		}
+	}
+	return false
+}
+
+// rolloutStatus reports whether the deployment of the service is completed.
+// It uses rolloutState of the primary deployment if available, otherwise the count-based predicate
+// same as the ServicesStable waiter (for older services).
+func rolloutStatus(sv *ecs.Service) (done bool, strategy string, err error) {
+	for _, dep := range sv.Deployments {
+		if aws.StringValue(dep.Status) != "PRIMARY" || dep.RolloutState == nil {
+			continue
+		}
+		switch aws.StringValue(dep.RolloutState) {
+		case ecs.DeploymentRolloutStateCompleted:
+			return true, waitStrategyRolloutState, nil
+		case ecs.DeploymentRolloutStateFailed:
+			return false, waitStrategyRolloutState, errors.Wrapf(ErrDeploymentFailed, "deployment %s: %s", aws.StringValue(dep.Id), aws.StringValue(dep.RolloutStateReason))
+		default:
+			return false, waitStrategyRolloutState, nil
+		}
+	}
+	return isServiceStable(sv), waitStrategyCount, nil
+}
+
+// waitServiceRollout waits until rolloutStatus reports done, or the deployment failed.
+func (d *App) waitServiceRollout(ctx context.Context, after func(time.Duration) <-chan time.Time) error {
+	b := newPollBackoff(d.config.PollIntervalMin, d.config.PollIntervalMax)
+	var strategy string
+	for {
+		sv, err := d.describeService(ctx)
+		if err != nil {
+			return err
+		}
+		done, s, err := rolloutStatus(sv)
+		if s != strategy {
+			switch s {
+			case waitStrategyRolloutState:
+				d.Log("Waiting for rolloutState of the primary deployment to be COMPLETED")
+			case waitStrategyCount:
+				d.Log("rolloutState is not available. Waiting for running count to reach desired count")
+			}
+			strategy = s
+		}
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-after(b.next(false)):
+		}
+	}
+}
+
+// waitSteadyStateEvent waits until the service has the steady state event emitted after startedAt,
+// or the deployment failed.
+func (d *App) waitSteadyStateEvent(ctx context.Context, startedAt time.Time, after func(time.Duration) <-chan time.Time) error {
+	d.Log("Waiting for the steady state event of the service")
+	b := newPollBackoff(d.config.PollIntervalMin, d.config.PollIntervalMax)
+	for {
+		sv, err := d.describeService(ctx)
+		if err != nil {
+			return err
+		}
+		if _, _, err := rolloutStatus(sv); err != nil {
+			return err
+		}
+		if hasSteadyStateEvent(sv, startedAt) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-after(b.next(false)):
+		}
+	}
+}
+
+// waitMinStableDuration waits until the service is continuously stable for min_stable_duration.
+func (d *App) waitMinStableDuration(ctx context.Context) error {
+	d.Log("Service is stable. Confirming it stays stable for", d.config.MinStableDuration)
+	return waitContinuouslyStable(ctx, d.config.MinStableDuration, d.config.PollIntervalMin, time.Now, time.After,
+		func() (bool, error) {
+			sv, err := d.describeService(ctx)
+			if err != nil {
+				return false, err
+			}
+			stable := isServiceStable(sv)
+			if !stable {
+				d.Log("Service became unstable. Restarting the stable duration timer")
+			}
+			return stable, nil
+		},
+	)
+}
+
+// waitContinuouslyStable returns nil after check reports stable continuously for duration.
+// The timer is restarted when check reports unstable.
+func waitContinuouslyStable(ctx context.Context, duration, interval time.Duration, now func() time.Time, after func(time.Duration) <-chan time.Time, check func() (bool, error)) error {
+	stableSince := now()
+	for {
+		if !stableSince.IsZero() && now().Sub(stableSince) >= duration {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "service did not stay stable")
+		case <-after(interval):
+		}
+		stable, err := check()
+		if err != nil {
+			return err
+		}
+		switch {
+		case !stable:
+			stableSince = time.Time{}
+		case stableSince.IsZero():
+			stableSince = now()
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package ecspresso
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/pkg/errors"
+)
+
+func TestWaitContinuouslyStable(t *testing.T) {
+	// stable, regress, then re-stabilize
+	results := []bool{true, false, true, true, true, true, true}
+	var clock time.Time
+	now := func() time.Time { return clock }
+	after := func(d time.Duration) <-chan time.Time {
+		clock = clock.Add(d)
+		ch := make(chan time.Time, 1)
+		ch <- clock
+		return ch
+	}
+	var checks int
+	check := func() (bool, error) {
+		if checks >= len(results) {
+			t.Fatal("too many checks")
+		}
+		r := results[checks]
+		checks++
+		return r, nil
+	}
+	clock = time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	err := waitContinuouslyStable(context.Background(), 30*time.Second, 10*time.Second, now, after, check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the timer is restarted at the 3rd check, and 30s requires 3 more checks
+	if checks != 6 {
+		t.Errorf("unexpected checks %d", checks)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	blocked := func(time.Duration) <-chan time.Time { return nil }
+	if err := waitContinuouslyStable(ctx, 30*time.Second, 10*time.Second, now, blocked, check); err == nil {
+		t.Error("canceled context must be an error")
+	}
+}
+
+func testRolloutService(rolloutState *string, deployments int) *ecs.Service {
+	sv := &ecs.Service{
+		DesiredCount: aws.Int64(2),
+		RunningCount: aws.Int64(2),
+		Deployments: []*ecs.Deployment{
+			{Id: aws.String("ecs-svc/2"), Status: aws.String("PRIMARY"), RolloutState: rolloutState, RolloutStateReason: aws.String("reason")},
+		},
+	}
+	for i := 1; i < deployments; i++ {
+		sv.Deployments = append(sv.Deployments, &ecs.Deployment{Id: aws.String("ecs-svc/1"), Status: aws.String("ACTIVE")})
+	}
+	return sv
+}
+
+func TestRolloutStatus(t *testing.T) {
+	cases := []struct {
+		sv       *ecs.Service
+		done     bool
+		strategy string
+		failed   bool
+	}{
+		{testRolloutService(aws.String("COMPLETED"), 2), true, waitStrategyRolloutState, false},
+		{testRolloutService(aws.String("IN_PROGRESS"), 1), false, waitStrategyRolloutState, false},
+		{testRolloutService(aws.String("FAILED"), 2), false, waitStrategyRolloutState, true},
+		{testRolloutService(nil, 1), true, waitStrategyCount, false},
+		{testRolloutService(nil, 2), false, waitStrategyCount, false},
+	}
+	for i, c := range cases {
+		done, strategy, err := rolloutStatus(c.sv)
+		if done != c.done || strategy != c.strategy {
+			t.Errorf("case %d: unexpected result %t %s", i, done, strategy)
+		}
+		if c.failed {
+			if errors.Cause(err) != ErrDeploymentFailed {
+				t.Errorf("case %d: unexpected error %v", i, err)
+			}
+		} else if err != nil {
+			t.Errorf("case %d: unexpected error %s", i, err)
+		}
+	}
+}
+
+type mockRolloutECS struct {
+	ecsiface.ECSAPI
+	services []*ecs.Service
+	calls    int
+}
+
+func (m *mockRolloutECS) DescribeServicesWithContext(_ aws.Context, _ *ecs.DescribeServicesInput, _ ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	sv := m.services[m.calls]
+	if m.calls < len(m.services)-1 {
+		m.calls++
+	}
+	return &ecs.DescribeServicesOutput{Services: []*ecs.Service{sv}}, nil
+}
+
+func TestWaitServiceRollout(t *testing.T) {
+	after := func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}
+	d := &App{config: &Config{Quiet: true}}
+	m := &mockRolloutECS{services: []*ecs.Service{
+		testRolloutService(aws.String("IN_PROGRESS"), 2),
+		testRolloutService(aws.String("IN_PROGRESS"), 2),
+		testRolloutService(aws.String("COMPLETED"), 2),
+	}}
+	d.ecs = m
+	if err := d.waitServiceRollout(context.Background(), after); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if m.calls != 2 {
+		t.Errorf("unexpected calls %d", m.calls)
+	}
+
+	d.ecs = &mockRolloutECS{services: []*ecs.Service{
+		testRolloutService(aws.String("IN_PROGRESS"), 2),
+		testRolloutService(aws.String("FAILED"), 2),
+	}}
+	if err := d.waitServiceRollout(context.Background(), after); errors.Cause(err) != ErrDeploymentFailed {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// never completes
+	d.ecs = &mockRolloutECS{services: []*ecs.Service{testRolloutService(nil, 2)}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	never := func(time.Duration) <-chan time.Time { return nil }
+	if err := d.waitServiceRollout(ctx, never); err == nil {
+		t.Error("must fail when ctx is done")
+	}
+}
+
+func TestWaitSteadyStateEvent(t *testing.T) {
+	after := func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}
+	startedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := func(msg string, createdAt time.Time) *ecs.ServiceEvent {
+		return &ecs.ServiceEvent{Message: aws.String(msg), CreatedAt: aws.Time(createdAt)}
+	}
+	stale := event("(service test) has reached a steady state.", startedAt.Add(-time.Hour))
+	started := event("(service test) has started 2 tasks: (task 1) (task 2).", startedAt.Add(time.Second))
+	steady := event("(service test) has reached a steady state.", startedAt.Add(time.Minute))
+
+	services := []*ecs.Service{
+		testRolloutService(aws.String("IN_PROGRESS"), 2),
+		testRolloutService(aws.String("IN_PROGRESS"), 2),
+		testRolloutService(aws.String("COMPLETED"), 1),
+		testRolloutService(aws.String("COMPLETED"), 1),
+	}
+	// events are ordered newest first
+	services[0].Events = []*ecs.ServiceEvent{stale}
+	services[1].Events = []*ecs.ServiceEvent{started, stale}
+	// rolloutState is completed before the steady state event
+	services[2].Events = []*ecs.ServiceEvent{started, stale}
+	services[3].Events = []*ecs.ServiceEvent{steady, started, stale}
+
+	d := &App{config: &Config{Quiet: true}}
+	m := &mockRolloutECS{services: services}
+	d.ecs = m
+	if err := d.waitSteadyStateEvent(context.Background(), startedAt, after); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if m.calls != 3 {
+		t.Errorf("must succeed only after the new steady state event. calls %d", m.calls)
+	}
+
+	if hasSteadyStateEvent(services[1], startedAt) {
+		t.Error("stale steady state event must be ignored")
+	}
+
+	d.ecs = &mockRolloutECS{services: []*ecs.Service{
+		testRolloutService(aws.String("FAILED"), 2),
+	}}
+	if err := d.waitSteadyStateEvent(context.Background(), startedAt, after); errors.Cause(err) != ErrDeploymentFailed {
+		t.Errorf("unexpected error %v", err)
+	}
+}
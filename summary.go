@@ -0,0 +1,79 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const describeSummaryTimeout = 10 * time.Second
+
+// DeploySummary represents a summary of a deploy.
+type DeploySummary struct {
+	Service      string
+	Cluster      string
+	Family       string
+	OldRevision  string
+	NewRevision  string
+	DesiredCount int64
+	RunningCount int64
+	Duration     time.Duration
+	Error        error
+}
+
+func (s *DeploySummary) Outcome() string {
+	if s.Error != nil {
+		return "FAILED"
+	}
+	return "SUCCEEDED"
+}
+
+func (d *App) printDeploySummary(startedAt time.Time, oldArn, newArn string, err error) {
+	s := &DeploySummary{
+		Service:     d.Service,
+		Cluster:     d.Cluster,
+		OldRevision: arnToName(oldArn),
+		NewRevision: arnToName(oldArn),
+		Duration:    time.Since(startedAt),
+		Error:       err,
+	}
+	if newArn != "" {
+		s.NewRevision = arnToName(newArn)
+	}
+	if i := strings.LastIndex(s.NewRevision, ":"); i > 0 {
+		s.Family = s.NewRevision[:i]
+	}
+
+	// the context of deploy may be already expired
+	ctx, cancel := context.WithTimeout(context.Background(), describeSummaryTimeout)
+	defer cancel()
+	if sv, err := d.describeService(ctx); err != nil {
+		d.DebugLog("failed to describe service for summary", err)
+	} else {
+		s.DesiredCount = aws.Int64Value(sv.DesiredCount)
+		s.RunningCount = aws.Int64Value(sv.RunningCount)
+	}
+	fmt.Fprint(d.stdout(), formatDeploySummary(s))
+}
+
+func formatDeploySummary(s *DeploySummary) string {
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Deploy summary:")
+	fmt.Fprintf(w, "%sService:\t%s/%s\n", spcIndent, s.Service, s.Cluster)
+	fmt.Fprintf(w, "%sFamily:\t%s\n", spcIndent, s.Family)
+	fmt.Fprintf(w, "%sRevision:\t%s -> %s\n", spcIndent, s.OldRevision, s.NewRevision)
+	fmt.Fprintf(w, "%sTasks:\tdesired:%d running:%d\n", spcIndent, s.DesiredCount, s.RunningCount)
+	fmt.Fprintf(w, "%sDuration:\t%s\n", spcIndent, s.Duration.Round(time.Second))
+	fmt.Fprintf(w, "%sOutcome:\t%s\n", spcIndent, s.Outcome())
+	if s.Error != nil {
+		fmt.Fprintf(w, "%sError:\t%s\n", spcIndent, s.Error)
+	}
+	w.Flush()
+	return b.String()
+}
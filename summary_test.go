@@ -0,0 +1,45 @@
+package ecspresso
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFormatDeploySummary(t *testing.T) {
+	s := &DeploySummary{
+		Service:      "app",
+		Cluster:      "default",
+		Family:       "app",
+		OldRevision:  "app:2",
+		NewRevision:  "app:3",
+		DesiredCount: 2,
+		RunningCount: 2,
+		Duration:     3*time.Minute + 21*time.Second + 300*time.Millisecond,
+	}
+	expected := `Deploy summary:
+  Service:   app/default
+  Family:    app
+  Revision:  app:2 -> app:3
+  Tasks:     desired:2 running:2
+  Duration:  3m21s
+  Outcome:   SUCCEEDED
+`
+	if got := formatDeploySummary(s); got != expected {
+		t.Errorf("unexpected summary\n%s\nexpected\n%s", got, expected)
+	}
+
+	s.Error = errors.New("failed to wait service stable")
+	expected = `Deploy summary:
+  Service:   app/default
+  Family:    app
+  Revision:  app:2 -> app:3
+  Tasks:     desired:2 running:2
+  Duration:  3m21s
+  Outcome:   FAILED
+  Error:     failed to wait service stable
+`
+	if got := formatDeploySummary(s); got != expected {
+		t.Errorf("unexpected summary\n%s\nexpected\n%s", got, expected)
+	}
+}
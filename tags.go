@@ -0,0 +1,96 @@
+package ecspresso
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+const maxTags = 50
+
+// validateTags validates tags against the constraints of ECS API.
+func validateTags(tags map[string]string) error {
+	v := &validation{}
+	if len(tags) > maxTags {
+		v.errorf("up to %d tags are allowed", maxTags)
+	}
+	for _, key := range sortedTagKeys(tags) {
+		if n := utf8.RuneCountInString(key); n == 0 || n > 128 {
+			v.errorf("tag key %q must be 1-128 characters", key)
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			v.errorf("tag key %q must not start with aws:", key)
+		}
+		if n := utf8.RuneCountInString(tags[key]); n > 256 {
+			v.errorf("tag value of %s must be up to 256 characters", key)
+		}
+	}
+	if len(v.errors) > 0 {
+		return fmt.Errorf("invalid tags: %s", strings.Join(v.errors, ", "))
+	}
+	return nil
+}
+
+// mergeTags returns tags merged over defaults. Tags override defaults of the same keys,
+// and empty-valued tags are dropped after merged (so an empty tag removes the default).
+func mergeTags(defaults, tags map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults)+len(tags))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range tags {
+		merged[key] = value
+	}
+	for key, value := range merged {
+		if value == "" {
+			delete(merged, key)
+		}
+	}
+	return merged
+}
+
+// tags returns default_tags merged with tags in config.
+func (d *App) tags() map[string]string {
+	return mergeTags(d.config.DefaultTags, d.config.Tags)
+}
+
+// mergeServiceTags returns tags of the service definition followed by tags in config
+// which are not defined in the service definition.
+func mergeServiceTags(svdTags []*ecs.Tag, tags map[string]string) []*ecs.Tag {
+	defined := make(map[string]bool, len(svdTags))
+	for _, tag := range svdTags {
+		defined[aws.StringValue(tag.Key)] = true
+	}
+	merged := svdTags
+	for _, tag := range ecsTags(tags) {
+		if !defined[aws.StringValue(tag.Key)] {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+// ecsTags converts tags to ECS tags sorted by keys. Empty-valued tags are dropped.
+func ecsTags(tags map[string]string) []*ecs.Tag {
+	var ts []*ecs.Tag
+	for _, key := range sortedTagKeys(tags) {
+		if tags[key] == "" {
+			continue
+		}
+		ts = append(ts, &ecs.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return ts
+}
+
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockRunTaskECS struct {
+	ecsiface.ECSAPI
+	in *ecs.RunTaskInput
+}
+
+func (m *mockRunTaskECS) RunTaskWithContext(_ aws.Context, in *ecs.RunTaskInput, _ ...request.Option) (*ecs.RunTaskOutput, error) {
+	m.in = in
+	return &ecs.RunTaskOutput{
+		Tasks: []*ecs.Task{{TaskArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task/default/0123")}},
+	}, nil
+}
+
+func TestRunTaskTags(t *testing.T) {
+	m := &mockRunTaskECS{}
+	app := &App{
+		Service: "test",
+		Cluster: "default",
+		ecs:     m,
+		config: &Config{
+			Tags: map[string]string{"Owner": "team-a", "CostCenter": "1234", "Empty": ""},
+		},
+	}
+	_, err := app.RunTask(context.Background(), "app:1", &ecs.Service{}, &ecs.TaskOverride{}, RunOption{
+		Count:         aws.Int64(1),
+		StartedBy:     aws.String("ecspresso/test"),
+		PropagateTags: aws.String("TASK_DEFINITION"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for _, tag := range m.in.Tags {
+		tags = append(tags, *tag.Key+"="+*tag.Value)
+	}
+	if got := strings.Join(tags, ","); got != "CostCenter=1234,Owner=team-a" {
+		t.Errorf("unexpected tags %s", got)
+	}
+	if aws.StringValue(m.in.PropagateTags) != "TASK_DEFINITION" {
+		t.Errorf("unexpected propagateTags %v", m.in.PropagateTags)
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	if err := validateTags(map[string]string{"Owner": "team-a"}); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	err := validateTags(map[string]string{
+		"aws:owner":              "team-a",
+		strings.Repeat("k", 129): "v",
+		"Description":            strings.Repeat("v", 257),
+	})
+	if err == nil {
+		t.Fatal("invalid tags must be an error")
+	}
+	for _, s := range []string{"must not start with aws:", "must be 1-128 characters", "tag value of Description must be up to 256 characters"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error must contain %s: %s", s, err)
+		}
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	merged := mergeTags(
+		map[string]string{"Team": "platform", "CostCenter": "1234", "Env": "dev", "Empty": ""},
+		map[string]string{"Env": "prod", "CostCenter": "", "GitSHA": "abcdef"},
+	)
+	var tags []string
+	for _, tag := range ecsTags(merged) {
+		tags = append(tags, *tag.Key+"="+*tag.Value)
+	}
+	// tags override defaults, and empty values remove them
+	if got := strings.Join(tags, ","); got != "Env=prod,GitSHA=abcdef,Team=platform" {
+		t.Errorf("unexpected tags %s", got)
+	}
+	if merged := mergeTags(nil, nil); len(merged) != 0 {
+		t.Errorf("unexpected tags %v", merged)
+	}
+
+	svdTags := mergeServiceTags(
+		[]*ecs.Tag{{Key: aws.String("Team"), Value: aws.String("web")}},
+		map[string]string{"Team": "platform", "GitSHA": "abcdef"},
+	)
+	tags = nil
+	for _, tag := range svdTags {
+		tags = append(tags, *tag.Key+"="+*tag.Value)
+	}
+	if got := strings.Join(tags, ","); got != "Team=web,GitSHA=abcdef" {
+		t.Errorf("unexpected service tags %s", got)
+	}
+}
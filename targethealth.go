@@ -0,0 +1,181 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/pkg/errors"
+)
+
+// taskTargets returns targets ("{ip or instance id}:{port}") of the tasks for the load balancer.
+// Tasks using awsvpc network mode are registered by IP, and others by the container instance and the host port.
+func taskTargets(lb *ecs.LoadBalancer, tasks []*ecs.Task, instanceIDs map[string]string) []string {
+	var targets []string
+	for _, task := range tasks {
+		if ip := taskPrivateIP(task); ip != "" {
+			targets = append(targets, fmt.Sprintf("%s:%d", ip, aws.Int64Value(lb.ContainerPort)))
+			continue
+		}
+		id := instanceIDs[aws.StringValue(task.ContainerInstanceArn)]
+		if id == "" {
+			continue
+		}
+		for _, c := range task.Containers {
+			if aws.StringValue(c.Name) != aws.StringValue(lb.ContainerName) {
+				continue
+			}
+			for _, nb := range c.NetworkBindings {
+				if aws.Int64Value(nb.ContainerPort) == aws.Int64Value(lb.ContainerPort) {
+					targets = append(targets, fmt.Sprintf("%s:%d", id, aws.Int64Value(nb.HostPort)))
+				}
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+func taskPrivateIP(task *ecs.Task) string {
+	for _, a := range task.Attachments {
+		if aws.StringValue(a.Type) != "ElasticNetworkInterface" {
+			continue
+		}
+		for _, kv := range a.Details {
+			if aws.StringValue(kv.Name) == "privateIPv4Address" {
+				return aws.StringValue(kv.Value)
+			}
+		}
+	}
+	return ""
+}
+
+// unhealthyTargets returns descriptions of the targets which are not healthy in the target group.
+// Targets which are not registered yet are also unhealthy.
+func unhealthyTargets(targets []string, descs []*elbv2.TargetHealthDescription) []string {
+	health := make(map[string]*elbv2.TargetHealth, len(descs))
+	for _, desc := range descs {
+		if desc.Target == nil {
+			continue
+		}
+		health[fmt.Sprintf("%s:%d", aws.StringValue(desc.Target.Id), aws.Int64Value(desc.Target.Port))] = desc.TargetHealth
+	}
+	var unhealthy []string
+	for _, t := range targets {
+		h, ok := health[t]
+		switch {
+		case !ok || h == nil:
+			unhealthy = append(unhealthy, t+" not registered")
+		case aws.StringValue(h.State) != elbv2.TargetHealthStateEnumHealthy:
+			s := t + " " + aws.StringValue(h.State)
+			if r := aws.StringValue(h.Reason); r != "" {
+				s += " (" + r + ")"
+			}
+			unhealthy = append(unhealthy, s)
+		}
+	}
+	return unhealthy
+}
+
+// waitTargetsHealthy waits until all targets of the running tasks of the task definition of the service
+// are healthy in the target groups of the service.
+func (d *App) waitTargetsHealthy(ctx context.Context, after func(time.Duration) <-chan time.Time) error {
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	var lbs []*ecs.LoadBalancer
+	for _, lb := range sv.LoadBalancers {
+		if aws.StringValue(lb.TargetGroupArn) != "" {
+			lbs = append(lbs, lb)
+		}
+	}
+	if len(lbs) == 0 {
+		d.Log("No target groups are attached to the service. Skipping target health check")
+		return nil
+	}
+	d.Log("Waiting for targets of the new tasks to be healthy")
+
+	tdArn := aws.StringValue(sv.TaskDefinition)
+	b := newPollBackoff(d.config.PollIntervalMin, d.config.PollIntervalMax)
+	var unhealthy []string
+	for {
+		unhealthy, err = d.describeUnhealthyTargets(ctx, lbs, tdArn)
+		if err != nil {
+			return err
+		}
+		if len(unhealthy) == 0 {
+			d.Log("All targets of the new tasks are healthy")
+			return nil
+		}
+		d.DebugLog("unhealthy targets:", strings.Join(unhealthy, ", "))
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ErrWaitTimeout, "targets are not healthy: %s", strings.Join(unhealthy, ", "))
+		case <-after(b.next(false)):
+		}
+	}
+}
+
+func (d *App) describeUnhealthyTargets(ctx context.Context, lbs []*ecs.LoadBalancer, tdArn string) ([]string, error) {
+	all, err := d.listTasks(ctx, ecs.DesiredStatusRunning, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tasks")
+	}
+	var tasks []*ecs.Task
+	var instanceArns []*string
+	for _, task := range all {
+		if aws.StringValue(task.TaskDefinitionArn) != tdArn || aws.StringValue(task.LastStatus) != ecs.DesiredStatusRunning {
+			continue
+		}
+		tasks = append(tasks, task)
+		if task.ContainerInstanceArn != nil && taskPrivateIP(task) == "" {
+			instanceArns = append(instanceArns, task.ContainerInstanceArn)
+		}
+	}
+	if len(tasks) == 0 {
+		return []string{"no running tasks of " + arnToName(tdArn)}, nil
+	}
+	instanceIDs := make(map[string]string)
+	if len(instanceArns) > 0 {
+		out, err := d.ecs.DescribeContainerInstancesWithContext(ctx, &ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(d.Cluster),
+			ContainerInstances: instanceArns,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe container instances")
+		}
+		for _, ci := range out.ContainerInstances {
+			instanceIDs[aws.StringValue(ci.ContainerInstanceArn)] = aws.StringValue(ci.Ec2InstanceId)
+		}
+	}
+
+	var unhealthy []string
+	for _, lb := range lbs {
+		tg := aws.StringValue(lb.TargetGroupArn)
+		out, err := d.elbv2.DescribeTargetHealthWithContext(ctx, &elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: aws.String(tg),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to describe target health of %s", tg)
+		}
+		for _, u := range unhealthyTargets(taskTargets(lb, tasks, instanceIDs), out.TargetHealthDescriptions) {
+			unhealthy = append(unhealthy, arnToTargetGroupName(tg)+" "+u)
+		}
+	}
+	return unhealthy, nil
+}
+
+// arnToTargetGroupName returns the name of the target group from ARN (...:targetgroup/{name}/{id}).
+func arnToTargetGroupName(s string) string {
+	ns := strings.Split(s, "/")
+	if len(ns) < 3 {
+		return s
+	}
+	return ns[len(ns)-2]
+}
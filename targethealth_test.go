@@ -0,0 +1,191 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+)
+
+const testTargetGroupArn = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/app/0123456789abcdef"
+
+type mockTargetHealthECS struct {
+	ecsiface.ECSAPI
+	tasks []*ecs.Task
+}
+
+func (m *mockTargetHealthECS) DescribeServicesWithContext(_ aws.Context, _ *ecs.DescribeServicesInput, _ ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	return &ecs.DescribeServicesOutput{Services: []*ecs.Service{
+		{
+			ServiceName:    aws.String("test"),
+			TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/app:2"),
+			LoadBalancers: []*ecs.LoadBalancer{
+				{ContainerName: aws.String("app"), ContainerPort: aws.Int64(80), TargetGroupArn: aws.String(testTargetGroupArn)},
+			},
+		},
+	}}, nil
+}
+
+func (m *mockTargetHealthECS) ListTasksPagesWithContext(_ aws.Context, _ *ecs.ListTasksInput, fn func(*ecs.ListTasksOutput, bool) bool, _ ...request.Option) error {
+	var arns []*string
+	for _, t := range m.tasks {
+		arns = append(arns, t.TaskArn)
+	}
+	fn(&ecs.ListTasksOutput{TaskArns: arns}, true)
+	return nil
+}
+
+func (m *mockTargetHealthECS) DescribeTasksWithContext(_ aws.Context, _ *ecs.DescribeTasksInput, _ ...request.Option) (*ecs.DescribeTasksOutput, error) {
+	return &ecs.DescribeTasksOutput{Tasks: m.tasks}, nil
+}
+
+// mockTargetHealth returns target health descriptions in sequence, and the last one repeatedly.
+type mockTargetHealth struct {
+	elbv2iface.ELBV2API
+	sequence [][]*elbv2.TargetHealthDescription
+	calls    int
+}
+
+func (m *mockTargetHealth) DescribeTargetHealthWithContext(_ aws.Context, in *elbv2.DescribeTargetHealthInput, _ ...request.Option) (*elbv2.DescribeTargetHealthOutput, error) {
+	i := m.calls
+	if i >= len(m.sequence) {
+		i = len(m.sequence) - 1
+	}
+	m.calls++
+	return &elbv2.DescribeTargetHealthOutput{TargetHealthDescriptions: m.sequence[i]}, nil
+}
+
+func testAwsvpcTask(id, tdRev, ip string) *ecs.Task {
+	return &ecs.Task{
+		TaskArn:           aws.String("arn:aws:ecs:us-east-1:123456789012:task/default/" + id),
+		TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/app:" + tdRev),
+		LastStatus:        aws.String("RUNNING"),
+		Attachments: []*ecs.Attachment{
+			{
+				Type: aws.String("ElasticNetworkInterface"),
+				Details: []*ecs.KeyValuePair{
+					{Name: aws.String("privateIPv4Address"), Value: aws.String(ip)},
+				},
+			},
+		},
+	}
+}
+
+func targetHealth(id string, port int64, state, reason string) *elbv2.TargetHealthDescription {
+	h := &elbv2.TargetHealth{State: aws.String(state)}
+	if reason != "" {
+		h.Reason = aws.String(reason)
+	}
+	return &elbv2.TargetHealthDescription{
+		Target:       &elbv2.TargetDescription{Id: aws.String(id), Port: aws.Int64(port)},
+		TargetHealth: h,
+	}
+}
+
+func testTargetHealthApp(t *testing.T, sequence [][]*elbv2.TargetHealthDescription) (*App, *mockTargetHealth) {
+	app, err := NewApp(&Config{
+		Region:                      "us-east-1",
+		Service:                     "test",
+		Cluster:                     "default",
+		Timeout:                     time.Minute,
+		TaskDefinitionPath:          "tests/td.json",
+		PostStableTargetHealthCheck: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.ecs = &mockTargetHealthECS{
+		tasks: []*ecs.Task{
+			testAwsvpcTask("new1", "2", "10.0.0.1"),
+			testAwsvpcTask("new2", "2", "10.0.0.2"),
+			// old task being drained
+			testAwsvpcTask("old1", "1", "10.0.0.9"),
+		},
+	}
+	m := &mockTargetHealth{sequence: sequence}
+	app.elbv2 = m
+	return app, m
+}
+
+func noWait(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+func TestWaitTargetsHealthy(t *testing.T) {
+	app, m := testTargetHealthApp(t, [][]*elbv2.TargetHealthDescription{
+		{
+			targetHealth("10.0.0.1", 80, "initial", "Elb.RegistrationInProgress"),
+			targetHealth("10.0.0.9", 80, "draining", "Target.DeregistrationInProgress"),
+		},
+		{
+			targetHealth("10.0.0.1", 80, "healthy", ""),
+			targetHealth("10.0.0.2", 80, "unhealthy", "Target.FailedHealthChecks"),
+			targetHealth("10.0.0.9", 80, "draining", "Target.DeregistrationInProgress"),
+		},
+		{
+			targetHealth("10.0.0.1", 80, "healthy", ""),
+			targetHealth("10.0.0.2", 80, "healthy", ""),
+			targetHealth("10.0.0.9", 80, "draining", "Target.DeregistrationInProgress"),
+		},
+	})
+	if err := app.waitTargetsHealthy(context.Background(), noWait); err != nil {
+		t.Fatal(err)
+	}
+	if m.calls != 3 {
+		t.Errorf("unexpected calls of DescribeTargetHealth %d", m.calls)
+	}
+}
+
+func TestWaitTargetsHealthyTimeout(t *testing.T) {
+	app, _ := testTargetHealthApp(t, [][]*elbv2.TargetHealthDescription{
+		{
+			targetHealth("10.0.0.1", 80, "healthy", ""),
+			targetHealth("10.0.0.2", 80, "unhealthy", "Target.FailedHealthChecks"),
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := app.waitTargetsHealthy(ctx, func(time.Duration) <-chan time.Time { return nil })
+	if err == nil {
+		t.Fatal("must fail with unhealthy targets")
+	}
+	if ErrorType(err) != "WaitTimeout" {
+		t.Errorf("unexpected error type %s", ErrorType(err))
+	}
+	if !strings.Contains(err.Error(), "app 10.0.0.2:80 unhealthy (Target.FailedHealthChecks)") {
+		t.Errorf("unhealthy targets must be reported: %s", err)
+	}
+	if strings.Contains(err.Error(), "10.0.0.1") {
+		t.Errorf("healthy targets must not be reported: %s", err)
+	}
+}
+
+func TestTaskTargetsByInstance(t *testing.T) {
+	lb := &ecs.LoadBalancer{ContainerName: aws.String("app"), ContainerPort: aws.Int64(80)}
+	task := &ecs.Task{
+		ContainerInstanceArn: aws.String("arn:aws:ecs:us-east-1:123456789012:container-instance/default/abc"),
+		Containers: []*ecs.Container{
+			{Name: aws.String("sidecar"), NetworkBindings: []*ecs.NetworkBinding{{ContainerPort: aws.Int64(80), HostPort: aws.Int64(32001)}}},
+			{Name: aws.String("app"), NetworkBindings: []*ecs.NetworkBinding{{ContainerPort: aws.Int64(80), HostPort: aws.Int64(32768)}}},
+		},
+	}
+	targets := taskTargets(lb, []*ecs.Task{task}, map[string]string{
+		"arn:aws:ecs:us-east-1:123456789012:container-instance/default/abc": "i-0123456789abcdef0",
+	})
+	if len(targets) != 1 || targets[0] != "i-0123456789abcdef0:32768" {
+		t.Errorf("unexpected targets %v", targets)
+	}
+	unhealthy := unhealthyTargets(targets, nil)
+	if len(unhealthy) != 1 || unhealthy[0] != "i-0123456789abcdef0:32768 not registered" {
+		t.Errorf("unexpected unhealthy targets %v", unhealthy)
+	}
+}
@@ -0,0 +1,150 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+var (
+	startedByRegexp        = regexp.MustCompile(`^[a-zA-Z0-9_/-]{1,36}$`)
+	startedByInvalidRegexp = regexp.MustCompile(`[^a-zA-Z0-9_/-]`)
+)
+
+func validateStartedBy(s string) error {
+	if !startedByRegexp.MatchString(s) {
+		return fmt.Errorf("invalid startedBy %q: up to 36 letters, numbers, hyphens, forward slashes and underscores are allowed", s)
+	}
+	return nil
+}
+
+// defaultStartedBy returns "ecspresso/{user}" as startedBy of tasks run by ecspresso.
+func defaultStartedBy(user string) string {
+	s := "ecspresso"
+	if user != "" {
+		s += "/" + startedByInvalidRegexp.ReplaceAllString(user, "_")
+	}
+	if len(s) > 36 {
+		s = s[:36]
+	}
+	return s
+}
+
+func (d *App) Tasks(opt TasksOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	tasks, err := d.listTasks(ctx, *opt.DesiredStatus, *opt.StartedBy)
+	if err != nil {
+		return errors.Wrap(err, "failed to list tasks")
+	}
+	for _, task := range tasks {
+		fmt.Println(formatTask(task))
+	}
+	return nil
+}
+
+// listTasks lists tasks of the service.
+// When startedBy is not empty, lists tasks started with the value instead of the service's tasks.
+func (d *App) listTasks(ctx context.Context, desiredStatus, startedBy string) ([]*ecs.Task, error) {
+	in := &ecs.ListTasksInput{
+		Cluster:       aws.String(d.Cluster),
+		DesiredStatus: aws.String(desiredStatus),
+	}
+	if startedBy != "" {
+		if err := validateStartedBy(startedBy); err != nil {
+			return nil, err
+		}
+		in.StartedBy = aws.String(startedBy)
+	} else {
+		in.ServiceName = aws.String(d.Service)
+	}
+
+	var arns []*string
+	err := d.ecs.ListTasksPagesWithContext(ctx, in, func(out *ecs.ListTasksOutput, _ bool) bool {
+		arns = append(arns, out.TaskArns...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []*ecs.Task
+	// DescribeTasks accepts up to 100 tasks at once
+	for i := 0; i < len(arns); i += 100 {
+		end := i + 100
+		if end > len(arns) {
+			end = len(arns)
+		}
+		out, err := d.ecs.DescribeTasksWithContext(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(d.Cluster),
+			Tasks:   arns[i:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, out.Tasks...)
+	}
+	return tasks, nil
+}
+
+func formatTask(t *ecs.Task) string {
+	var startedAt string
+	if t.StartedAt != nil {
+		startedAt = t.StartedAt.In(timezone).Format("2006/01/02 15:04:05")
+	}
+	return strings.Join([]string{
+		arnToName(*t.TaskArn),
+		fmt.Sprintf("%s/%s", aws.StringValue(t.LastStatus), aws.StringValue(t.DesiredStatus)),
+		arnToName(*t.TaskDefinitionArn),
+		aws.StringValue(t.StartedBy),
+		startedAt,
+	}, "\t")
+}
+
+func (d *App) StopTask(opt StopTaskOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	id := *opt.ID
+	d.Log("Stopping task", id, opt.DryRunString())
+	out, err := d.ecs.DescribeTasksWithContext(ctx, &ecs.DescribeTasksInput{
+		Cluster: aws.String(d.Cluster),
+		Tasks:   []*string{aws.String(id)},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to describe task")
+	}
+	if len(out.Tasks) == 0 {
+		return fmt.Errorf("task %s is not found", id)
+	}
+	task := out.Tasks[0]
+	// tasks of a service belong to the group "service:{service name}"
+	if group := aws.StringValue(task.Group); group != "service:"+d.Service {
+		return fmt.Errorf("task %s does not belong to the service %s (group: %s)", id, d.Service, group)
+	}
+	d.Log("Task:", formatTask(task))
+	if *opt.DryRun {
+		d.ResultLog("DRY RUN OK")
+		return nil
+	}
+
+	in := &ecs.StopTaskInput{
+		Cluster: aws.String(d.Cluster),
+		Task:    task.TaskArn,
+	}
+	if *opt.Reason != "" {
+		in.Reason = opt.Reason
+	}
+	res, err := d.ecs.StopTaskWithContext(ctx, in)
+	if err != nil {
+		return errors.Wrap(err, "failed to stop task")
+	}
+	d.ResultLog("Task is stopping. last status:", aws.StringValue(res.Task.LastStatus))
+	return nil
+}
@@ -0,0 +1,33 @@
+package ecspresso
+
+import "testing"
+
+func TestDefaultStartedBy(t *testing.T) {
+	for user, expected := range map[string]string{
+		"":                                 "ecspresso",
+		"fujiwara":                         "ecspresso/fujiwara",
+		"john.doe@example.com":             "ecspresso/john_doe_example_com",
+		"a-very-long-user-name-over-limit": "ecspresso/a-very-long-user-name-over",
+	} {
+		got := defaultStartedBy(user)
+		if got != expected {
+			t.Errorf("defaultStartedBy(%q) expected %s got %s", user, expected, got)
+		}
+		if err := validateStartedBy(got); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestValidateStartedBy(t *testing.T) {
+	for s, valid := range map[string]bool{
+		"ecspresso/ci_123-a":                    true,
+		"":                                      false,
+		"with space":                            false,
+		"0123456789012345678901234567890123456": false,
+	} {
+		if err := validateStartedBy(s); (err == nil) != valid {
+			t.Errorf("validateStartedBy(%q) expected valid:%t got %v", s, valid, err)
+		}
+	}
+}
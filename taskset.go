@@ -0,0 +1,168 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// DefaultTaskSetScale is a default scale (percent of the desired count of the service) of a new task set.
+const DefaultTaskSetScale = 100.0
+
+// validateTaskSetController returns an error when the deployment controller of the service doesn't support task sets by ecspresso.
+func validateTaskSetController(sv *ecs.Service) error {
+	t := ecs.DeploymentControllerTypeEcs
+	if dc := sv.DeploymentController; dc != nil {
+		t = aws.StringValue(dc.Type)
+	}
+	switch t {
+	case ecs.DeploymentControllerTypeExternal:
+		return nil
+	case ecs.DeploymentControllerTypeCodeDeploy:
+		return errors.New("task sets of the service using CODE_DEPLOY deployment controller are managed by CodeDeploy. use deploy instead")
+	default:
+		return fmt.Errorf("task sets are supported only for EXTERNAL deployment controller, not %s", t)
+	}
+}
+
+// CreateTaskSet registers a new task definition and creates a task set of the service by it.
+// The task set is promoted to primary when opt.Promote is set.
+func (d *App) CreateTaskSet(opt CreateTaskSetOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	d.Log("Starting create task set", opt.DryRunString())
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	if err := validateTaskSetController(sv); err != nil {
+		return err
+	}
+	td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to load task definition")
+	}
+	in, err := d.createTaskSetInput(sv, opt)
+	if err != nil {
+		return err
+	}
+	if *opt.DryRun {
+		d.Log("task definition:", td.String())
+		d.Log("create task set input:", in.String())
+		d.ResultLog("DRY RUN OK")
+		return nil
+	}
+
+	newTd, err := d.RegisterTaskDefinition(ctx, td)
+	if err != nil {
+		return errors.Wrap(err, "failed to register task definition")
+	}
+	in.TaskDefinition = newTd.TaskDefinitionArn
+	d.DebugLog(in.String())
+	out, err := d.ecs.CreateTaskSetWithContext(ctx, in)
+	if err != nil {
+		return errors.Wrap(err, "failed to create task set")
+	}
+	ts := out.TaskSet
+	d.ResultLog("Task set", aws.StringValue(ts.Id), "is created by", arnToName(*newTd.TaskDefinitionArn))
+
+	if !aws.BoolValue(opt.Promote) {
+		return nil
+	}
+	return d.updateServicePrimaryTaskSet(ctx, aws.StringValue(ts.Id))
+}
+
+// createTaskSetInput returns an input to create a task set of the service.
+// Network configuration, load balancers and others are taken from the service definition if defined,
+// otherwise from the current service.
+func (d *App) createTaskSetInput(sv *ecs.Service, opt CreateTaskSetOption) (*ecs.CreateTaskSetInput, error) {
+	scale := DefaultTaskSetScale
+	if opt.Scale != nil {
+		scale = *opt.Scale
+	}
+	if scale < 0 || scale > 100 {
+		return nil, fmt.Errorf("scale must be in 0-100: %g", scale)
+	}
+	in := &ecs.CreateTaskSetInput{
+		Cluster:                  aws.String(d.Cluster),
+		Service:                  aws.String(d.Service),
+		NetworkConfiguration:     sv.NetworkConfiguration,
+		LoadBalancers:            sv.LoadBalancers,
+		ServiceRegistries:        sv.ServiceRegistries,
+		LaunchType:               sv.LaunchType,
+		CapacityProviderStrategy: sv.CapacityProviderStrategy,
+		PlatformVersion:          sv.PlatformVersion,
+		Scale: &ecs.Scale{
+			Unit:  aws.String(ecs.ScaleUnitPercent),
+			Value: aws.Float64(scale),
+		},
+	}
+	if id := aws.StringValue(opt.ExternalID); id != "" {
+		in.ExternalId = aws.String(id)
+	}
+	if d.config.ServiceDefinitionPath != "" {
+		svd, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load service definition")
+		}
+		in.NetworkConfiguration = svd.NetworkConfiguration
+		in.LoadBalancers = svd.LoadBalancers
+		in.ServiceRegistries = svd.ServiceRegistries
+		in.LaunchType = svd.LaunchType
+		in.CapacityProviderStrategy = svd.CapacityProviderStrategy
+		in.PlatformVersion = svd.PlatformVersion
+	}
+	return in, nil
+}
+
+// PromoteTaskSet promotes the task set to primary of the service.
+func (d *App) PromoteTaskSet(opt PromoteTaskSetOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	d.Log("Starting promote task set", opt.DryRunString())
+	sv, err := d.describeService(ctx)
+	if err != nil {
+		return err
+	}
+	if err := validateTaskSetController(sv); err != nil {
+		return err
+	}
+	id := aws.StringValue(opt.ID)
+	var found *ecs.TaskSet
+	for _, ts := range sv.TaskSets {
+		if aws.StringValue(ts.Id) == id || aws.StringValue(ts.TaskSetArn) == id {
+			found = ts
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("task set %s is not found in the service", id)
+	}
+	if aws.StringValue(found.Status) == "PRIMARY" {
+		d.ResultLog("Task set", aws.StringValue(found.Id), "is already primary")
+		return nil
+	}
+	if *opt.DryRun {
+		d.Log("task set:", formatTaskSet(found))
+		d.ResultLog("DRY RUN OK")
+		return nil
+	}
+	return d.updateServicePrimaryTaskSet(ctx, aws.StringValue(found.Id))
+}
+
+func (d *App) updateServicePrimaryTaskSet(ctx context.Context, id string) error {
+	d.Log("Promoting task set", id, "to primary")
+	if _, err := d.ecs.UpdateServicePrimaryTaskSetWithContext(ctx, &ecs.UpdateServicePrimaryTaskSetInput{
+		Cluster:        aws.String(d.Cluster),
+		Service:        aws.String(d.Service),
+		PrimaryTaskSet: aws.String(id),
+	}); err != nil {
+		return errors.Wrap(err, "failed to update primary task set")
+	}
+	d.ResultLog("Task set", id, "is primary now")
+	return nil
+}
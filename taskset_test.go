@@ -0,0 +1,203 @@
+package ecspresso
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockTaskSetECS struct {
+	ecsiface.ECSAPI
+	service  *ecs.Service
+	created  *ecs.CreateTaskSetInput
+	promoted *ecs.UpdateServicePrimaryTaskSetInput
+}
+
+func (m *mockTaskSetECS) DescribeServicesWithContext(_ aws.Context, _ *ecs.DescribeServicesInput, _ ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	return &ecs.DescribeServicesOutput{Services: []*ecs.Service{m.service}}, nil
+}
+
+func (m *mockTaskSetECS) RegisterTaskDefinitionWithContext(_ aws.Context, in *ecs.RegisterTaskDefinitionInput, _ ...request.Option) (*ecs.RegisterTaskDefinitionOutput, error) {
+	return &ecs.RegisterTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			Family:            in.Family,
+			Revision:          aws.Int64(2),
+			TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/" + *in.Family + ":2"),
+		},
+	}, nil
+}
+
+func (m *mockTaskSetECS) CreateTaskSetWithContext(_ aws.Context, in *ecs.CreateTaskSetInput, _ ...request.Option) (*ecs.CreateTaskSetOutput, error) {
+	m.created = in
+	ts := &ecs.TaskSet{
+		Id:             aws.String("ecs-svc/2"),
+		TaskSetArn:     aws.String("arn:aws:ecs:us-east-1:123456789012:task-set/default/test/ecs-svc/2"),
+		Status:         aws.String("ACTIVE"),
+		TaskDefinition: in.TaskDefinition,
+	}
+	m.service.TaskSets = append(m.service.TaskSets, ts)
+	return &ecs.CreateTaskSetOutput{TaskSet: ts}, nil
+}
+
+func (m *mockTaskSetECS) UpdateServicePrimaryTaskSetWithContext(_ aws.Context, in *ecs.UpdateServicePrimaryTaskSetInput, _ ...request.Option) (*ecs.UpdateServicePrimaryTaskSetOutput, error) {
+	m.promoted = in
+	for _, ts := range m.service.TaskSets {
+		if *ts.Id == *in.PrimaryTaskSet {
+			ts.Status = aws.String("PRIMARY")
+		} else {
+			ts.Status = aws.String("ACTIVE")
+		}
+	}
+	return &ecs.UpdateServicePrimaryTaskSetOutput{}, nil
+}
+
+func testTaskSetApp(t *testing.T, controller string) (*App, *mockTaskSetECS) {
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		Timeout:            time.Minute,
+		TaskDefinitionPath: "tests/td.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockTaskSetECS{
+		service: &ecs.Service{
+			ServiceName:          aws.String("test"),
+			DeploymentController: &ecs.DeploymentController{Type: aws.String(controller)},
+			LaunchType:           aws.String("FARGATE"),
+			NetworkConfiguration: &ecs.NetworkConfiguration{
+				AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+					Subnets: []*string{aws.String("subnet-1")},
+				},
+			},
+			TaskSets: []*ecs.TaskSet{
+				{Id: aws.String("ecs-svc/1"), Status: aws.String("PRIMARY")},
+			},
+		},
+	}
+	app.ecs = m
+	return app, m
+}
+
+func TestTaskSetLifecycle(t *testing.T) {
+	app, m := testTaskSetApp(t, ecs.DeploymentControllerTypeExternal)
+	scale := 50.0
+	err := app.CreateTaskSet(CreateTaskSetOption{
+		DryRun:     aws.Bool(false),
+		Scale:      &scale,
+		ExternalID: aws.String(""),
+		Promote:    aws.Bool(false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := m.created
+	if in == nil {
+		t.Fatal("task set is not created")
+	}
+	if *in.TaskDefinition != "arn:aws:ecs:us-east-1:123456789012:task-definition/katsubushi:2" {
+		t.Errorf("unexpected task definition %s", *in.TaskDefinition)
+	}
+	if *in.Scale.Unit != ecs.ScaleUnitPercent || *in.Scale.Value != 50 {
+		t.Errorf("unexpected scale %s", in.Scale)
+	}
+	if *in.LaunchType != "FARGATE" || *in.NetworkConfiguration.AwsvpcConfiguration.Subnets[0] != "subnet-1" {
+		t.Errorf("configurations of the service are not inherited %s", in)
+	}
+	if in.ExternalId != nil {
+		t.Errorf("unexpected external id %s", *in.ExternalId)
+	}
+	if m.promoted != nil {
+		t.Error("task set must not be promoted")
+	}
+
+	err = app.PromoteTaskSet(PromoteTaskSetOption{
+		DryRun: aws.Bool(false),
+		ID:     aws.String("ecs-svc/2"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.promoted == nil || *m.promoted.PrimaryTaskSet != "ecs-svc/2" {
+		t.Errorf("unexpected promoted input %v", m.promoted)
+	}
+
+	// already primary
+	m.promoted = nil
+	if err := app.PromoteTaskSet(PromoteTaskSetOption{DryRun: aws.Bool(false), ID: aws.String("ecs-svc/2")}); err != nil {
+		t.Fatal(err)
+	}
+	if m.promoted != nil {
+		t.Error("primary task set must not be promoted again")
+	}
+
+	if err := app.PromoteTaskSet(PromoteTaskSetOption{DryRun: aws.Bool(false), ID: aws.String("ecs-svc/9")}); err == nil {
+		t.Error("promoting unknown task set must fail")
+	}
+}
+
+func TestCreateTaskSetPromote(t *testing.T) {
+	app, m := testTaskSetApp(t, ecs.DeploymentControllerTypeExternal)
+	err := app.CreateTaskSet(CreateTaskSetOption{
+		DryRun:     aws.Bool(false),
+		ExternalID: aws.String("blue-green-1"),
+		Promote:    aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *m.created.Scale.Value != DefaultTaskSetScale {
+		t.Errorf("unexpected scale %s", m.created.Scale)
+	}
+	if aws.StringValue(m.created.ExternalId) != "blue-green-1" {
+		t.Errorf("unexpected external id %v", m.created.ExternalId)
+	}
+	if m.promoted == nil || *m.promoted.PrimaryTaskSet != "ecs-svc/2" {
+		t.Errorf("unexpected promoted input %v", m.promoted)
+	}
+}
+
+func TestCreateTaskSetScale(t *testing.T) {
+	app, _ := testTaskSetApp(t, ecs.DeploymentControllerTypeExternal)
+	sv := &ecs.Service{}
+	zero := 0.0
+	in, err := app.createTaskSetInput(sv, CreateTaskSetOption{Scale: &zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *in.Scale.Value != 0 {
+		t.Errorf("an empty task set must be created by scale 0: %s", in.Scale)
+	}
+	for _, scale := range []float64{-1, 100.5} {
+		scale := scale
+		if _, err := app.createTaskSetInput(sv, CreateTaskSetOption{Scale: &scale}); err == nil {
+			t.Errorf("scale %g must be invalid", scale)
+		}
+	}
+}
+
+func TestTaskSetControllerNotSupported(t *testing.T) {
+	for _, controller := range []string{ecs.DeploymentControllerTypeEcs, ecs.DeploymentControllerTypeCodeDeploy} {
+		app, m := testTaskSetApp(t, controller)
+		err := app.CreateTaskSet(CreateTaskSetOption{
+			DryRun:     aws.Bool(false),
+			ExternalID: aws.String(""),
+			Promote:    aws.Bool(true),
+		})
+		if err == nil {
+			t.Errorf("create task set must fail for %s", controller)
+		}
+		if m.created != nil {
+			t.Errorf("task set must not be created for %s", controller)
+		}
+		if err := app.PromoteTaskSet(PromoteTaskSetOption{DryRun: aws.Bool(false), ID: aws.String("ecs-svc/1")}); err == nil {
+			t.Errorf("promote task set must fail for %s", controller)
+		}
+	}
+}
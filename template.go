@@ -0,0 +1,95 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/kayac/go-config"
+	"github.com/pkg/errors"
+)
+
+// LocalAccountIDPlaceholder is used as aws_account_id in templates when validating locally.
+const LocalAccountIDPlaceholder = "000000000000"
+
+func newLoader(conf *Config) *config.Loader {
+	loader := config.New()
+	if conf.EnvPrefix != "" {
+		loader.Funcs(prefixedEnvFuncs(conf.EnvPrefix))
+	}
+	for _, f := range conf.templateFuncs {
+		loader.Funcs(f)
+	}
+	return loader
+}
+
+// prefixedEnvFuncs returns env and must_env template functions which resolve only
+// environment variables with the prefix. {{ env "FOO" }} refers $PREFIX_FOO.
+// Unprefixed environment variables are never resolved.
+func prefixedEnvFuncs(prefix string) template.FuncMap {
+	lookup := func(key string) (string, bool) {
+		if !strings.HasPrefix(key, prefix) {
+			key = prefix + key
+		}
+		return os.LookupEnv(key)
+	}
+	return template.FuncMap{
+		"env": func(keys ...string) string {
+			v := ""
+			for _, k := range keys {
+				if ev, ok := lookup(k); ok && ev != "" {
+					return ev
+				}
+				v = k
+			}
+			return v
+		},
+		"must_env": func(key string) string {
+			if v, ok := lookup(key); ok {
+				return v
+			}
+			panic(fmt.Sprintf("environment variable %s%s is not defined", prefix, strings.TrimPrefix(key, prefix)))
+		},
+	}
+}
+
+// awsTemplateFuncs returns aws_account_id and aws_region template functions.
+// The account ID is resolved by STS GetCallerIdentity at first use, and cached.
+func (d *App) awsTemplateFuncs(region string) template.FuncMap {
+	return template.FuncMap{
+		"aws_account_id": func() (string, error) {
+			if d.accountID != "" {
+				return d.accountID, nil
+			}
+			out, err := d.sts.GetCallerIdentityWithContext(context.Background(), &sts.GetCallerIdentityInput{})
+			if err != nil {
+				return "", errors.Wrap(err, "failed to get caller identity for aws_account_id")
+			}
+			d.accountID = aws.StringValue(out.Account)
+			return d.accountID, nil
+		},
+		"aws_region": func() string {
+			return region
+		},
+	}
+}
+
+// localAWSTemplateFuncs returns aws_account_id and aws_region template functions without AWS API calls.
+// aws_account_id is $AWS_ACCOUNT_ID or a placeholder.
+func localAWSTemplateFuncs(region string) template.FuncMap {
+	return template.FuncMap{
+		"aws_account_id": func() string {
+			if id := os.Getenv("AWS_ACCOUNT_ID"); id != "" {
+				return id
+			}
+			return LocalAccountIDPlaceholder
+		},
+		"aws_region": func() string {
+			return region
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package ecspresso
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+func TestEnvPrefix(t *testing.T) {
+	os.Setenv("ECSPRESSO_TAG", "v1")
+	os.Setenv("ECSPRESSO_FAMILY", "app")
+	os.Setenv("CPU", "1024")
+	defer func() {
+		os.Unsetenv("ECSPRESSO_TAG")
+		os.Unsetenv("ECSPRESSO_FAMILY")
+		os.Unsetenv("CPU")
+	}()
+
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "td.json")
+	src := `{
+  "family": "{{ env "ECSPRESSO_FAMILY" }}",
+  "cpu": "{{ env "CPU" }}",
+  "memory": "{{ env "MEMORY" "512" }}",
+  "containerDefinitions": [{"name": "app", "image": "app:{{ must_env "TAG" }}"}]
+}`
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app, err := NewApp(&Config{
+		Region:             "us-east-1",
+		Service:            "test",
+		Cluster:            "default",
+		TaskDefinitionPath: path,
+		EnvPrefix:          "ECSPRESSO_",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	td, err := app.LoadTaskDefinition(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := aws.StringValue(td.Family); got != "app" {
+		t.Errorf("prefixed variable must be resolved: %s", got)
+	}
+	if got := aws.StringValue(td.ContainerDefinitions[0].Image); got != "app:v1" {
+		t.Errorf("unprefixed reference must be resolved with the prefix: %s", got)
+	}
+	if got := aws.StringValue(td.Cpu); got != "CPU" {
+		t.Errorf("unprefixed variable must be left literal: %s", got)
+	}
+	if got := aws.StringValue(td.Memory); got != "512" {
+		t.Errorf("default value must be used: %s", got)
+	}
+}
+
+type mockSTS struct {
+	stsiface.STSAPI
+	calls int
+}
+
+func (m *mockSTS) GetCallerIdentityWithContext(_ aws.Context, _ *sts.GetCallerIdentityInput, _ ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+	m.calls++
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String("123456789012"),
+		Arn:     aws.String("arn:aws:iam::123456789012:user/ecspresso"),
+	}, nil
+}
+
+func TestAWSTemplateFuncs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "td.json")
+	src := `{
+  "family": "app",
+  "taskRoleArn": "arn:aws:iam::{{ aws_account_id }}:role/app",
+  "executionRoleArn": "arn:aws:iam::{{ aws_account_id }}:role/ecsTaskExecutionRole",
+  "containerDefinitions": [{"name": "app", "image": "{{ aws_account_id }}.dkr.ecr.{{ aws_region }}.amazonaws.com/app:v1", "memory": 128}]
+}`
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app, err := NewApp(&Config{
+		Region:             "ap-northeast-1",
+		Service:            "test",
+		Cluster:            "default",
+		TaskDefinitionPath: path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockSTS{}
+	app.sts = m
+	td, err := app.LoadTaskDefinition(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := aws.StringValue(td.TaskRoleArn), "arn:aws:iam::123456789012:role/app"; got != expected {
+		t.Errorf("unexpected taskRoleArn %s expected %s", got, expected)
+	}
+	if got, expected := aws.StringValue(td.ContainerDefinitions[0].Image), "123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:v1"; got != expected {
+		t.Errorf("unexpected image %s expected %s", got, expected)
+	}
+	if m.calls != 1 {
+		t.Errorf("account ID must be cached. GetCallerIdentity is called %d times", m.calls)
+	}
+
+	conf := NewDefaultConfig()
+	conf.Region = "ap-northeast-1"
+	conf.TaskDefinitionPath = path
+	if err := ValidateLocal(conf); err != nil {
+		t.Errorf("aws template functions must work in local validation: %s", err)
+	}
+}
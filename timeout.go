@@ -0,0 +1,26 @@
+package ecspresso
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// phaseContext returns a context for a phase (e.g. register) with the timeout of the phase.
+// When timeout is not set, ctx bounded by the global timeout is used as is.
+func phaseContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+// phaseTimeoutError wraps err to report which phase exceeded its timeout,
+// when ctx of the phase is expired but parent is not.
+func phaseTimeoutError(parent, ctx context.Context, phase string, timeout time.Duration, err error) error {
+	if err == nil || timeout <= 0 || parent.Err() != nil || ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	return errors.Wrapf(err, "%s phase exceeded its timeout %s", phase, timeout)
+}
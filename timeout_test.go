@@ -0,0 +1,91 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+type mockSlowRegisterECS struct {
+	mockCreateECS
+}
+
+func (m *mockSlowRegisterECS) RegisterTaskDefinitionWithContext(ctx aws.Context, in *ecs.RegisterTaskDefinitionInput, opts ...request.Option) (*ecs.RegisterTaskDefinitionOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type mockWaitTimeoutECS struct {
+	mockRolloutECS
+}
+
+func (m *mockWaitTimeoutECS) ListTasksPagesWithContext(_ aws.Context, _ *ecs.ListTasksInput, _ func(*ecs.ListTasksOutput, bool) bool, _ ...request.Option) error {
+	return nil
+}
+
+func TestRegisterTimeout(t *testing.T) {
+	app := &App{config: &Config{RegisterTimeout: 10 * time.Millisecond}}
+	td := &ecs.TaskDefinition{
+		Family:               aws.String("app"),
+		Memory:               aws.String("512"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("app"), Image: aws.String("app:v1")}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	app.ecs = &mockSlowRegisterECS{}
+	_, err := app.RegisterTaskDefinition(ctx, td)
+	if err == nil || !strings.Contains(err.Error(), "register phase exceeded its timeout 10ms") {
+		t.Errorf("register must time out by register_timeout: %v", err)
+	}
+
+	// wait_timeout doesn't affect register
+	app.config = &Config{WaitTimeout: 10 * time.Millisecond}
+	app.ecs = &mockCreateECS{}
+	if _, err := app.RegisterTaskDefinition(ctx, td); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	app := &App{
+		Service: "test",
+		Cluster: "default",
+		config: &Config{
+			Quiet:           true,
+			Timeout:         time.Minute,
+			RegisterTimeout: time.Minute,
+			WaitTimeout:     10 * time.Millisecond,
+		},
+	}
+	app.ecs = &mockWaitTimeoutECS{
+		mockRolloutECS: mockRolloutECS{services: []*ecs.Service{testRolloutService(aws.String("IN_PROGRESS"), 2)}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err := app.WaitServiceStable(ctx, time.Now())
+	if errors.Cause(err) != ErrWaitTimeout {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "wait phase exceeded its timeout 10ms") {
+		t.Errorf("error must report the wait phase: %v", err)
+	}
+
+	// the global timeout is not reported as a phase timeout
+	app.config.WaitTimeout = 0
+	app.config.Timeout = 10 * time.Millisecond
+	err = app.WaitServiceStable(ctx, time.Now())
+	if errors.Cause(err) != ErrWaitTimeout {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "phase") {
+		t.Errorf("global timeout must not be reported as a phase timeout: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// traceSpan represents a span of deploy phases.
+type traceSpan interface {
+	SetAttribute(key, value string)
+	End(err error)
+}
+
+// tracer starts spans and exports them. A nil parent means a root span.
+// App has a nil tracer when tracing is disabled.
+type tracer interface {
+	StartSpan(name string, parent traceSpan) traceSpan
+	Shutdown(ctx context.Context) error
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, string) {}
+func (noopSpan) End(error)                   {}
+
+// traceparentRegexp is a pattern of W3C trace context traceparent header (version 00).
+var traceparentRegexp = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// otlpTracer exports spans to an OTLP/HTTP endpoint in JSON encoding on Shutdown.
+type otlpTracer struct {
+	endpoint     string
+	client       *http.Client
+	traceID      string
+	parentSpanID string
+
+	mu    sync.Mutex
+	spans []*otlpSpan
+}
+
+// newOTLPTracer creates a tracer which exports spans to endpoint (e.g. http://localhost:4318).
+// When traceparent (W3C trace context) is valid, spans join the trace as children of it.
+func newOTLPTracer(endpoint, traceparent string) *otlpTracer {
+	t := &otlpTracer{
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	if m := traceparentRegexp.FindStringSubmatch(traceparent); m != nil {
+		t.traceID, t.parentSpanID = m[1], m[2]
+	} else {
+		t.traceID = randomHex(16)
+	}
+	return t
+}
+
+func (t *otlpTracer) StartSpan(name string, parent traceSpan) traceSpan {
+	s := &otlpSpan{
+		name:       name,
+		spanID:     randomHex(8),
+		parentID:   t.parentSpanID,
+		start:      time.Now(),
+		attributes: map[string]string{},
+	}
+	if p, ok := parent.(*otlpSpan); ok {
+		s.parentID = p.spanID
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return s
+}
+
+func (t *otlpTracer) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.spans) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(t.payload())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "failed to export spans")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("failed to export spans: %s", resp.Status)
+	}
+	t.spans = nil
+	return nil
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func otlpAttributes(attrs map[string]string) []otlpAttribute {
+	r := make([]otlpAttribute, 0, len(attrs))
+	for _, k := range sortedTagKeys(attrs) {
+		a := otlpAttribute{Key: k}
+		a.Value.StringValue = attrs[k]
+		r = append(r, a)
+	}
+	return r
+}
+
+// payload returns an ExportTraceServiceRequest of OTLP in JSON encoding.
+func (t *otlpTracer) payload() map[string]interface{} {
+	spans := make([]map[string]interface{}, 0, len(t.spans))
+	for _, s := range t.spans {
+		spans = append(spans, s.payload(t.traceID))
+	}
+	return map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{
+			{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]string{"service.name": "ecspresso"}),
+				},
+				"scopeSpans": []map[string]interface{}{
+					{
+						"scope": map[string]string{"name": "github.com/kayac/ecspresso"},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+type otlpSpan struct {
+	name       string
+	spanID     string
+	parentID   string
+	start      time.Time
+	end        time.Time
+	err        error
+	mu         sync.Mutex
+	attributes map[string]string
+}
+
+func (s *otlpSpan) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+func (s *otlpSpan) End(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.end.IsZero() {
+		s.end = time.Now()
+		s.err = err
+	}
+}
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeOk     = 1
+	otlpStatusCodeError  = 2
+)
+
+func (s *otlpSpan) payload(traceID string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	end := s.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	status := map[string]interface{}{"code": otlpStatusCodeOk}
+	if s.err != nil {
+		status = map[string]interface{}{"code": otlpStatusCodeError, "message": s.err.Error()}
+	}
+	p := map[string]interface{}{
+		"traceId":           traceID,
+		"spanId":            s.spanID,
+		"name":              s.name,
+		"kind":              otlpSpanKindInternal,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(end.UnixNano(), 10),
+		"attributes":        otlpAttributes(s.attributes),
+		"status":            status,
+	}
+	if s.parentID != "" {
+		p["parentSpanId"] = s.parentID
+	}
+	return p
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// startSpan starts a span of the deploy phase. It is a no-op when tracing is disabled.
+func (d *App) startSpan(name string, parent traceSpan) traceSpan {
+	if d.tracer == nil {
+		return noopSpan{}
+	}
+	s := d.tracer.StartSpan(name, parent)
+	if parent == nil {
+		s.SetAttribute("ecs.service", d.Service)
+		s.SetAttribute("ecs.cluster", d.Cluster)
+	}
+	return s
+}
+
+// flushTraces exports spans. Failures to export don't fail the deploy.
+func (d *App) flushTraces() {
+	if d.tracer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := d.tracer.Shutdown(ctx); err != nil {
+		d.Log("[WARNING] failed to export traces:", err)
+	}
+}
@@ -0,0 +1,103 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testExportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+func TestOTLPTracer(t *testing.T) {
+	var spans []testExportedSpan
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []testExportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.Unmarshal(b, &req); err != nil {
+			t.Error(err)
+		}
+		spans = req.ResourceSpans[0].ScopeSpans[0].Spans
+	}))
+	defer ts.Close()
+
+	traceID := "0af7651916cd43dd8448eb211c80319c"
+	parentID := "b7ad6b7169203331"
+	d := &App{
+		Service: "app",
+		Cluster: "default",
+		tracer:  newOTLPTracer(ts.URL, "00-"+traceID+"-"+parentID+"-01"),
+	}
+	root := d.startSpan("deploy", nil)
+	register := d.startSpan("register", root)
+	register.SetAttribute("ecs.task_definition", "app:3")
+	register.End(nil)
+	wait := d.startSpan("wait", root)
+	wait.End(errors.New("timed out"))
+	root.End(nil)
+	if err := d.tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spans) != 3 {
+		t.Fatalf("unexpected spans %#v", spans)
+	}
+	for _, s := range spans {
+		if s.TraceID != traceID {
+			t.Errorf("span %s must be in the trace of TRACEPARENT: %s", s.Name, s.TraceID)
+		}
+	}
+	if spans[0].Name != "deploy" || spans[0].ParentSpanID != parentID {
+		t.Errorf("unexpected root span %#v", spans[0])
+	}
+	if len(spans[0].Attributes) != 2 || spans[0].Attributes[0].Key != "ecs.cluster" || spans[0].Attributes[1].Value.StringValue != "app" {
+		t.Errorf("unexpected attributes of root span %#v", spans[0].Attributes)
+	}
+	if spans[1].ParentSpanID != spans[0].SpanID || spans[1].Attributes[0].Value.StringValue != "app:3" {
+		t.Errorf("unexpected register span %#v", spans[1])
+	}
+	if spans[2].Status.Code != otlpStatusCodeError || spans[2].Status.Message != "timed out" {
+		t.Errorf("unexpected status of wait span %#v", spans[2].Status)
+	}
+}
+
+func TestTracerDisabled(t *testing.T) {
+	d := &App{}
+	s := d.startSpan("deploy", nil)
+	if _, ok := s.(noopSpan); !ok {
+		t.Errorf("span must be a no-op when tracing is disabled: %T", s)
+	}
+	d.flushTraces()
+
+	tr := newOTLPTracer("http://localhost:4318", "invalid")
+	if len(tr.traceID) != 32 || tr.parentSpanID != "" {
+		t.Errorf("a new trace must be started for invalid TRACEPARENT: %s %s", tr.traceID, tr.parentSpanID)
+	}
+}
@@ -0,0 +1,33 @@
+package ecspresso
+
+import (
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Version is the version of ecspresso, reported in the user agent of AWS API calls.
+var Version = "current"
+
+// UserAgentHandlerName is the name of the request handler which adds the user agent of ecspresso.
+const UserAgentHandlerName = "ecspresso.UserAgentHandler"
+
+// userAgent returns the suffix of the user agent of AWS API calls, e.g. "ecspresso/v1.0.0 (client_id)".
+// user_agent in config overrides it.
+func (c *Config) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+	ua := "ecspresso/" + Version
+	if c.ClientID != "" {
+		ua += " (" + c.ClientID + ")"
+	}
+	return ua
+}
+
+// userAgentHandler returns a request handler which appends the user agent of ecspresso,
+// to identify API calls by ecspresso in CloudTrail.
+func userAgentHandler(conf *Config) request.NamedHandler {
+	return request.NamedHandler{
+		Name: UserAgentHandlerName,
+		Fn:   request.MakeAddToUserAgentFreeFormHandler(conf.userAgent()),
+	}
+}
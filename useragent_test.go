@@ -0,0 +1,41 @@
+package ecspresso
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func testUserAgent(t *testing.T, conf *Config) string {
+	t.Helper()
+	conf.Region = "us-east-1"
+	conf.Service = "test"
+	conf.Cluster = "default"
+	conf.TaskDefinitionPath = "tests/td.json"
+	app, err := NewApp(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := app.ecs.(*ecs.ECS).ListClustersRequest(&ecs.ListClustersInput{})
+	req.Handlers.Build.Run(req)
+	if req.Error != nil {
+		t.Fatal(req.Error)
+	}
+	return req.HTTPRequest.Header.Get("User-Agent")
+}
+
+func TestUserAgent(t *testing.T) {
+	defer func(v string) { Version = v }(Version)
+	Version = "v1.2.3"
+
+	if ua := testUserAgent(t, &Config{}); !strings.HasSuffix(ua, " ecspresso/v1.2.3") {
+		t.Errorf("unexpected user agent %s", ua)
+	}
+	if ua := testUserAgent(t, &Config{ClientID: "deployer"}); !strings.HasSuffix(ua, " ecspresso/v1.2.3 (deployer)") {
+		t.Errorf("unexpected user agent with client_id %s", ua)
+	}
+	if ua := testUserAgent(t, &Config{UserAgent: "my-deployer/1.0", ClientID: "deployer"}); !strings.HasSuffix(ua, " my-deployer/1.0") {
+		t.Errorf("user_agent must override the user agent %s", ua)
+	}
+}
@@ -17,3 +17,37 @@ func MarshalJSON(s interface{}) ([]byte, error) {
 	buf.WriteString("\n")
 	return buf.Bytes(), nil
 }
+
+// marshalJSONSorted returns JSON with indent, and keys of objects are sorted.
+func marshalJSONSorted(s interface{}) ([]byte, error) {
+	b, err := jsonutil.BuildJSON(s)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	b, err = json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// jsonForLog returns JSON of s for logs. It is indented when pretty_print is set in config.
+// This doesn't affect payloads of API calls.
+func (d *App) jsonForLog(s interface{}) string {
+	if d.config.PrettyPrint {
+		b, err := MarshalJSON(s)
+		if err != nil {
+			return err.Error()
+		}
+		return string(bytes.TrimRight(b, "\n"))
+	}
+	b, err := jsonutil.BuildJSON(s)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
@@ -0,0 +1,53 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+)
+
+func TestPrettyPrint(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, pretty := range []bool{false, true} {
+		buf.Reset()
+		app, err := NewApp(&Config{
+			Region:             "us-east-1",
+			Service:            "test",
+			Cluster:            "default",
+			TaskDefinitionPath: "tests/td.json",
+			PrettyPrint:        pretty,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		app.Debug = true
+		m := &mockCreateECS{}
+		app.ecs = m
+		td, err := app.LoadTaskDefinition(app.config.TaskDefinitionPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := app.RegisterTaskDefinition(context.Background(), td); err != nil {
+			t.Fatal(err)
+		}
+
+		payload, err := jsonutil.BuildJSON(m.registeredInput)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(payload, []byte("\n")) {
+			t.Errorf("payload must be compact (pretty_print:%t): %s", pretty, payload)
+		}
+		if got := strings.Contains(buf.String(), "payload: {\n"); got != pretty {
+			t.Errorf("payload log must be indented only if pretty_print is set (pretty_print:%t): %s", pretty, buf.String())
+		}
+	}
+}
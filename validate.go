@@ -0,0 +1,631 @@
+package ecspresso
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+type validation struct {
+	errors   []string
+	warnings []string
+}
+
+func (v *validation) errorf(format string, args ...interface{}) {
+	v.errors = append(v.errors, fmt.Sprintf(format, args...))
+}
+
+func (v *validation) warnf(format string, args ...interface{}) {
+	v.warnings = append(v.warnings, fmt.Sprintf(format, args...))
+}
+
+func (v *validation) err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errors}
+}
+
+// ValidationError represents problems found in a task definition.
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid task definition: %s", strings.Join(e.Errors, ", "))
+}
+
+// ValidateTaskDefinition validates a task definition locally without any AWS API calls.
+// It returns warnings, and an error including all of the problems found.
+func ValidateTaskDefinition(td *ecs.TaskDefinition) ([]string, error) {
+	v := &validation{}
+	validateTaskDefinitionBasic(v, td)
+	validateWindowsTaskDefinition(v, td)
+	validateHealthChecks(v, td)
+	validateSystemControls(v, td)
+	validatePlacementConstraints(v, td)
+	validateMountPoints(v, td)
+	validateDeprecatedFields(v, td)
+	validateTaskDefinitionSize(v, td)
+	validateEssentialContainers(v, td)
+	validateTaskResources(v, td)
+	return v.warnings, v.err()
+}
+
+func (d *App) validateTaskDefinition(td *ecs.TaskDefinition) error {
+	warnings, err := ValidateTaskDefinition(td)
+	for _, w := range warnings {
+		d.Log("[WARNING]", w)
+	}
+	if err != nil {
+		return err
+	}
+	if err := validatePrimaryContainer(d.config.PrimaryContainer, td); err != nil {
+		return err
+	}
+	if d.config.DisallowMutableTags {
+		return validateImmutableImages(td)
+	}
+	return nil
+}
+
+// validateImmutableImages returns an error when any container image refers the "latest" tag or no tag.
+func validateImmutableImages(td *ecs.TaskDefinition) error {
+	v := &validation{}
+	for _, c := range td.ContainerDefinitions {
+		image := aws.StringValue(c.Image)
+		if isMutableImage(image) {
+			v.errorf("container %s: image %s has a mutable tag (use an explicit tag or digest, or --allow-latest)", aws.StringValue(c.Name), image)
+		}
+	}
+	return v.err()
+}
+
+func isMutableImage(image string) bool {
+	if strings.Contains(image, "@") {
+		// digest
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	if i == -1 {
+		// no tag means latest
+		return true
+	}
+	return name[i+1:] == "latest"
+}
+
+func validateTaskDefinitionBasic(v *validation, td *ecs.TaskDefinition) {
+	if family := aws.StringValue(td.Family); family == "" {
+		v.errorf("family is required")
+	} else if !familyNameRegexp.MatchString(family) {
+		v.errorf("family %s must be up to 255 letters, numbers, hyphens and underscores", family)
+	}
+	if len(td.ContainerDefinitions) == 0 {
+		v.errorf("containerDefinitions is required")
+	}
+	names := make(map[string]bool, len(td.ContainerDefinitions))
+	for i, c := range td.ContainerDefinitions {
+		name := aws.StringValue(c.Name)
+		if name == "" {
+			v.errorf("containerDefinitions[%d]: name is required", i)
+		} else if names[name] {
+			v.errorf("container %s: name is duplicated", name)
+		}
+		names[name] = true
+		if aws.StringValue(c.Image) == "" {
+			v.errorf("containerDefinitions[%d]: image is required", i)
+		}
+	}
+}
+
+func isWindowsTaskDefinition(td *ecs.TaskDefinition) bool {
+	if td.RuntimePlatform == nil {
+		return false
+	}
+	return strings.HasPrefix(aws.StringValue(td.RuntimePlatform.OperatingSystemFamily), "WINDOWS_SERVER")
+}
+
+// validateWindowsTaskDefinition rejects parameters not supported for Windows containers.
+func validateWindowsTaskDefinition(v *validation, td *ecs.TaskDefinition) {
+	if !isWindowsTaskDefinition(td) {
+		return
+	}
+	family := *td.RuntimePlatform.OperatingSystemFamily
+	if td.PidMode != nil {
+		v.errorf("pidMode is not supported for %s", family)
+	}
+	if td.IpcMode != nil {
+		v.errorf("ipcMode is not supported for %s", family)
+	}
+	for _, c := range td.ContainerDefinitions {
+		var fields []string
+		if c.LinuxParameters != nil {
+			fields = append(fields, "linuxParameters")
+		}
+		if aws.BoolValue(c.Privileged) {
+			fields = append(fields, "privileged")
+		}
+		if c.User != nil {
+			fields = append(fields, "user")
+		}
+		if aws.BoolValue(c.ReadonlyRootFilesystem) {
+			fields = append(fields, "readonlyRootFilesystem")
+		}
+		if len(c.Ulimits) > 0 {
+			fields = append(fields, "ulimits")
+		}
+		if len(c.Links) > 0 {
+			fields = append(fields, "links")
+		}
+		if len(c.DockerSecurityOptions) > 0 {
+			fields = append(fields, "dockerSecurityOptions")
+		}
+		if len(fields) > 0 {
+			v.errorf("container %s: %s not supported for %s", aws.StringValue(c.Name), strings.Join(fields, ", "), family)
+		}
+	}
+}
+
+// validateHealthChecks validates healthCheck of containers by the ranges documented in ECS.
+func validateHealthChecks(v *validation, td *ecs.TaskDefinition) {
+	for _, c := range td.ContainerDefinitions {
+		hc := c.HealthCheck
+		if hc == nil {
+			continue
+		}
+		name := aws.StringValue(c.Name)
+		if len(hc.Command) == 0 {
+			v.errorf("container %s: healthCheck.command is required", name)
+		} else if cmd := aws.StringValue(hc.Command[0]); cmd != "CMD" && cmd != "CMD-SHELL" {
+			v.errorf("container %s: healthCheck.command must start with CMD or CMD-SHELL, not %q", name, cmd)
+		} else if len(hc.Command) == 1 {
+			v.errorf("container %s: healthCheck.command has no command to run after %s", name, cmd)
+		}
+		for _, r := range []struct {
+			name     string
+			value    *int64
+			min, max int64
+		}{
+			{"interval", hc.Interval, 5, 300},
+			{"timeout", hc.Timeout, 2, 60},
+			{"retries", hc.Retries, 1, 10},
+			{"startPeriod", hc.StartPeriod, 0, 300},
+		} {
+			if r.value == nil {
+				continue
+			}
+			if *r.value < r.min || *r.value > r.max {
+				v.errorf("container %s: healthCheck.%s must be between %d and %d, not %d", name, r.name, r.min, r.max, *r.value)
+			}
+		}
+	}
+}
+
+// validateSystemControls validates systemControls of containers.
+// Network namespace parameters (net.*) are shared by all containers in a task for awsvpc network mode,
+// and not supported for host network mode.
+func validateSystemControls(v *validation, td *ecs.TaskDefinition) {
+	networkMode := aws.StringValue(td.NetworkMode)
+	netValues := make(map[string]string)
+	for _, c := range td.ContainerDefinitions {
+		name := aws.StringValue(c.Name)
+		for i, sc := range c.SystemControls {
+			ns, value := aws.StringValue(sc.Namespace), aws.StringValue(sc.Value)
+			if ns == "" || sc.Value == nil {
+				v.errorf("container %s: systemControls[%d] requires namespace and value", name, i)
+				continue
+			}
+			if !strings.HasPrefix(ns, "net.") {
+				continue
+			}
+			switch networkMode {
+			case ecs.NetworkModeHost:
+				v.errorf("container %s: systemControls %s is not supported for host network mode", name, ns)
+			case ecs.NetworkModeAwsvpc:
+				if prev, ok := netValues[ns]; ok && prev != value {
+					v.warnf("container %s: systemControls %s=%s conflicts with %s set by another container. it applies to all containers in the task for awsvpc network mode, and the container started last wins", name, ns, value, prev)
+				}
+				netValues[ns] = value
+			}
+		}
+	}
+}
+
+func validatePlacementConstraints(v *validation, td *ecs.TaskDefinition) {
+	for i, pc := range td.PlacementConstraints {
+		switch typ := aws.StringValue(pc.Type); typ {
+		case ecs.PlacementConstraintTypeDistinctInstance:
+		case ecs.PlacementConstraintTypeMemberOf:
+			if strings.TrimSpace(aws.StringValue(pc.Expression)) == "" {
+				v.errorf("placementConstraints[%d]: memberOf requires an expression", i)
+			}
+		default:
+			v.errorf("placementConstraints[%d]: type %q must be one of %s or %s", i, typ, ecs.PlacementConstraintTypeDistinctInstance, ecs.PlacementConstraintTypeMemberOf)
+		}
+	}
+}
+
+// validateMountPoints validates that sourceVolume of mountPoints of containers refers a volume of the task definition.
+// Volumes not mounted by any container are warned.
+func validateMountPoints(v *validation, td *ecs.TaskDefinition) {
+	volumes := make(map[string]bool, len(td.Volumes))
+	for _, vol := range td.Volumes {
+		volumes[aws.StringValue(vol.Name)] = true
+	}
+	mounted := make(map[string]bool)
+	for _, c := range td.ContainerDefinitions {
+		name := aws.StringValue(c.Name)
+		for i, mp := range c.MountPoints {
+			src := aws.StringValue(mp.SourceVolume)
+			switch {
+			case src == "":
+				v.errorf("container %s: mountPoints[%d] requires sourceVolume", name, i)
+			case !volumes[src]:
+				v.errorf("container %s: mountPoints[%d] sourceVolume %s is not defined in volumes", name, i, src)
+			default:
+				mounted[src] = true
+			}
+		}
+	}
+	for _, vol := range td.Volumes {
+		if name := aws.StringValue(vol.Name); !mounted[name] {
+			v.warnf("volume %s is not mounted by any container", name)
+		}
+	}
+}
+
+// deprecatedContainerField is a field of container definitions which is deprecated or ignored.
+type deprecatedContainerField struct {
+	name       string
+	used       func(td *ecs.TaskDefinition, c *ecs.ContainerDefinition) bool
+	suggestion string
+}
+
+// deprecatedContainerFields are fields warned by validateDeprecatedFields. Add new deprecations here.
+var deprecatedContainerFields = []deprecatedContainerField{
+	{
+		name: "links",
+		used: func(_ *ecs.TaskDefinition, c *ecs.ContainerDefinition) bool {
+			return len(c.Links) > 0
+		},
+		suggestion: "links is a legacy feature of Docker. use awsvpc network mode (containers share localhost) or Service Connect instead",
+	},
+	{
+		name: "resourceRequirements InferenceAccelerator",
+		used: func(_ *ecs.TaskDefinition, c *ecs.ContainerDefinition) bool {
+			for _, r := range c.ResourceRequirements {
+				if aws.StringValue(r.Type) == ecs.ResourceTypeInferenceAccelerator {
+					return true
+				}
+			}
+			return false
+		},
+		suggestion: "Amazon Elastic Inference is no longer available. use GPU or AWS Inferentia instances instead",
+	},
+	{
+		name: "hostname",
+		used: func(td *ecs.TaskDefinition, c *ecs.ContainerDefinition) bool {
+			return aws.StringValue(c.Hostname) != "" && aws.StringValue(td.NetworkMode) == ecs.NetworkModeAwsvpc
+		},
+		suggestion: "hostname is not supported in awsvpc network mode. remove it",
+	},
+}
+
+// validateDeprecatedFields warns about deprecated or ignored fields in container definitions.
+func validateDeprecatedFields(v *validation, td *ecs.TaskDefinition) {
+	for _, c := range td.ContainerDefinitions {
+		for _, f := range deprecatedContainerFields {
+			if f.used(td, c) {
+				v.warnf("container %s: %s is deprecated or ignored: %s", aws.StringValue(c.Name), f.name, f.suggestion)
+			}
+		}
+	}
+}
+
+// sidecarImages are substrings of well-known sidecar images.
+var sidecarImages = []string{
+	"aws-for-fluent-bit",
+	"fluent-bit",
+	"fluentd",
+	"aws-xray-daemon",
+	"datadog/agent",
+	"cloudwatch-agent",
+	"aws-otel-collector",
+}
+
+func isSidecarContainer(c *ecs.ContainerDefinition) bool {
+	if c.FirelensConfiguration != nil {
+		return true
+	}
+	image := aws.StringValue(c.Image)
+	for _, s := range sidecarImages {
+		if strings.Contains(image, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateEssentialContainers requires at least one essential container.
+// essential is true by default when unspecified.
+func validateEssentialContainers(v *validation, td *ecs.TaskDefinition) {
+	if len(td.ContainerDefinitions) == 0 {
+		return
+	}
+	var essentials, sidecars []string
+	m := make([]string, 0, len(td.ContainerDefinitions))
+	for _, c := range td.ContainerDefinitions {
+		name := aws.StringValue(c.Name)
+		essential := c.Essential == nil || *c.Essential
+		m = append(m, fmt.Sprintf("%s=%t", name, essential))
+		if !essential {
+			continue
+		}
+		essentials = append(essentials, name)
+		if isSidecarContainer(c) {
+			sidecars = append(sidecars, name)
+		}
+	}
+	switch {
+	case len(essentials) == 0:
+		v.errorf("at least one container must be essential (%s)", strings.Join(m, " "))
+	case len(sidecars) > 0 && len(sidecars) < len(td.ContainerDefinitions):
+		v.warnf("sidecar containers %s are essential. the task stops when they exit (%s)", strings.Join(sidecars, ", "), strings.Join(m, " "))
+	}
+}
+
+// fargateMemoryRanges are supported memory (MiB) values for each cpu (units) of Fargate tasks.
+var fargateMemoryRanges = map[int64]struct{ min, max, step int64 }{
+	256:   {512, 2048, 0},
+	512:   {1024, 4096, 1024},
+	1024:  {2048, 8192, 1024},
+	2048:  {4096, 16384, 1024},
+	4096:  {8192, 30720, 1024},
+	8192:  {16384, 61440, 4096},
+	16384: {32768, 122880, 8192},
+}
+
+// parseTaskResource parses task-level cpu or memory like "1024", "1 vCPU", "1vCPU", "2 GB" or "2GB" into cpu units or MiB.
+func parseTaskResource(s, unit string) (int64, error) {
+	s = strings.TrimSpace(s)
+	num, scale := s, 1.0
+	if len(s) > len(unit) && strings.EqualFold(s[len(s)-len(unit):], unit) {
+		// the space before the unit is optional
+		num, scale = strings.TrimSpace(s[:len(s)-len(unit)]), 1024
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid value %s", s)
+	}
+	return int64(n * scale), nil
+}
+
+// isFargateMemory reports whether the combination of cpu and memory is supported by Fargate.
+func isFargateMemory(cpu, memory int64) bool {
+	r, ok := fargateMemoryRanges[cpu]
+	if !ok || memory < r.min || memory > r.max {
+		return false
+	}
+	if r.step == 0 {
+		// 256 cpu supports 512, 1024 and 2048
+		return memory == 512 || memory%1024 == 0
+	}
+	return (memory-r.min)%r.step == 0
+}
+
+// validateTaskResources validates that cpu and memory of containers fit within the task-level cpu and memory,
+// and that the task-level cpu and memory are a combination supported by Fargate.
+func validateTaskResources(v *validation, td *ecs.TaskDefinition) {
+	var fargate, ec2 bool
+	for _, c := range td.RequiresCompatibilities {
+		switch aws.StringValue(c) {
+		case ecs.CompatibilityFargate:
+			fargate = true
+		case ecs.CompatibilityEc2:
+			ec2 = true
+		}
+	}
+	// ECS defaults to EC2 when requiresCompatibilities is not specified.
+	if len(td.RequiresCompatibilities) == 0 {
+		ec2 = true
+	}
+	var cpu, memory int64
+	if s := aws.StringValue(td.Cpu); s != "" {
+		var err error
+		if cpu, err = parseTaskResource(s, "vCPU"); err != nil {
+			v.errorf("cpu: %s", err)
+		}
+	}
+	if s := aws.StringValue(td.Memory); s != "" {
+		var err error
+		if memory, err = parseTaskResource(s, "GB"); err != nil {
+			v.errorf("memory: %s", err)
+		}
+	}
+	if fargate {
+		switch {
+		case td.Cpu == nil || td.Memory == nil:
+			v.errorf("cpu and memory are required for FARGATE")
+		case cpu > 0 && memory > 0:
+			if _, ok := fargateMemoryRanges[cpu]; !ok {
+				v.errorf("cpu %d is not supported by FARGATE", cpu)
+			} else if !isFargateMemory(cpu, memory) {
+				r := fargateMemoryRanges[cpu]
+				v.errorf("memory %d is not supported with cpu %d by FARGATE (%d-%d MiB)", memory, cpu, r.min, r.max)
+			}
+		}
+	}
+
+	var totalCPU, totalMemory int64
+	for _, c := range td.ContainerDefinitions {
+		name := aws.StringValue(c.Name)
+		totalCPU += aws.Int64Value(c.Cpu)
+		// hard limit takes precedence over reservation
+		m := aws.Int64Value(c.Memory)
+		if m == 0 {
+			m = aws.Int64Value(c.MemoryReservation)
+		}
+		totalMemory += m
+		if ec2 && td.Memory == nil && c.Memory == nil && c.MemoryReservation == nil {
+			v.errorf("container %s: memory or memoryReservation is required for EC2 without the task memory", name)
+		}
+		if c.Memory != nil && c.MemoryReservation != nil && *c.MemoryReservation > *c.Memory {
+			v.errorf("container %s: memoryReservation %d must not be greater than memory %d", name, *c.MemoryReservation, *c.Memory)
+		}
+		if memory > 0 && aws.Int64Value(c.Memory) > memory {
+			v.errorf("container %s: memory %d exceeds the task memory %d", name, *c.Memory, memory)
+		}
+	}
+	if cpu > 0 && totalCPU > cpu {
+		v.errorf("sum of container cpu %d exceeds the task cpu %d", totalCPU, cpu)
+	}
+	if memory > 0 && totalMemory > memory {
+		v.errorf("sum of container memory %d exceeds the task memory %d", totalMemory, memory)
+	}
+}
+
+// MaxTaskDefinitionSize is the maximum size of a task definition document.
+const MaxTaskDefinitionSize = 64 * 1024
+
+func validateTaskDefinitionSize(v *validation, td *ecs.TaskDefinition) {
+	b, err := jsonutil.BuildJSON(registerTaskDefinitionInput(td))
+	if err != nil {
+		v.errorf("unable to marshal task definition to JSON: %s", err)
+		return
+	}
+	size := len(b)
+	switch {
+	case size > MaxTaskDefinitionSize:
+		v.errorf("task definition size %d bytes exceeds the limit %d bytes. consider moving environment variables into environmentFiles or secrets", size, MaxTaskDefinitionSize)
+	case size > MaxTaskDefinitionSize*9/10:
+		v.warnf("task definition size %d bytes is approaching the limit %d bytes. consider moving environment variables into environmentFiles or secrets", size, MaxTaskDefinitionSize)
+	}
+}
+
+// ValidateLocal validates the configuration, the task definition and the service definition
+// without any AWS API calls, so it works without AWS credentials.
+// It returns an error including all of the problems found.
+func ValidateLocal(conf *Config) error {
+	v := &validation{}
+	if err := conf.Validate(); err != nil {
+		v.errorf("config: %s", err)
+	}
+	d := &App{
+		Service: conf.Service,
+		Cluster: conf.Cluster,
+		config:  conf,
+		loader:  newLoader(conf),
+	}
+	d.loader.Funcs(localAWSTemplateFuncs(conf.Region))
+	if err := validateTags(d.tags()); err != nil {
+		v.errorf("config: %s", err)
+	}
+
+	var td *ecs.TaskDefinition
+	switch {
+	case conf.TaskDefinitionPath == "":
+		if conf.PatchPath != "" {
+			d.Log("[WARNING] patch requires the current task definition on AWS. skipped validating the task definition")
+		}
+	case strings.HasPrefix(conf.TaskDefinitionPath, "s3://"):
+		v.errorf("task_definition: %s can not be validated locally", conf.TaskDefinitionPath)
+	default:
+		var err error
+		td, err = d.LoadTaskDefinition(conf.TaskDefinitionPath)
+		if err != nil {
+			v.errorf("task_definition: %s", err)
+			break
+		}
+		warnings, err := ValidateTaskDefinition(td)
+		for _, w := range warnings {
+			d.Log("[WARNING]", w)
+		}
+		if err != nil {
+			v.errorf("%s", err)
+		}
+		if err := validatePrimaryContainer(conf.PrimaryContainer, td); err != nil {
+			v.errorf("%s", err)
+		}
+		if conf.DisallowMutableTags {
+			if err := validateImmutableImages(td); err != nil {
+				v.errorf("%s", err)
+			}
+		}
+	}
+
+	if conf.ServiceDefinitionPath != "" {
+		if svd, err := d.LoadServiceDefinition(conf.ServiceDefinitionPath); err != nil {
+			v.errorf("service_definition: %s", err)
+		} else if td != nil {
+			if err := validateLoadBalancers(svd.LoadBalancers, td); err != nil {
+				v.errorf("service_definition: %s", err)
+			}
+			if err := validateServiceConnect(svd.ServiceConnectConfiguration, td); err != nil {
+				v.errorf("service_definition: %s", err)
+			}
+		}
+	}
+	if len(v.errors) > 0 {
+		return fmt.Errorf("validation failed:\n  %s", strings.Join(v.errors, "\n  "))
+	}
+	return nil
+}
+
+// Validate validates the configuration and definitions locally, and then checks the service exists
+// and the resources referred by the task definition (log groups of awslogs containers, roles and secrets) are valid.
+func (d *App) Validate(opt ValidateOption) error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	if err := ValidateLocal(d.config); err != nil {
+		return err
+	}
+	if err := d.verifyIdentity(ctx); err != nil {
+		return err
+	}
+	out, err := d.ecs.DescribeServicesWithContext(ctx, d.DescribeServicesInput())
+	if err != nil {
+		return errors.Wrap(err, "failed to describe service")
+	}
+	if len(out.Services) == 0 || aws.StringValue(out.Services[0].Status) == "INACTIVE" {
+		return errors.Errorf("service %s is not found in cluster %s", d.Service, d.Cluster)
+	}
+	if d.config.PatchPath != "" || d.config.TaskDefinitionPath == "" {
+		d.ResultLog("Validation passed")
+		return nil
+	}
+	td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to load task definition")
+	}
+	if sv := out.Services[0]; len(sv.LoadBalancers) > 0 {
+		// load balancers of the live service are not validated locally
+		attrs, err := d.serviceAttributesFor(sv, true)
+		if err != nil {
+			return err
+		}
+		if err := validateLoadBalancers(attrs.LoadBalancers, td); err != nil {
+			return errors.Wrap(err, "task definition does not match load balancers of the service")
+		}
+	}
+	if err := d.verifyLogGroups(ctx, td, aws.BoolValue(opt.CreateLogGroups)); err != nil {
+		return err
+	}
+	if err := d.verifyTaskRoles(ctx, td); err != nil {
+		return err
+	}
+	if err := d.verifySecretReferences(td); err != nil {
+		return err
+	}
+	if err := d.verifySecretsReadable(ctx, td); err != nil {
+		return err
+	}
+	d.ResultLog("Validation passed")
+	return nil
+}
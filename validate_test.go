@@ -0,0 +1,486 @@
+package ecspresso
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestIsMutableImage(t *testing.T) {
+	for image, mutable := range map[string]bool{
+		"nginx":                     true,
+		"nginx:latest":              true,
+		"nginx:1.17":                false,
+		"localhost:5000/app":        true,
+		"localhost:5000/app:v1":     false,
+		"localhost:5000/app:latest": true,
+		"123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app@sha256:0123456789abcdef": false,
+	} {
+		if got := isMutableImage(image); got != mutable {
+			t.Errorf("isMutableImage(%s) expected %t got %t", image, mutable, got)
+		}
+	}
+}
+
+func TestValidateImmutableImages(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1")},
+			{Name: aws.String("sidecar"), Image: aws.String("sidecar:latest")},
+		},
+	}
+	err := validateImmutableImages(td)
+	if err == nil {
+		t.Fatal("latest tag must be an error")
+	}
+	if !strings.Contains(err.Error(), "container sidecar: image sidecar:latest") {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestValidateWindowsTaskDefinition(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		Memory: aws.String("512"),
+		RuntimePlatform: &ecs.RuntimePlatform{
+			OperatingSystemFamily: aws.String("WINDOWS_SERVER_2019_CORE"),
+		},
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1")},
+			{
+				Name:            aws.String("sidecar"),
+				Image:           aws.String("sidecar:v1"),
+				Privileged:      aws.Bool(true),
+				User:            aws.String("root"),
+				LinuxParameters: &ecs.LinuxParameters{InitProcessEnabled: aws.Bool(true)},
+			},
+		},
+	}
+	_, err := ValidateTaskDefinition(td)
+	if err == nil {
+		t.Fatal("linux only parameters must be an error")
+	}
+	if !strings.Contains(err.Error(), "container sidecar: linuxParameters, privileged, user not supported for WINDOWS_SERVER_2019_CORE") {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	td.RuntimePlatform.OperatingSystemFamily = aws.String("LINUX")
+	if _, err := ValidateTaskDefinition(td); err != nil {
+		t.Errorf("unexpected error for LINUX %s", err)
+	}
+
+	// explicit false is harmless for Windows
+	td.RuntimePlatform.OperatingSystemFamily = aws.String("WINDOWS_SERVER_2019_CORE")
+	td.ContainerDefinitions[1] = &ecs.ContainerDefinition{
+		Name:                   aws.String("sidecar"),
+		Image:                  aws.String("sidecar:v1"),
+		Privileged:             aws.Bool(false),
+		ReadonlyRootFilesystem: aws.Bool(false),
+	}
+	if _, err := ValidateTaskDefinition(td); err != nil {
+		t.Errorf("unexpected error for explicit false %s", err)
+	}
+}
+
+func TestValidateHealthChecks(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name:  aws.String("app"),
+				Image: aws.String("app:v1"),
+				HealthCheck: &ecs.HealthCheck{
+					Command:  aws.StringSlice([]string{"CMD-SHELL", "curl -f http://localhost/ || exit 1"}),
+					Interval: aws.Int64(30),
+					Retries:  aws.Int64(3),
+				},
+			},
+		},
+	}
+	if _, err := ValidateTaskDefinition(td); err != nil {
+		t.Error(err)
+	}
+
+	td.ContainerDefinitions[0].HealthCheck = &ecs.HealthCheck{
+		Command:  aws.StringSlice([]string{"curl", "-f", "http://localhost/"}),
+		Interval: aws.Int64(0),
+	}
+	_, err := ValidateTaskDefinition(td)
+	if err == nil {
+		t.Fatal("invalid health check must be an error")
+	}
+	for _, s := range []string{
+		`container app: healthCheck.command must start with CMD or CMD-SHELL, not "curl"`,
+		"container app: healthCheck.interval must be between 5 and 300, not 0",
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error must contain %q: %s", s, err)
+		}
+	}
+}
+
+func TestValidateSystemControls(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family:      aws.String("app"),
+		Memory:      aws.String("512"),
+		NetworkMode: aws.String("awsvpc"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name:  aws.String("app"),
+				Image: aws.String("app:v1"),
+				SystemControls: []*ecs.SystemControl{
+					{Namespace: aws.String("net.core.somaxconn"), Value: aws.String("4096")},
+				},
+			},
+			{
+				Name:  aws.String("sidecar"),
+				Image: aws.String("sidecar:v1"),
+				SystemControls: []*ecs.SystemControl{
+					{Namespace: aws.String("net.core.somaxconn"), Value: aws.String("1024")},
+				},
+			},
+		},
+	}
+	warnings, err := ValidateTaskDefinition(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "container sidecar: systemControls net.core.somaxconn=1024 conflicts with 4096") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	td.ContainerDefinitions[1].SystemControls = []*ecs.SystemControl{{Namespace: aws.String("net.ipv4.tcp_keepalive_time")}}
+	if _, err := ValidateTaskDefinition(td); err == nil || !strings.Contains(err.Error(), "container sidecar: systemControls[0] requires namespace and value") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestValidatePlacementConstraints(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1")},
+		},
+		PlacementConstraints: []*ecs.TaskDefinitionPlacementConstraint{
+			{Type: aws.String("memberOf"), Expression: aws.String("attribute:ecs.instance-type =~ t3.*")},
+			{Type: aws.String("distinctInstance")},
+		},
+	}
+	if _, err := ValidateTaskDefinition(td); err != nil {
+		t.Fatal(err)
+	}
+
+	td.PlacementConstraints = []*ecs.TaskDefinitionPlacementConstraint{
+		{Type: aws.String("memberOf")},
+		{Type: aws.String("spread"), Expression: aws.String("attribute:ecs.availability-zone")},
+	}
+	_, err := ValidateTaskDefinition(td)
+	if err == nil {
+		t.Fatal("malformed placement constraints must be an error")
+	}
+	for _, s := range []string{
+		"placementConstraints[0]: memberOf requires an expression",
+		`placementConstraints[1]: type "spread" must be one of`,
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error must contain %s: %s", s, err)
+		}
+	}
+}
+
+func TestValidateMountPoints(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name:  aws.String("app"),
+				Image: aws.String("app:v1"),
+				MountPoints: []*ecs.MountPoint{
+					{SourceVolume: aws.String("data"), ContainerPath: aws.String("/data")},
+				},
+			},
+		},
+		Volumes: []*ecs.Volume{
+			{Name: aws.String("data")},
+			{Name: aws.String("cache")},
+		},
+	}
+	warnings, err := ValidateTaskDefinition(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0] != "volume cache is not mounted by any container" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	// a dangling mount reference (e.g. a typo of the volume name)
+	td.ContainerDefinitions[0].MountPoints = append(td.ContainerDefinitions[0].MountPoints,
+		&ecs.MountPoint{SourceVolume: aws.String("cahce"), ContainerPath: aws.String("/cache")},
+	)
+	_, err = ValidateTaskDefinition(td)
+	if err == nil || !strings.Contains(err.Error(), "container app: mountPoints[1] sourceVolume cahce is not defined in volumes") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestValidateDeprecatedFields(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1"), Links: aws.StringSlice([]string{"db"}), Hostname: aws.String("app")},
+			{Name: aws.String("db"), Image: aws.String("mysql:8.0"), Essential: aws.Bool(false)},
+		},
+	}
+	warnings, err := ValidateTaskDefinition(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hostname is supported in bridge network mode
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "container app: links is deprecated or ignored: ") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	td.NetworkMode = aws.String("awsvpc")
+	td.ContainerDefinitions[0].Links = nil
+	warnings, err = ValidateTaskDefinition(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "container app: hostname is deprecated or ignored: ") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
+
+func TestValidateTaskDefinitionSize(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1")},
+		},
+	}
+	value := strings.Repeat("x", 1000)
+	for i := 0; i < 60; i++ {
+		td.ContainerDefinitions[0].Environment = append(td.ContainerDefinitions[0].Environment,
+			&ecs.KeyValuePair{Name: aws.String(fmt.Sprintf("ENV_%d", i)), Value: aws.String(value)},
+		)
+	}
+	warnings, err := ValidateTaskDefinition(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "is approaching the limit 65536 bytes") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	for i := 60; i < 70; i++ {
+		td.ContainerDefinitions[0].Environment = append(td.ContainerDefinitions[0].Environment,
+			&ecs.KeyValuePair{Name: aws.String(fmt.Sprintf("ENV_%d", i)), Value: aws.String(value)},
+		)
+	}
+	if _, err := ValidateTaskDefinition(td); err == nil || !strings.Contains(err.Error(), "exceeds the limit 65536 bytes") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestValidateEssentialContainers(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family: aws.String("app"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1")},
+			{Name: aws.String("log_router"), Image: aws.String("amazon/aws-for-fluent-bit:2.10.0"), Essential: aws.Bool(false)},
+		},
+	}
+	warnings, err := ValidateTaskDefinition(td)
+	if err != nil || len(warnings) != 0 {
+		t.Errorf("unexpected result %v %v", warnings, err)
+	}
+
+	td.ContainerDefinitions[1].Essential = aws.Bool(true)
+	warnings, err = ValidateTaskDefinition(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "sidecar containers log_router are essential. the task stops when they exit (app=true log_router=true)") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	td.ContainerDefinitions[0].Essential = aws.Bool(false)
+	td.ContainerDefinitions[1].Essential = aws.Bool(false)
+	if _, err := ValidateTaskDefinition(td); err == nil || !strings.Contains(err.Error(), "at least one container must be essential (app=false log_router=false)") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestValidateTaskResources(t *testing.T) {
+	newTd := func(cpu, memory string, containers ...*ecs.ContainerDefinition) *ecs.TaskDefinition {
+		return &ecs.TaskDefinition{
+			Family:                  aws.String("app"),
+			RequiresCompatibilities: aws.StringSlice([]string{"FARGATE"}),
+			Cpu:                     aws.String(cpu),
+			Memory:                  aws.String(memory),
+			ContainerDefinitions:    containers,
+		}
+	}
+	container := func(name string, cpu, memory, reservation int64) *ecs.ContainerDefinition {
+		c := &ecs.ContainerDefinition{Name: aws.String(name), Image: aws.String("app:v1"), Cpu: aws.Int64(cpu)}
+		if memory > 0 {
+			c.Memory = aws.Int64(memory)
+		}
+		if reservation > 0 {
+			c.MemoryReservation = aws.Int64(reservation)
+		}
+		return c
+	}
+	for _, td := range []*ecs.TaskDefinition{
+		newTd("256", "512", container("app", 256, 512, 0)),
+		newTd(".25 vCPU", "2 GB", container("app", 128, 0, 1024), container("sidecar", 128, 0, 1024)),
+		newTd("1024", "3072", container("app", 512, 2048, 1024), container("sidecar", 512, 0, 1024)),
+		newTd("8192", "20480", container("app", 0, 0, 0)),
+	} {
+		if _, err := ValidateTaskDefinition(td); err != nil {
+			t.Errorf("unexpected error %s", err)
+		}
+	}
+	for want, td := range map[string]*ecs.TaskDefinition{
+		"memory 1536 is not supported with cpu 256 by FARGATE (512-2048 MiB)":       newTd("256", "1536", container("app", 0, 0, 0)),
+		"memory 18432 is not supported with cpu 8192 by FARGATE":                    newTd("8192", "18432", container("app", 0, 0, 0)),
+		"cpu 300 is not supported by FARGATE":                                       newTd("300", "1024", container("app", 0, 0, 0)),
+		"sum of container cpu 768 exceeds the task cpu 512":                         newTd("512", "1024", container("app", 512, 0, 0), container("sidecar", 256, 0, 0)),
+		"sum of container memory 1536 exceeds the task memory 1024":                 newTd("512", "1024", container("app", 0, 1024, 0), container("sidecar", 0, 0, 512)),
+		"container app: memory 2048 exceeds the task memory 1024":                   newTd("512", "1024", container("app", 0, 2048, 0)),
+		"container app: memoryReservation 1024 must not be greater than memory 512": newTd("512", "1024", container("app", 0, 512, 1024)),
+		"memory: invalid value 1 TB":                                                newTd("512", "1 TB", container("app", 0, 0, 0)),
+	} {
+		if _, err := ValidateTaskDefinition(td); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error must contain %s: %v", want, err)
+		}
+	}
+
+	td := newTd("", "", container("app", 0, 0, 0))
+	td.Cpu, td.Memory = nil, nil
+	if _, err := ValidateTaskDefinition(td); err == nil || !strings.Contains(err.Error(), "cpu and memory are required for FARGATE") {
+		t.Errorf("unexpected error %v", err)
+	}
+	td.RequiresCompatibilities = nil
+	td.ContainerDefinitions[0].Memory = aws.Int64(512)
+	if _, err := ValidateTaskDefinition(td); err != nil {
+		t.Errorf("cpu and memory are optional for EC2: %s", err)
+	}
+}
+
+func TestParseTaskResource(t *testing.T) {
+	for _, c := range []struct {
+		s, unit string
+		want    int64
+	}{
+		{"1024", "vCPU", 1024},
+		{"1 vCPU", "vCPU", 1024},
+		{"1vCPU", "vCPU", 1024},
+		{".25 vcpu", "vCPU", 256},
+		{"0.5VCPU", "vCPU", 512},
+		{"2048", "GB", 2048},
+		{"2 GB", "GB", 2048},
+		{"2GB", "GB", 2048},
+		{" 0.5gb ", "GB", 512},
+	} {
+		got, err := parseTaskResource(c.s, c.unit)
+		if err != nil {
+			t.Errorf("%q: %s", c.s, err)
+		} else if got != c.want {
+			t.Errorf("%q: expected %d got %d", c.s, c.want, got)
+		}
+	}
+	for _, s := range []string{"", "GB", "1 TB", "1TB", "-1 GB", "0", "one GB"} {
+		if _, err := parseTaskResource(s, "GB"); err == nil {
+			t.Errorf("%q must be invalid", s)
+		}
+	}
+}
+
+func TestValidateEC2ContainerMemory(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Family:                  aws.String("app"),
+		RequiresCompatibilities: aws.StringSlice([]string{"EC2"}),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1"), Memory: aws.Int64(512), MemoryReservation: aws.Int64(1024)},
+			{Name: aws.String("sidecar"), Image: aws.String("sidecar:v1")},
+			{Name: aws.String("log_router"), Image: aws.String("fluent-bit:v1"), MemoryReservation: aws.Int64(64)},
+		},
+	}
+	_, err := ValidateTaskDefinition(td)
+	if err == nil {
+		t.Fatal("must be invalid")
+	}
+	for _, want := range []string{
+		"container app: memoryReservation 1024 must not be greater than memory 512",
+		"container sidecar: memory or memoryReservation is required for EC2 without the task memory",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error must contain %s: %s", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "log_router") {
+		t.Errorf("memoryReservation only is valid: %s", err)
+	}
+
+	td.ContainerDefinitions[0].MemoryReservation = aws.Int64(256)
+	td.Memory = aws.String("2048")
+	if _, err := ValidateTaskDefinition(td); err != nil {
+		t.Errorf("container memory is optional with the task memory: %s", err)
+	}
+
+	// EC2 is the default without requiresCompatibilities
+	td.RequiresCompatibilities = nil
+	td.Memory = nil
+	if _, err := ValidateTaskDefinition(td); err == nil || !strings.Contains(err.Error(), "container sidecar: memory or memoryReservation is required for EC2 without the task memory") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestValidateLocal(t *testing.T) {
+	conf := NewDefaultConfig()
+	conf.Region = "ap-northeast-1"
+	conf.Service = "test"
+	conf.TaskDefinitionPath = "tests/td.json"
+	if err := ValidateLocal(conf); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "ecspresso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	invalid := filepath.Join(dir, "td.json")
+	if err := ioutil.WriteFile(invalid, []byte(`{"containerDefinitions":[{"name":"app"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf = NewDefaultConfig()
+	conf.TaskDefinitionPath = invalid
+	conf.ServiceDefinitionPath = filepath.Join(dir, "not-found.json")
+	conf.PollIntervalMin = time.Minute
+	conf.PollIntervalMax = time.Second
+	err = ValidateLocal(conf)
+	if err == nil {
+		t.Fatal("invalid definitions must be an error")
+	}
+	for _, s := range []string{"poll_interval_min", "family is required", "image is required", "service_definition"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error must report %s: %s", s, err)
+		}
+	}
+}
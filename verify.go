@@ -0,0 +1,47 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// VerifyDeployedRevision verifies that all of the running tasks of the service run the task definition.
+// It guards against concurrent changes to the service by others.
+func (d *App) VerifyDeployedRevision(ctx context.Context, tdArn string) error {
+	tasks, err := d.listTasks(ctx, ecs.DesiredStatusRunning, "")
+	if err != nil {
+		return errors.Wrap(err, "failed to list tasks")
+	}
+	if err := verifyTaskRevisions(tasks, tdArn); err != nil {
+		return err
+	}
+	d.Log("All running tasks are", arnToName(tdArn))
+	return nil
+}
+
+func verifyTaskRevisions(tasks []*ecs.Task, tdArn string) error {
+	mismatched := make(map[string]int)
+	for _, task := range tasks {
+		if aws.StringValue(task.LastStatus) != ecs.DesiredStatusRunning {
+			continue
+		}
+		if arn := aws.StringValue(task.TaskDefinitionArn); arn != tdArn {
+			mismatched[arnToName(arn)]++
+		}
+	}
+	if len(mismatched) == 0 {
+		return nil
+	}
+	revs := make([]string, 0, len(mismatched))
+	for rev, n := range mismatched {
+		revs = append(revs, fmt.Sprintf("%s (%d tasks)", rev, n))
+	}
+	sort.Strings(revs)
+	return errors.Errorf("running tasks are not %s: %s", arnToName(tdArn), strings.Join(revs, ", "))
+}
@@ -0,0 +1,34 @@
+package ecspresso
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestVerifyTaskRevisions(t *testing.T) {
+	tdArn := "arn:aws:ecs:us-east-1:123456789012:task-definition/app:3"
+	task := func(rev, status string) *ecs.Task {
+		return &ecs.Task{
+			TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/app:" + rev),
+			LastStatus:        aws.String(status),
+		}
+	}
+	if err := verifyTaskRevisions([]*ecs.Task{task("3", "RUNNING"), task("2", "STOPPED")}, tdArn); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	err := verifyTaskRevisions([]*ecs.Task{
+		task("3", "RUNNING"),
+		task("2", "RUNNING"),
+		task("4", "RUNNING"),
+		task("2", "RUNNING"),
+	}, tdArn)
+	if err == nil {
+		t.Fatal("mixed revisions must be an error")
+	}
+	if expected := "running tasks are not app:3: app:2 (2 tasks), app:4 (1 tasks)"; err.Error() != expected {
+		t.Errorf("unexpected error %s expected %s", err, expected)
+	}
+}
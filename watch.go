@@ -0,0 +1,60 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/morikuni/aec"
+)
+
+// watchStatus shows the status of the service every interval until interrupted.
+func (d *App) watchStatus(opt StatusOption) error {
+	d.setLogOutput()
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		select {
+		case <-sig:
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+
+	return watchLoop(ctx, *opt.Watch, time.After, func() error {
+		if isTerminal {
+			fmt.Print(aec.EraseDisplay(aec.EraseModes.All), aec.Position(1, 1))
+		} else {
+			fmt.Println()
+		}
+		fmt.Println("Every", *opt.Watch, time.Now().Format(time.RFC3339))
+		if opt.Query != nil && *opt.Query != "" {
+			return d.queryServiceStatus(ctx, *opt.Query)
+		}
+		_, err := d.DescribeServiceStatus(ctx, *opt.Events)
+		return err
+	})
+}
+
+// watchLoop calls f immediately and every interval until ctx is done.
+// It returns nil when ctx is done.
+func watchLoop(ctx context.Context, interval time.Duration, after func(time.Duration) <-chan time.Time, f func() error) error {
+	for {
+		if err := f(); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-after(interval):
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchLoop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	after := func(time.Duration) <-chan time.Time {
+		if ctx.Err() != nil {
+			// never fires after interrupted
+			return nil
+		}
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+	var n int
+	err := watchLoop(ctx, time.Second, after, func() error {
+		n++
+		if n == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("interrupted watch must not be an error: %s", err)
+	}
+	if n != 3 {
+		t.Errorf("unexpected calls %d", n)
+	}
+
+	err = watchLoop(context.Background(), time.Second, after, func() error {
+		return errors.New("failed to describe service")
+	})
+	if err == nil {
+		t.Error("error must be returned")
+	}
+}
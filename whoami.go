@@ -0,0 +1,151 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+)
+
+// Identity represents the caller identity and the source of the credentials.
+// It never contains secret keys.
+type Identity struct {
+	Account            string `json:"account"`
+	Arn                string `json:"arn"`
+	UserID             string `json:"user_id"`
+	PrincipalType      string `json:"principal_type"`
+	PrincipalName      string `json:"principal_name"`
+	Region             string `json:"region"`
+	Profile            string `json:"profile,omitempty"`
+	CredentialProvider string `json:"credential_provider"`
+	CredentialSource   string `json:"credential_source"`
+	AccessKeyID        string `json:"access_key_id"`
+}
+
+// Whoami prints the caller identity and the credential provider which supplied the credentials.
+func (d *App) Whoami() error {
+	ctx, cancel := d.Start()
+	defer cancel()
+
+	id := &Identity{
+		Region:  d.region,
+		Profile: os.Getenv("AWS_PROFILE"),
+	}
+	if d.credentials != nil {
+		v, err := d.credentials.GetWithContext(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to resolve credentials")
+		}
+		id.CredentialProvider = v.ProviderName
+		id.AccessKeyID = maskAccessKeyID(v.AccessKeyID)
+	}
+	id.CredentialSource = credentialSource(id.CredentialProvider, d.config.DeployRoleARN)
+
+	out, err := d.sts.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get caller identity")
+	}
+	id.Account = aws.StringValue(out.Account)
+	id.Arn = aws.StringValue(out.Arn)
+	id.UserID = aws.StringValue(out.UserId)
+	id.PrincipalType, id.PrincipalName = principalOf(id.Arn)
+	return d.printIdentity(os.Stdout, id)
+}
+
+func (d *App) printIdentity(w io.Writer, id *Identity) error {
+	if d.config.OutputFormat == OutputFormatJSON {
+		b, err := json.Marshal(id)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+	for _, kv := range [][2]string{
+		{"Account", id.Account},
+		{"Arn", id.Arn},
+		{"UserId", id.UserID},
+		{"Principal", id.PrincipalType + " " + id.PrincipalName},
+		{"Region", id.Region},
+		{"Profile", id.Profile},
+		{"CredentialProvider", id.CredentialProvider},
+		{"CredentialSource", id.CredentialSource},
+		{"AccessKeyId", id.AccessKeyID},
+	} {
+		if kv[1] == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// principalOf returns the type (user, assumed-role, root, ...) and the name of the principal by the ARN.
+func principalOf(s string) (string, string) {
+	a, err := arn.Parse(s)
+	if err != nil {
+		return "", ""
+	}
+	if a.Resource == "root" {
+		return "root", a.AccountID
+	}
+	ps := strings.SplitN(a.Resource, "/", 2)
+	if len(ps) < 2 {
+		return ps[0], ""
+	}
+	if ps[0] == "assumed-role" {
+		// assumed-role/{RoleName}/{SessionName}
+		return ps[0], ps[1]
+	}
+	// user or role may have a path
+	name := ps[1]
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+	return ps[0], name
+}
+
+// credentialSource returns a human readable source of credentials by the provider name of the SDK.
+func credentialSource(provider, deployRoleARN string) string {
+	if deployRoleARN != "" {
+		return "deploy_role_arn " + deployRoleARN
+	}
+	switch {
+	case provider == "EnvConfigCredentials" || provider == "EnvProvider":
+		return "environment variables"
+	case strings.HasPrefix(provider, "SharedConfigCredentials"), provider == "SharedCredentialsProvider":
+		return "shared credentials file"
+	case provider == "AssumeRoleProvider":
+		return "assumed role"
+	case provider == "WebIdentityCredentials":
+		return "web identity token"
+	case provider == "SSOProvider":
+		return "AWS SSO"
+	case provider == "ProcessProvider":
+		return "credential_process"
+	case provider == "EC2RoleProvider":
+		return "EC2 instance role"
+	case provider == "CredentialsEndpointProvider":
+		return "container credentials endpoint (e.g. ECS task role)"
+	case provider == "":
+		return "unknown"
+	default:
+		return provider
+	}
+}
+
+// maskAccessKeyID returns the access key ID showing only the prefix (type of the key) and the last 4 characters.
+func maskAccessKeyID(id string) string {
+	if len(id) <= 8 {
+		return strings.Repeat("*", len(id))
+	}
+	return id[:4] + strings.Repeat("*", len(id)-8) + id[len(id)-4:]
+}